@@ -0,0 +1,300 @@
+package gozmo
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+// An AssetLoader reads and decodes files (images, sounds, JSON) on worker
+// goroutines, so that loading a level does not freeze the window. Only what
+// needs OpenGL or changes a scene, like uploading a texture, runs on the main
+// thread, at the start of each frame. Concurrent requests for the same file
+// share the decoding.
+//
+// Progress and OnComplete follow the loads requested since the loader was
+// last idle, for loading screens.
+type AssetLoader struct {
+	lock sync.Mutex
+	// Requests being decoded, by kind and file name.
+	decoding map[string]*assetRequest
+	// Requests decoded, waiting for the main thread.
+	decoded []*assetRequest
+	workers sync.WaitGroup
+	slots   chan bool
+
+	total     int
+	done      int
+	listeners []func()
+}
+
+type assetRequest struct {
+	key    string
+	decode func() (interface{}, error)
+	value  interface{}
+	err    error
+	// The callbacks of all of the requests sharing the decoding, called on
+	// the main thread.
+	callbacks []func(interface{}, error)
+}
+
+var assetLoader = NewAssetLoader()
+
+// DefaultAssetLoader is the loader whose loads complete in the window loop.
+func DefaultAssetLoader() *AssetLoader {
+	return assetLoader
+}
+
+// NewAssetLoader creates a loader decoding as many files at once as there
+// are CPUs.
+func NewAssetLoader() *AssetLoader {
+	loader := AssetLoader{decoding: make(map[string]*assetRequest)}
+	loader.slots = make(chan bool, runtime.NumCPU())
+	return &loader
+}
+
+// request decodes a file once for all of the concurrent requests with the
+// same key.
+func (loader *AssetLoader) request(key string, decode func() (interface{}, error), callback func(interface{}, error)) {
+	loader.lock.Lock()
+	defer loader.lock.Unlock()
+
+	loader.total++
+	request, ok := loader.decoding[key]
+	if ok {
+		request.callbacks = append(request.callbacks, callback)
+		return
+	}
+	request = &assetRequest{key: key, decode: decode, callbacks: []func(interface{}, error){callback}}
+	loader.decoding[key] = request
+
+	loader.workers.Add(1)
+	go func() {
+		defer loader.workers.Done()
+		loader.slots <- true
+		value, err := request.decode()
+		<-loader.slots
+
+		loader.lock.Lock()
+		request.value = value
+		request.err = err
+		delete(loader.decoding, key)
+		loader.decoded = append(loader.decoded, request)
+		loader.lock.Unlock()
+	}()
+}
+
+// process runs the callbacks of the decoded files, on the main thread.
+func (loader *AssetLoader) process() {
+	loader.lock.Lock()
+	decoded := loader.decoded
+	loader.decoded = nil
+	loader.lock.Unlock()
+
+	for _, request := range decoded {
+		for _, callback := range request.callbacks {
+			callback(request.value, request.err)
+			loader.lock.Lock()
+			loader.done++
+			loader.lock.Unlock()
+		}
+	}
+
+	loader.lock.Lock()
+	var listeners []func()
+	if loader.done == loader.total {
+		loader.total = 0
+		loader.done = 0
+		listeners = loader.listeners
+		loader.listeners = nil
+	}
+	loader.lock.Unlock()
+
+	for _, listener := range listeners {
+		listener()
+	}
+}
+
+// Wait blocks until all of the loads are complete, including the ones they
+// start (like the textures of a scene), running their main thread part.
+func (loader *AssetLoader) Wait() {
+	for loader.Loading() {
+		loader.workers.Wait()
+		loader.process()
+	}
+}
+
+// Loading tells if some loads are not complete.
+func (loader *AssetLoader) Loading() bool {
+	loader.lock.Lock()
+	defer loader.lock.Unlock()
+	return loader.total > loader.done
+}
+
+// Progress is the fraction (0..1) of the complete loads, 1 when idle.
+func (loader *AssetLoader) Progress() float32 {
+	loader.lock.Lock()
+	defer loader.lock.Unlock()
+	if loader.total == 0 {
+		return 1
+	}
+	return float32(loader.done) / float32(loader.total)
+}
+
+// OnComplete calls a function, on the main thread, once all of the loads are
+// complete. Without loads it is called at the next frame.
+func (loader *AssetLoader) OnComplete(listener func()) {
+	loader.lock.Lock()
+	loader.listeners = append(loader.listeners, listener)
+	loader.lock.Unlock()
+}
+
+// NotifyOnComplete sends an "assetsLoaded" event to a GameObject, like a
+// loading screen, once all of the loads are complete.
+func (loader *AssetLoader) NotifyOnComplete(gameObject *GameObject) {
+	loader.OnComplete(func() {
+		gameObject.EnqueueEvent(nil, "assetsLoaded")
+	})
+}
+
+// LoadTexture adds a texture to the scene once the image is decoded and
+// uploaded, or at once when it is in the ResourceCache. Done is optional,
+// errors are reported without it.
+func (loader *AssetLoader) LoadTexture(scene *Scene, name string, fileName string, options TextureOptions, done func(*Texture, error)) {
+	key := fmt.Sprintf("image:%v:%v", options, fileName)
+	_, cached := resourceCache.textures[textureKey{fileName: filepath.Clean(fileName), options: options}]
+	loader.request(key, func() (interface{}, error) {
+		if cached {
+			return nil, nil
+		}
+		return decodeImageFile(fileName, options.Premultiplied)
+	}, func(value interface{}, err error) {
+		var texture *Texture
+		if err == nil {
+			texture, err = resourceCache.acquireTexture(name, fileName, options, func() (decodedImage, error) {
+				decoded, ok := value.(decodedImage)
+				if !ok {
+					// The cached texture was freed meanwhile.
+					return decodeImageFile(fileName, options.Premultiplied)
+				}
+				return decoded, nil
+			})
+		}
+		if err == nil {
+			scene.textures[name] = texture
+		}
+		if done != nil {
+			done(texture, err)
+		} else if err != nil {
+			fmt.Println(err)
+		}
+	})
+}
+
+// LoadAudioClip adds a sound, decoded in memory, to the scene.
+func (loader *AssetLoader) LoadAudioClip(scene *Scene, name string, fileName string, done func(*AudioClip, error)) {
+	_, cached := resourceCache.clips[filepath.Clean(fileName)]
+	loader.request("audio:"+fileName, func() (interface{}, error) {
+		if cached {
+			return nil, nil
+		}
+		return decodeAudioFile(name, fileName)
+	}, func(value interface{}, err error) {
+		var clip *AudioClip
+		if err == nil {
+			clip, err = resourceCache.acquireAudioClip(name, fileName, func() (*AudioClip, error) {
+				decoded, ok := value.(*AudioClip)
+				if !ok {
+					return decodeAudioFile(name, fileName)
+				}
+				return decoded, nil
+			})
+		}
+		if err == nil {
+			scene.AddAudioClip(clip)
+		}
+		if done != nil {
+			done(clip, err)
+		} else if err != nil {
+			fmt.Println(err)
+		}
+	})
+}
+
+// LoadJSON parses a JSON object.
+func (loader *AssetLoader) LoadJSON(fileName string, done func(map[string]interface{}, error)) {
+	loader.request("json:"+fileName, func() (interface{}, error) {
+		data, err := fileSystem.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		var parsed map[string]interface{}
+		err = json.Unmarshal(data, &parsed)
+		if err != nil {
+			return nil, fmt.Errorf("%v %v", fileName, err)
+		}
+		return parsed, nil
+	}, func(value interface{}, err error) {
+		parsed, _ := value.(map[string]interface{})
+		done(parsed, err)
+	})
+}
+
+// LoadScene loads a scene from a JSON file, with its textures and sounds.
+// Done gets the scene once all of them are loaded. Like NewSceneFromFilename
+// it panics on invalid scenes, but missing or malformed files (including the
+// textures and sounds) are errors.
+func (loader *AssetLoader) LoadScene(fileName string, done func(*Scene, error)) {
+	loader.LoadJSON(fileName, func(parsed map[string]interface{}, err error) {
+		if err != nil {
+			done(nil, err)
+			return
+		}
+		var scene *Scene
+		assets := sceneAssets{loader: loader}
+		assets.done = func(err error) {
+			if err != nil {
+				scene.Destroy()
+				done(nil, err)
+				return
+			}
+			done(scene, nil)
+		}
+		scene = newSceneFromJSON(parsed, &assets)
+		watchScene(scene, fileName)
+		assets.built = true
+		assets.check()
+	})
+}
+
+// sceneAssets loads the files of a scene with an AssetLoader, calling done
+// once the scene is built and all of them are loaded, with the first error.
+type sceneAssets struct {
+	loader  *AssetLoader
+	pending int
+	built   bool
+	err     error
+	done    func(error)
+}
+
+func (assets *sceneAssets) add() {
+	assets.pending++
+}
+
+func (assets *sceneAssets) finish(err error) {
+	if assets.err == nil {
+		assets.err = err
+	}
+	assets.pending--
+	assets.check()
+}
+
+func (assets *sceneAssets) check() {
+	if assets.built && assets.pending == 0 && assets.done != nil {
+		done := assets.done
+		assets.done = nil
+		done(assets.err)
+	}
+}
@@ -0,0 +1,117 @@
+package gozmo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAssetLoaderSharedDecoding(t *testing.T) {
+	loader := NewAssetLoader()
+	var decodes int32
+	release := make(chan bool)
+	decode := func() (interface{}, error) {
+		atomic.AddInt32(&decodes, 1)
+		<-release
+		return "decoded", nil
+	}
+
+	var results []interface{}
+	callback := func(value interface{}, err error) {
+		results = append(results, value)
+	}
+	completed := 0
+	loader.OnComplete(func() { completed++ })
+	loader.request("file", decode, callback)
+	loader.request("file", decode, callback)
+	if progress := loader.Progress(); progress != 0 {
+		t.Error("Expected 0, got", progress)
+	}
+
+	close(release)
+	loader.Wait()
+	if decodes != 1 {
+		t.Error("Expected 1 decoding, got", decodes)
+	}
+	if len(results) != 2 || results[0] != "decoded" || results[1] != "decoded" {
+		t.Error("Expected 2 results, got", results)
+	}
+	if completed != 1 || loader.Loading() || loader.Progress() != 1 {
+		t.Error("Expected the loads to be complete")
+	}
+}
+
+func TestAssetLoaderScene(t *testing.T) {
+	dir, err := ioutil.TempDir("", "assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	clipName := filepath.Join(dir, "jump.wav")
+	err = ioutil.WriteFile(clipName, encodeWAV(wavFormatPCM, 1, 8000, 8, []byte{128, 255, 0}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sceneName := filepath.Join(dir, "level.json")
+	data := `{"name": "Async", "audio": [{"name": "jump", "filename": "` + filepath.ToSlash(clipName) + `"}], "objects": [{"name": "Player"}]}`
+	err = ioutil.WriteFile(sceneName, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewAssetLoader()
+	var loaded *Scene
+	loader.LoadScene(sceneName, func(scene *Scene, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		loaded = scene
+	})
+	other := NewScene("Other")
+	loader.LoadAudioClip(other, "hop", clipName, nil)
+	loader.LoadAudioClip(other, "jump", clipName, nil)
+	loadingScreen := other.NewGameObject("LoadingScreen")
+	loader.NotifyOnComplete(loadingScreen)
+	loader.Wait()
+
+	if loaded == nil || loaded.FindGameObject("Player") == nil {
+		t.Fatal("Expected the scene to be loaded")
+	}
+	clip := loaded.GetAudioClip("jump")
+	if clip == nil || clip.Frames() != 3 {
+		t.Fatal("Expected the jump clip, got", clip)
+	}
+	hop := other.GetAudioClip("hop")
+	if hop == nil || hop.Name != "hop" || &hop.samples[0] != &other.GetAudioClip("jump").samples[0] {
+		t.Error("Expected the hop clip sharing the samples, got", hop)
+	}
+	if len(loadingScreen.events) != 1 || loadingScreen.events[0].Msg != "assetsLoaded" {
+		t.Error("Expected an assetsLoaded event, got", loadingScreen.events)
+	}
+
+	loader.LoadScene(filepath.Join(dir, "missing.json"), func(scene *Scene, err error) {
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+	loader.Wait()
+
+	// Missing textures and sounds are errors too.
+	brokenName := filepath.Join(dir, "broken.json")
+	data = `{"name": "Broken", "textures": [{"name": "hero", "filename": "` + filepath.ToSlash(filepath.Join(dir, "hero.png")) + `"}],
+		"audio": [{"name": "jump", "filename": "` + filepath.ToSlash(filepath.Join(dir, "missing.wav")) + `"}]}`
+	err = ioutil.WriteFile(brokenName, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := false
+	loader.LoadScene(brokenName, func(scene *Scene, err error) {
+		failed = scene == nil && err != nil
+	})
+	loader.Wait()
+	if !failed || Engine.scenes["Broken"] != nil {
+		t.Error("Expected the scene to fail and be destroyed")
+	}
+}
@@ -0,0 +1,326 @@
+package gozmo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/jfreymuth/oggvorbis"
+)
+
+// An AudioClip is a sound of a scene, decoded in memory from WAV or Ogg
+// Vorbis files. Samples are interleaved float32 values in the -1..1 range.
+type AudioClip struct {
+	Name       string
+	SampleRate int
+	Channels   int
+	samples    []float32
+	// Clips loaded from files are shared through the ResourceCache.
+	resource *clipResource
+}
+
+// Frames is the number of samples per channel.
+func (clip *AudioClip) Frames() int {
+	return len(clip.samples) / clip.Channels
+}
+
+// Duration in seconds.
+func (clip *AudioClip) Duration() float32 {
+	return float32(clip.Frames()) / float32(clip.SampleRate)
+}
+
+// NewAudioClip creates a clip from interleaved samples.
+func NewAudioClip(name string, sampleRate int, channels int, samples []float32) (*AudioClip, error) {
+	if channels != 1 && channels != 2 {
+		return nil, fmt.Errorf("audio clip %v has %v channels, only mono and stereo are supported", name, channels)
+	}
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("audio clip %v has an invalid sample rate %v", name, sampleRate)
+	}
+	clip := AudioClip{Name: name, SampleRate: sampleRate, Channels: channels}
+	clip.samples = samples[:len(samples)/channels*channels]
+	return &clip, nil
+}
+
+// DecodeAudioClip decodes WAV or Ogg Vorbis data, recognized by its header.
+func DecodeAudioClip(name string, data []byte) (*AudioClip, error) {
+	var samples []float32
+	var sampleRate, channels int
+	var err error
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		samples, sampleRate, channels, err = decodeWAV(data)
+	case len(data) >= 4 && string(data[0:4]) == "OggS":
+		var format *oggvorbis.Format
+		samples, format, err = oggvorbis.ReadAll(bytes.NewReader(data))
+		if err == nil {
+			sampleRate = format.SampleRate
+			channels = format.Channels
+		}
+	default:
+		return nil, fmt.Errorf("audio clip %v is neither WAV nor Ogg Vorbis", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("audio clip %v %v", name, err)
+	}
+	return NewAudioClip(name, sampleRate, channels, samples)
+}
+
+// The samples are shared with the other scenes loading the same file.
+func (scene *Scene) NewAudioClipFromFilename(name string, fileName string) (*AudioClip, error) {
+	clip, err := resourceCache.acquireAudioClip(name, fileName, func() (*AudioClip, error) {
+		return decodeAudioFile(name, fileName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	scene.audioClips[name] = clip
+	return clip, nil
+}
+
+// Destroy releases a clip loaded from a file, voices playing it keep their
+// samples.
+func (clip *AudioClip) Destroy() {
+	if clip.resource != nil {
+		resourceCache.releaseAudioClip(clip.resource)
+		clip.resource = nil
+	}
+}
+
+func decodeAudioFile(name string, fileName string) (*AudioClip, error) {
+	data, err := fileSystem.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeAudioClip(name, data)
+}
+
+func (scene *Scene) AddAudioClip(clip *AudioClip) {
+	scene.audioClips[clip.Name] = clip
+}
+
+func (scene *Scene) GetAudioClip(name string) *AudioClip {
+	clip, ok := scene.audioClips[name]
+	if !ok {
+		return nil
+	}
+	return clip
+}
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xfffe
+)
+
+type wavFormat struct {
+	format     uint16
+	channels   uint16
+	sampleRate uint32
+	bits       uint16
+}
+
+// readWAVHeader reads the chunks up to the samples, returning their size.
+func readWAVHeader(reader io.ReadSeeker) (wavFormat, int64, error) {
+	var wav wavFormat
+	var header [12]byte
+	_, err := io.ReadFull(reader, header[:])
+	if err != nil || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return wav, 0, fmt.Errorf("is not a WAV file")
+	}
+	hasFormat := false
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		err := binary.Read(reader, binary.LittleEndian, &chunk)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return wav, 0, fmt.Errorf("has no data chunk")
+		}
+		if err != nil {
+			return wav, 0, err
+		}
+		size := int64(chunk.Size)
+
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			body := make([]byte, size)
+			_, err = io.ReadFull(reader, body)
+			if err != nil || len(body) < 16 {
+				return wav, 0, fmt.Errorf("has an invalid fmt chunk")
+			}
+			wav.format = binary.LittleEndian.Uint16(body[0:])
+			wav.channels = binary.LittleEndian.Uint16(body[2:])
+			wav.sampleRate = binary.LittleEndian.Uint32(body[4:])
+			wav.bits = binary.LittleEndian.Uint16(body[14:])
+			// The actual format follows the extension size and the valid bits.
+			if wav.format == wavFormatExtensible && len(body) >= 26 {
+				wav.format = binary.LittleEndian.Uint16(body[24:])
+			}
+			if !wav.supported() {
+				return wav, 0, fmt.Errorf("has an unsupported format %v with %v bits", wav.format, wav.bits)
+			}
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return wav, 0, fmt.Errorf("has no fmt chunk")
+			}
+			return wav, size, nil
+		default:
+			_, err = reader.Seek(size, io.SeekCurrent)
+			if err != nil {
+				return wav, 0, err
+			}
+		}
+		// Chunks are aligned to 2 bytes.
+		if size%2 == 1 {
+			reader.Seek(1, io.SeekCurrent)
+		}
+	}
+}
+
+func (wav wavFormat) supported() bool {
+	switch wav.format {
+	case wavFormatPCM:
+		return wav.bits == 8 || wav.bits == 16 || wav.bits == 24 || wav.bits == 32
+	case wavFormatFloat:
+		return wav.bits == 32
+	}
+	return false
+}
+
+func (wav wavFormat) frameSize() int {
+	return int(wav.channels) * int(wav.bits) / 8
+}
+
+// convert turns integer (8 to 32 bits) and float (32 bits) PCM data into
+// samples.
+func (wav wavFormat) convert(pcm []byte) []float32 {
+	var samples []float32
+	switch {
+	case wav.format == wavFormatPCM && wav.bits == 8:
+		samples = make([]float32, len(pcm))
+		for i, value := range pcm {
+			samples[i] = (float32(value) - 128) / 128
+		}
+	case wav.format == wavFormatPCM && wav.bits == 16:
+		samples = make([]float32, len(pcm)/2)
+		for i := range samples {
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(pcm[i*2:]))) / (1 << 15)
+		}
+	case wav.format == wavFormatPCM && wav.bits == 24:
+		samples = make([]float32, len(pcm)/3)
+		for i := range samples {
+			value := int32(pcm[i*3]) | int32(pcm[i*3+1])<<8 | int32(int8(pcm[i*3+2]))<<16
+			samples[i] = float32(value) / (1 << 23)
+		}
+	case wav.format == wavFormatPCM && wav.bits == 32:
+		samples = make([]float32, len(pcm)/4)
+		for i := range samples {
+			samples[i] = float32(int32(binary.LittleEndian.Uint32(pcm[i*4:]))) / (1 << 31)
+		}
+	case wav.format == wavFormatFloat && wav.bits == 32:
+		samples = make([]float32, len(pcm)/4)
+		for i := range samples {
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(pcm[i*4:]))
+		}
+	}
+	return samples
+}
+
+func decodeWAV(data []byte) ([]float32, int, int, error) {
+	reader := bytes.NewReader(data)
+	wav, size, err := readWAVHeader(reader)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	// Truncated files keep the available data.
+	if size > int64(reader.Len()) {
+		size = int64(reader.Len())
+	}
+	pcm := make([]byte, size)
+	reader.Read(pcm)
+	return wav.convert(pcm), int(wav.sampleRate), int(wav.channels), nil
+}
+
+func loadAudioClips(scene *Scene, clips []interface{}, assets *sceneAssets) {
+	for _, clip := range clips {
+		clipMap := clip.(map[string]interface{})
+
+		name, ok := clipMap["name"]
+		if !ok {
+			panic("audio clip requires a name")
+		}
+		filename, ok := clipMap["filename"]
+		if !ok {
+			panic("audio clip requires a filename")
+		}
+
+		// Streamed clips are music tracks.
+		stream, _ := clipMap["stream"].(bool)
+		if stream {
+			track, err := scene.NewMusicTrackFromFilename(name.(string), filename.(string))
+			if err != nil {
+				panic(err)
+			}
+			loop, hasLoop := clipMap["loop"]
+			if hasLoop {
+				track.Loop = loop.(bool)
+			}
+			continue
+		}
+
+		if assets != nil {
+			assets.add()
+			assets.loader.LoadAudioClip(scene, name.(string), filename.(string), func(clip *AudioClip, err error) {
+				assets.finish(err)
+			})
+			continue
+		}
+
+		_, err := scene.NewAudioClipFromFilename(name.(string), filename.(string))
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+// loadMusic sets the background music of the scene, as a track name and the
+// crossfade duration.
+func loadMusic(scene *Scene, music map[string]interface{}) {
+	track, ok := music["track"]
+	if !ok {
+		panic("music requires a track")
+	}
+	scene.music = track.(string)
+	crossfade, ok := music["crossfade"]
+	if ok {
+		scene.musicCrossfade = float32(crossfade.(float64))
+	}
+}
+
+// startMusic crossfades to the background music of the scene, if any.
+func (scene *Scene) startMusic() {
+	if scene.music == "" {
+		return
+	}
+	track := scene.GetMusicTrack(scene.music)
+	if track == nil {
+		fmt.Println("unknown music track", scene.music)
+		return
+	}
+	err := DefaultAudioMixer().PlayMusic(track, scene.musicCrossfade)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// SetMusic sets the background music, played when the scene is set on the
+// window.
+func (scene *Scene) SetMusic(trackName string, crossfade float32) {
+	scene.music = trackName
+	scene.musicCrossfade = crossfade
+}
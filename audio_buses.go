@@ -0,0 +1,143 @@
+package gozmo
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The AudioBuses component controls the buses of the default mixer with
+// attributes like "music.volume", "sfx.mute" or "master.lowPass", e.g. from
+// an options menu or an Animator muffling the sounds under water. Setting
+// "music" to the name of a track of the scene crossfades to it in
+// "crossfade" seconds.
+type AudioBuses struct {
+	gameObject *GameObject
+	crossfade  float32
+}
+
+func NewAudioBuses() *AudioBuses {
+	return &AudioBuses{crossfade: 1}
+}
+
+func (buses *AudioBuses) Start(gameObject *GameObject) {
+	buses.gameObject = gameObject
+}
+
+func (buses *AudioBuses) Update(gameObject *GameObject) {
+}
+
+// bus splits an attribute in the bus and the property.
+func (buses *AudioBuses) bus(attr string) (*AudioBus, string, error) {
+	parts := strings.SplitN(attr, ".", 2)
+	if len(parts) != 2 {
+		return nil, "", fmt.Errorf("%v attribute of %T not found", attr, buses)
+	}
+	bus := DefaultAudioMixer().Bus(parts[0])
+	if bus == nil {
+		return nil, "", fmt.Errorf("%v attribute of %T unknown bus %v", attr, buses, parts[0])
+	}
+	return bus, parts[1], nil
+}
+
+func (buses *AudioBuses) SetAttr(attr string, value interface{}) error {
+	switch attr {
+	case "crossfade":
+		crossfade, err := CastFloat32(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, buses, err)
+		}
+		buses.crossfade = crossfade
+		return nil
+	case "music":
+		trackName, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, buses)
+		}
+		if trackName == "" {
+			DefaultAudioMixer().StopMusic(buses.crossfade)
+			return nil
+		}
+		if buses.gameObject == nil {
+			return fmt.Errorf("%v attribute of %T requires a GameObject", attr, buses)
+		}
+		track := buses.gameObject.Scene.GetMusicTrack(trackName)
+		if track == nil {
+			return fmt.Errorf("%v attribute of %T unknown track %v", attr, buses, trackName)
+		}
+		return DefaultAudioMixer().PlayMusic(track, buses.crossfade)
+	}
+
+	bus, property, err := buses.bus(attr)
+	if err != nil {
+		return err
+	}
+	switch property {
+	case "volume":
+		var volume float32
+		volume, err = CastFloat32(value)
+		if err == nil {
+			bus.SetVolume(volume)
+		}
+	case "mute":
+		var muted bool
+		muted, err = CastBool(value)
+		if err == nil {
+			bus.SetMuted(muted)
+		}
+	case "lowPass":
+		var frequency float32
+		frequency, err = CastFloat32(value)
+		if err == nil {
+			bus.SetLowPass(frequency)
+		}
+	case "duckGain":
+		var gain float32
+		gain, err = CastFloat32(value)
+		if err == nil {
+			bus.SetDuckGain(gain)
+		}
+	default:
+		return fmt.Errorf("%v attribute of %T not found", attr, buses)
+	}
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, buses, err)
+	}
+	return nil
+}
+
+func (buses *AudioBuses) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "crossfade":
+		return buses.crossfade, nil
+	case "music":
+		return DefaultAudioMixer().Music(), nil
+	}
+
+	bus, property, err := buses.bus(attr)
+	if err != nil {
+		return nil, err
+	}
+	switch property {
+	case "volume":
+		return bus.Volume(), nil
+	case "mute":
+		return bus.Muted(), nil
+	case "lowPass":
+		return bus.LowPass(), nil
+	case "duckGain":
+		return bus.DuckGain(), nil
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, buses)
+}
+
+func (buses *AudioBuses) GetType() string {
+	return "AudioBuses"
+}
+
+func initAudioBuses(args []interface{}) Component {
+	return NewAudioBuses()
+}
+
+func init() {
+	RegisterComponent("AudioBuses", initAudioBuses)
+}
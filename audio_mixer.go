@@ -0,0 +1,569 @@
+package gozmo
+
+import (
+	"math"
+	"sync"
+)
+
+// The AudioMixer sums the playing voices into interleaved stereo samples.
+// Sound devices pull the samples through an AudioBackend, from their own
+// goroutine: the windows play the default mixer with the OpenAL one. Without a
+// backend the mixer can still render into memory.
+//
+// Voices play on buses (music, sfx, ui and dialogue), each with a volume, a
+// mute switch and effects, all summed on the master bus. The music bus is
+// ducked while dialogue plays. The volumes and mute switches of the default
+// mixer are persisted in the settings.
+
+// An AudioBackend plays the samples of a mixer, e.g. on a sound device.
+type AudioBackend interface {
+	// Start begins pulling samples with the Render method of the mixer.
+	Start(mixer *AudioMixer) error
+	Stop() error
+}
+
+type AudioMixer struct {
+	SampleRate int
+
+	lock    sync.Mutex
+	voices  []*audioVoice
+	buses   []*AudioBus
+	music   *audioVoice
+	track   *MusicTrack
+	backend AudioBackend
+}
+
+// An AudioBus groups voices, its fields are protected by the mixer lock.
+type AudioBus struct {
+	Name   string
+	mixer  *AudioMixer
+	volume float32
+	muted  bool
+	// Stored in the settings.
+	persistent bool
+
+	// The cutoff frequency of the low-pass filter in Hz, 0 disables it.
+	lowPass      float32
+	lowPassState [2]float32
+
+	// Ducking lowers the volume while another bus plays.
+	duckTrigger string
+	duckGain    float32
+	// Seconds to reach the ducked volume and to restore it.
+	duckAttack  float32
+	duckRelease float32
+	duck        float32
+
+	buffer []float32
+	active bool
+}
+
+// An audioVoice is an input being played, its fields are protected by the
+// mixer lock.
+type audioVoice struct {
+	mixer    *AudioMixer
+	input    audioInput
+	bus      *AudioBus
+	position float64
+	volume   float32
+	pitch    float32
+	// -1 is left, 1 right.
+	pan      float32
+	loop     bool
+	paused   bool
+	finished bool
+
+	// Fades move the gain towards the target by step per frame.
+	fade          float32
+	fadeTarget    float32
+	fadeStep      float32
+	stopAfterFade bool
+}
+
+var audioBusNames = []string{"master", "music", "sfx", "ui", "dialogue"}
+
+func NewAudioMixer(sampleRate int) *AudioMixer {
+	mixer := AudioMixer{SampleRate: sampleRate}
+	for _, name := range audioBusNames {
+		mixer.AddBus(name)
+	}
+	mixer.Bus("music").SetDucking("dialogue", 0.4, 0.1, 0.5)
+	return &mixer
+}
+
+var defaultMixer *AudioMixer
+
+// DefaultAudioMixer returns the mixer used by the AudioSources, at 44100Hz.
+func DefaultAudioMixer() *AudioMixer {
+	if defaultMixer == nil {
+		defaultMixer = NewAudioMixer(44100)
+		for _, bus := range defaultMixer.buses {
+			bus.persistent = true
+		}
+		defaultMixer.loadSettings()
+	}
+	return defaultMixer
+}
+
+// SetAudioBackend plays the default mixer with a backend, stopping the
+// previous one. A nil backend silences the mixer.
+func SetAudioBackend(backend AudioBackend) error {
+	return DefaultAudioMixer().SetBackend(backend)
+}
+
+func (mixer *AudioMixer) SetBackend(backend AudioBackend) error {
+	if mixer.backend != nil {
+		err := mixer.backend.Stop()
+		if err != nil {
+			return err
+		}
+	}
+	mixer.backend = backend
+	if backend == nil {
+		return nil
+	}
+	return backend.Start(mixer)
+}
+
+// AddBus creates a bus, or returns the existing one.
+func (mixer *AudioMixer) AddBus(name string) *AudioBus {
+	bus := mixer.Bus(name)
+	if bus != nil {
+		return bus
+	}
+	bus = &AudioBus{Name: name, mixer: mixer, volume: 1, duck: 1}
+	mixer.lock.Lock()
+	mixer.buses = append(mixer.buses, bus)
+	mixer.lock.Unlock()
+	return bus
+}
+
+func (mixer *AudioMixer) Bus(name string) *AudioBus {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+	for _, bus := range mixer.buses {
+		if bus.Name == name {
+			return bus
+		}
+	}
+	return nil
+}
+
+// busOrDefault falls back to the sfx bus.
+func (mixer *AudioMixer) busOrDefault(name string) *AudioBus {
+	bus := mixer.Bus(name)
+	if bus == nil {
+		bus = mixer.Bus("sfx")
+	}
+	return bus
+}
+
+// loadSettings applies the persisted volumes.
+func (mixer *AudioMixer) loadSettings() {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+	for _, bus := range mixer.buses {
+		if bus.persistent {
+			bus.volume = settingFloat32("audio."+bus.Name+".volume", bus.volume)
+			bus.muted = settingBool("audio."+bus.Name+".mute", bus.muted)
+		}
+	}
+}
+
+func (bus *AudioBus) SetVolume(volume float32) {
+	bus.mixer.lock.Lock()
+	bus.volume = volume
+	bus.mixer.lock.Unlock()
+	if bus.persistent {
+		SetSetting("audio."+bus.Name+".volume", volume)
+	}
+}
+
+func (bus *AudioBus) Volume() float32 {
+	bus.mixer.lock.Lock()
+	defer bus.mixer.lock.Unlock()
+	return bus.volume
+}
+
+func (bus *AudioBus) SetMuted(muted bool) {
+	bus.mixer.lock.Lock()
+	bus.muted = muted
+	bus.mixer.lock.Unlock()
+	if bus.persistent {
+		SetSetting("audio."+bus.Name+".mute", muted)
+	}
+}
+
+func (bus *AudioBus) Muted() bool {
+	bus.mixer.lock.Lock()
+	defer bus.mixer.lock.Unlock()
+	return bus.muted
+}
+
+// SetLowPass sets the cutoff frequency of the low-pass filter, 0 disables it.
+func (bus *AudioBus) SetLowPass(frequency float32) {
+	bus.mixer.lock.Lock()
+	bus.lowPass = frequency
+	bus.mixer.lock.Unlock()
+}
+
+func (bus *AudioBus) LowPass() float32 {
+	bus.mixer.lock.Lock()
+	defer bus.mixer.lock.Unlock()
+	return bus.lowPass
+}
+
+// SetDucking lowers the volume to gain while the trigger bus plays, an empty
+// trigger disables it.
+func (bus *AudioBus) SetDucking(trigger string, gain, attack, release float32) {
+	bus.mixer.lock.Lock()
+	bus.duckTrigger = trigger
+	bus.duckGain = gain
+	bus.duckAttack = attack
+	bus.duckRelease = release
+	bus.mixer.lock.Unlock()
+}
+
+// DuckGain is the gain applied while ducked.
+func (bus *AudioBus) DuckGain() float32 {
+	bus.mixer.lock.Lock()
+	defer bus.mixer.lock.Unlock()
+	return bus.duckGain
+}
+
+func (bus *AudioBus) SetDuckGain(gain float32) {
+	bus.mixer.lock.Lock()
+	bus.duckGain = gain
+	bus.mixer.lock.Unlock()
+}
+
+// Playing is the number of voices being played.
+func (mixer *AudioMixer) Playing() int {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+	playing := 0
+	for _, voice := range mixer.voices {
+		if !voice.finished {
+			playing++
+		}
+	}
+	return playing
+}
+
+// play starts a clip on a bus, its parameters are updated with set.
+func (mixer *AudioMixer) play(clip *AudioClip, bus *AudioBus) *audioVoice {
+	voice := mixer.newVoice(clipInput{clip}, bus)
+	mixer.start(voice)
+	return voice
+}
+
+// newVoice prepares a voice, which is heard once started.
+func (mixer *AudioMixer) newVoice(input audioInput, bus *AudioBus) *audioVoice {
+	if bus == nil {
+		bus = mixer.busOrDefault("")
+	}
+	return &audioVoice{mixer: mixer, input: input, bus: bus, volume: 1, pitch: 1, fade: 1, fadeTarget: 1}
+}
+
+func (mixer *AudioMixer) start(voice *audioVoice) {
+	mixer.lock.Lock()
+	mixer.voices = append(mixer.voices, voice)
+	mixer.lock.Unlock()
+}
+
+// PlayMusic plays a track on the music bus, crossfading from the current one
+// in seconds. Playing the current track again does not restart it.
+func (mixer *AudioMixer) PlayMusic(track *MusicTrack, crossfade float32) error {
+	mixer.lock.Lock()
+	current := mixer.music
+	same := current != nil && !current.finished && mixer.track.fileName == track.fileName
+	mixer.lock.Unlock()
+	if same {
+		return nil
+	}
+
+	input, err := track.open()
+	if err != nil {
+		return err
+	}
+	voice := mixer.newVoice(input, mixer.Bus("music"))
+	voice.loop = track.Loop
+	if crossfade > 0 {
+		voice.fadeFrom(0, 1, crossfade, false)
+	}
+	mixer.stopMusic(crossfade)
+	mixer.start(voice)
+
+	mixer.lock.Lock()
+	mixer.music = voice
+	mixer.track = track
+	mixer.lock.Unlock()
+	return nil
+}
+
+// StopMusic fades the music out in seconds.
+func (mixer *AudioMixer) StopMusic(fade float32) {
+	mixer.stopMusic(fade)
+	mixer.lock.Lock()
+	mixer.music = nil
+	mixer.track = nil
+	mixer.lock.Unlock()
+}
+
+func (mixer *AudioMixer) stopMusic(fade float32) {
+	mixer.lock.Lock()
+	current := mixer.music
+	mixer.lock.Unlock()
+	if current == nil {
+		return
+	}
+	// Without a backend nothing would render the fade.
+	if fade > 0 && mixer.backend != nil {
+		current.fadeFrom(-1, 0, fade, true)
+	} else {
+		current.stop()
+	}
+}
+
+// Music returns the name of the track being played.
+func (mixer *AudioMixer) Music() string {
+	mixer.lock.Lock()
+	defer mixer.lock.Unlock()
+	if mixer.music == nil || mixer.music.finished {
+		return ""
+	}
+	return mixer.track.Name
+}
+
+func (voice *audioVoice) set(volume, pitch, pan float32, loop bool) {
+	voice.mixer.lock.Lock()
+	voice.volume = volume
+	voice.pitch = pitch
+	voice.pan = pan
+	voice.loop = loop
+	voice.mixer.lock.Unlock()
+}
+
+// fadeFrom moves the gain to target in seconds, from the current gain when
+// from is negative.
+func (voice *audioVoice) fadeFrom(from, target, seconds float32, stop bool) {
+	voice.mixer.lock.Lock()
+	if from >= 0 {
+		voice.fade = from
+	}
+	voice.fadeTarget = target
+	voice.fadeStep = float32(math.Abs(float64(target-voice.fade))) / (seconds * float32(voice.mixer.SampleRate))
+	voice.stopAfterFade = stop
+	voice.mixer.lock.Unlock()
+}
+
+func (voice *audioVoice) setPaused(paused bool) {
+	voice.mixer.lock.Lock()
+	voice.paused = paused
+	voice.mixer.lock.Unlock()
+}
+
+// stop removes the voice from the mixer at once, closing its input.
+func (voice *audioVoice) stop() {
+	mixer := voice.mixer
+	mixer.lock.Lock()
+	voice.finished = true
+	for i, playing := range mixer.voices {
+		if playing == voice {
+			last := len(mixer.voices) - 1
+			copy(mixer.voices[i:], mixer.voices[i+1:])
+			mixer.voices[last] = nil
+			mixer.voices = mixer.voices[:last]
+			voice.input.close()
+			break
+		}
+	}
+	mixer.lock.Unlock()
+}
+
+func (voice *audioVoice) isFinished() bool {
+	voice.mixer.lock.Lock()
+	defer voice.mixer.lock.Unlock()
+	return voice.finished
+}
+
+// Time is the playback position in seconds.
+func (voice *audioVoice) time() float32 {
+	voice.mixer.lock.Lock()
+	defer voice.mixer.lock.Unlock()
+	return float32(voice.position) / float32(voice.input.sampleRate())
+}
+
+// Render fills out with interleaved stereo samples, advancing the voices.
+func (mixer *AudioMixer) Render(out []float32) {
+	mixer.lock.Lock()
+	for _, bus := range mixer.buses {
+		if cap(bus.buffer) < len(out) {
+			bus.buffer = make([]float32, len(out))
+		}
+		bus.buffer = bus.buffer[:len(out)]
+		for i := range bus.buffer {
+			bus.buffer[i] = 0
+		}
+		bus.active = false
+	}
+
+	playing := mixer.voices[:0]
+	for _, voice := range mixer.voices {
+		if !voice.finished && !voice.paused {
+			voice.mix(voice.bus.buffer, mixer.SampleRate)
+			voice.bus.active = true
+		}
+		if voice.finished {
+			voice.input.close()
+		} else {
+			playing = append(playing, voice)
+		}
+	}
+	for i := len(playing); i < len(mixer.voices); i++ {
+		mixer.voices[i] = nil
+	}
+	mixer.voices = playing
+
+	// The buses are summed on the master one, which comes first.
+	master := mixer.buses[0]
+	for _, bus := range mixer.buses[1:] {
+		bus.process(mixer.SampleRate)
+		for i, value := range bus.buffer {
+			master.buffer[i] += value
+		}
+	}
+	master.process(mixer.SampleRate)
+
+	for i, value := range master.buffer {
+		if value > 1 {
+			value = 1
+		} else if value < -1 {
+			value = -1
+		}
+		out[i] = value
+	}
+	mixer.lock.Unlock()
+}
+
+// RenderFrames renders a number of stereo frames into a new buffer.
+func (mixer *AudioMixer) RenderFrames(frames int) []float32 {
+	out := make([]float32, frames*2)
+	mixer.Render(out)
+	return out
+}
+
+// triggered checks whether a bus has voices playing.
+func (mixer *AudioMixer) triggered(name string) bool {
+	for _, bus := range mixer.buses {
+		if bus.Name == name {
+			return bus.active
+		}
+	}
+	return false
+}
+
+// process applies the effects and the volume to the buffer of the bus.
+func (bus *AudioBus) process(sampleRate int) {
+	buffer := bus.buffer
+	if bus.lowPass > 0 {
+		// A one-pole filter for each channel.
+		alpha := 1 - float32(math.Exp(-2*math.Pi*float64(bus.lowPass)/float64(sampleRate)))
+		for i := 0; i+1 < len(buffer); i += 2 {
+			for channel := 0; channel < 2; channel++ {
+				bus.lowPassState[channel] += alpha * (buffer[i+channel] - bus.lowPassState[channel])
+				buffer[i+channel] = bus.lowPassState[channel]
+			}
+		}
+	} else {
+		bus.lowPassState = [2]float32{}
+	}
+
+	target := float32(1)
+	step := float32(1)
+	if bus.duckTrigger != "" && bus.mixer.triggered(bus.duckTrigger) {
+		target = bus.duckGain
+		if bus.duckAttack > 0 {
+			step = (1 - bus.duckGain) / (bus.duckAttack * float32(sampleRate))
+		}
+	} else if bus.duckRelease > 0 {
+		step = (1 - bus.duckGain) / (bus.duckRelease * float32(sampleRate))
+	}
+
+	volume := bus.volume
+	if bus.muted {
+		volume = 0
+	}
+	for i := 0; i+1 < len(buffer); i += 2 {
+		bus.duck = approach(bus.duck, target, step)
+		gain := volume * bus.duck
+		buffer[i] *= gain
+		buffer[i+1] *= gain
+	}
+}
+
+// approach moves a value towards a target, by step at most.
+func approach(value, target, step float32) float32 {
+	if value < target {
+		return float32(math.Min(float64(value+step), float64(target)))
+	}
+	return float32(math.Max(float64(value-step), float64(target)))
+}
+
+// mix adds the voice to the output, resampling the input with linear
+// interpolation.
+func (voice *audioVoice) mix(out []float32, sampleRate int) {
+	if voice.pitch <= 0 {
+		return
+	}
+	input := voice.input
+	step := float64(input.sampleRate()) / float64(sampleRate) * float64(voice.pitch)
+
+	// Balance keeps the center at full volume.
+	left := voice.volume
+	right := voice.volume
+	if voice.pan > 0 {
+		left *= 1 - voice.pan
+	} else if voice.pan < 0 {
+		right *= 1 + voice.pan
+	}
+
+	for i := 0; i+1 < len(out); {
+		index := int(voice.position)
+		l0, r0, ok := input.frame(index)
+		if !ok {
+			frames := input.rewind()
+			if !voice.loop || frames == 0 {
+				voice.finished = true
+				return
+			}
+			voice.position = math.Mod(voice.position, float64(frames))
+			continue
+		}
+		l1, r1, ok := input.frame(index + 1)
+		if !ok {
+			l1, r1 = l0, r0
+		}
+		fraction := float32(voice.position - float64(index))
+
+		if voice.fade != voice.fadeTarget {
+			voice.fade = approach(voice.fade, voice.fadeTarget, voice.fadeStep)
+		} else if voice.stopAfterFade {
+			voice.finished = true
+			return
+		}
+		out[i] += (l0 + (l1-l0)*fraction) * left * voice.fade
+		out[i+1] += (r0 + (r1-r0)*fraction) * right * voice.fade
+
+		voice.position += step
+		i += 2
+	}
+}
+
+func init() {
+	onSettingsLoaded(func() {
+		if defaultMixer != nil {
+			defaultMixer.loadSettings()
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package gozmo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/mobile/exp/audio/al"
+)
+
+// The OpenAL backend plays a mixer on the default sound device, the one the
+// windows start. It keeps a few buffers queued on a streaming source,
+// rendering the mixer again into each one the device has played.
+type openALBackend struct {
+	source  al.Source
+	buffers []al.Buffer
+	stop    chan bool
+	done    chan bool
+}
+
+const (
+	// About 23ms each at 44100Hz, for ~90ms of latency.
+	openALBufferFrames = 1024
+	openALBuffers      = 4
+	openALPoll         = 5 * time.Millisecond
+)
+
+// NewOpenALBackend creates the backend of the default sound device.
+func NewOpenALBackend() AudioBackend {
+	return &openALBackend{}
+}
+
+func (backend *openALBackend) Start(mixer *AudioMixer) error {
+	err := al.OpenDevice()
+	if err != nil {
+		return err
+	}
+	backend.source = al.GenSources(1)[0]
+	backend.buffers = al.GenBuffers(openALBuffers)
+	if code := al.Error(); code != 0 {
+		al.CloseDevice()
+		return fmt.Errorf("audio device error %#x", code)
+	}
+	backend.stop = make(chan bool)
+	backend.done = make(chan bool)
+
+	out := make([]float32, openALBufferFrames*2)
+	data := make([]byte, len(out)*2)
+	fill := func(buffer al.Buffer) {
+		mixer.Render(out)
+		for i, value := range out {
+			binary.LittleEndian.PutUint16(data[i*2:], uint16(int16(math.Round(float64(value)*32767))))
+		}
+		buffer.BufferData(al.FormatStereo16, data, int32(mixer.SampleRate))
+	}
+	for _, buffer := range backend.buffers {
+		fill(buffer)
+	}
+	backend.source.QueueBuffers(backend.buffers...)
+	al.PlaySources(backend.source)
+
+	go func() {
+		defer close(backend.done)
+		ticker := time.NewTicker(openALPoll)
+		defer ticker.Stop()
+		played := make([]al.Buffer, 1)
+		for {
+			select {
+			case <-backend.stop:
+				return
+			case <-ticker.C:
+			}
+			for backend.source.BuffersProcessed() > 0 {
+				backend.source.UnqueueBuffers(played...)
+				fill(played[0])
+				backend.source.QueueBuffers(played...)
+			}
+			// The source stops when it runs out of buffers.
+			if backend.source.State() != al.Playing {
+				al.PlaySources(backend.source)
+			}
+		}
+	}()
+	return nil
+}
+
+func (backend *openALBackend) Stop() error {
+	close(backend.stop)
+	<-backend.done
+	al.StopSources(backend.source)
+	al.DeleteSources(backend.source)
+	al.DeleteBuffers(backend.buffers...)
+	al.CloseDevice()
+	return nil
+}
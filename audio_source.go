@@ -0,0 +1,317 @@
+package gozmo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The AudioSource component plays a clip of its scene on the default mixer.
+// Setting the "play" attribute (to true, or to the name of a clip) starts it
+// from the beginning, and "stop" stops it, so sounds can be triggered by
+// Animator frames and scripts.
+//
+// Sources play on the "sfx" bus of the mixer unless another "bus" is set.
+// Spatial sources fade with the distance from the AudioListener, from full
+// volume within "minDistance" to silence at "maxDistance", and are panned
+// depending on their horizontal position.
+type AudioSource struct {
+	gameObject *GameObject
+	clipName   string
+	clip       *AudioClip
+	volume     float32
+	pitch      float32
+	pan        float32
+	loop       bool
+	bus        string
+	// Plays the clip as soon as the source starts.
+	playOnStart bool
+
+	spatial     bool
+	minDistance float32
+	maxDistance float32
+
+	voice *audioVoice
+	// Play was requested before the clip was available.
+	pending bool
+}
+
+// The AudioListener component marks the GameObject hearing the spatial
+// sources, usually the one of the camera. The last started listener is used.
+type AudioListener struct {
+	gameObject *GameObject
+}
+
+func NewAudioSource(clip *AudioClip) *AudioSource {
+	source := AudioSource{clip: clip, volume: 1, pitch: 1, bus: "sfx"}
+	if clip != nil {
+		source.clipName = clip.Name
+	}
+	source.minDistance = 1
+	source.maxDistance = 10
+	return &source
+}
+
+func (source *AudioSource) Start(gameObject *GameObject) {
+	source.gameObject = gameObject
+	if source.playOnStart {
+		source.Play()
+	}
+}
+
+func (source *AudioSource) Update(gameObject *GameObject) {
+	if source.clipName != "" {
+		source.clip, _ = gameObject.Scene.audioClips[source.clipName]
+	}
+	if source.pending {
+		source.Play()
+	}
+	if source.voice == nil {
+		return
+	}
+	if source.voice.isFinished() {
+		source.voice = nil
+		return
+	}
+	source.updateVoice()
+}
+
+func (source *AudioSource) Destroy(gameObject *GameObject) {
+	source.Stop()
+}
+
+// Play starts the clip from the beginning, stopping the previous playback.
+func (source *AudioSource) Play() {
+	source.Stop()
+	if source.clip == nil && source.clipName != "" && source.gameObject != nil {
+		source.clip, _ = source.gameObject.Scene.audioClips[source.clipName]
+	}
+	if source.clip == nil {
+		source.pending = source.clipName != ""
+		return
+	}
+	source.pending = false
+	mixer := DefaultAudioMixer()
+	source.voice = mixer.newVoice(clipInput{source.clip}, mixer.busOrDefault(source.bus))
+	// The backend may render at any time, the voice is heard once set.
+	source.updateVoice()
+	mixer.start(source.voice)
+}
+
+func (source *AudioSource) Stop() {
+	source.pending = false
+	if source.voice != nil {
+		source.voice.stop()
+		source.voice = nil
+	}
+}
+
+func (source *AudioSource) IsPlaying() bool {
+	return source.voice != nil && !source.voice.isFinished()
+}
+
+// Time is the playback position in seconds.
+func (source *AudioSource) Time() float32 {
+	if source.voice == nil {
+		return 0
+	}
+	return source.voice.time()
+}
+
+func (source *AudioSource) updateVoice() {
+	volume := source.volume
+	pan := source.pan
+	if source.spatial && source.gameObject != nil {
+		listener := source.gameObject.Scene.audioListener
+		if listener != nil {
+			gain, spatialPan := spatialize(source.gameObject.Position, listener.gameObject.Position, source.minDistance, source.maxDistance)
+			volume *= gain
+			pan = clampPan(pan + spatialPan)
+		}
+	}
+	source.voice.set(volume, source.pitch, pan, source.loop)
+}
+
+// spatialize computes the gain and the pan of a source heard from a
+// listener, with a linear falloff between the distances.
+func spatialize(source, listener mgl32.Vec2, minDistance, maxDistance float32) (float32, float32) {
+	delta := source.Sub(listener)
+	distance := delta.Len()
+	gain := float32(1)
+	if distance >= maxDistance {
+		gain = 0
+	} else if distance > minDistance {
+		gain = 1 - (distance-minDistance)/(maxDistance-minDistance)
+	}
+	pan := float32(0)
+	if maxDistance > 0 {
+		pan = clampPan(delta[0] / maxDistance)
+	}
+	return gain, pan
+}
+
+func clampPan(pan float32) float32 {
+	return float32(math.Max(-1, math.Min(1, float64(pan))))
+}
+
+// play accepts true (or a number) to start the current clip, or the name of
+// another clip.
+func (source *AudioSource) setPlay(value interface{}) error {
+	clipName, ok := value.(string)
+	if ok {
+		source.clipName = clipName
+		source.clip = nil
+		source.Play()
+		return nil
+	}
+	flag, err := CastBool(value)
+	if err != nil {
+		return err
+	}
+	if flag {
+		source.Play()
+	} else {
+		source.Stop()
+	}
+	return nil
+}
+
+func (source *AudioSource) SetAttr(attr string, value interface{}) error {
+	var err error
+	switch attr {
+	case "clip":
+		clipName, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, source)
+		}
+		source.clipName = clipName
+		source.clip = nil
+	case "play":
+		err = source.setPlay(value)
+	case "stop":
+		var flag bool
+		flag, err = CastBool(value)
+		if err == nil && flag {
+			source.Stop()
+		}
+	case "volume":
+		source.volume, err = CastFloat32(value)
+	case "pitch":
+		source.pitch, err = CastFloat32(value)
+	case "pan":
+		source.pan, err = CastFloat32(value)
+		source.pan = clampPan(source.pan)
+	case "loop":
+		source.loop, err = CastBool(value)
+	case "bus":
+		bus, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, source)
+		}
+		source.bus = bus
+	case "playOnStart":
+		source.playOnStart, err = CastBool(value)
+	case "spatial":
+		source.spatial, err = CastBool(value)
+	case "minDistance":
+		source.minDistance, err = CastFloat32(value)
+	case "maxDistance":
+		source.maxDistance, err = CastFloat32(value)
+	default:
+		return fmt.Errorf("%v attribute of %T not found", attr, source)
+	}
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, source, err)
+	}
+	// The changes are heard immediately.
+	if source.voice != nil {
+		source.updateVoice()
+	}
+	return nil
+}
+
+func (source *AudioSource) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "clip":
+		return source.clipName, nil
+	case "playing":
+		return source.IsPlaying(), nil
+	case "time":
+		return source.Time(), nil
+	case "volume":
+		return source.volume, nil
+	case "pitch":
+		return source.pitch, nil
+	case "pan":
+		return source.pan, nil
+	case "loop":
+		return source.loop, nil
+	case "bus":
+		return source.bus, nil
+	case "playOnStart":
+		return source.playOnStart, nil
+	case "spatial":
+		return source.spatial, nil
+	case "minDistance":
+		return source.minDistance, nil
+	case "maxDistance":
+		return source.maxDistance, nil
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, source)
+}
+
+func (source *AudioSource) GetType() string {
+	return "AudioSource"
+}
+
+// The optional argument is the name of the clip.
+func initAudioSource(args []interface{}) Component {
+	source := NewAudioSource(nil)
+	if len(args) > 0 {
+		err := source.SetAttr("clip", args[0])
+		if err != nil {
+			panic(err)
+		}
+	}
+	return source
+}
+
+func NewAudioListener() *AudioListener {
+	return &AudioListener{}
+}
+
+func (listener *AudioListener) Start(gameObject *GameObject) {
+	listener.gameObject = gameObject
+	gameObject.Scene.audioListener = listener
+}
+
+func (listener *AudioListener) Update(gameObject *GameObject) {
+}
+
+func (listener *AudioListener) Destroy(gameObject *GameObject) {
+	if gameObject.Scene.audioListener == listener {
+		gameObject.Scene.audioListener = nil
+	}
+}
+
+func (listener *AudioListener) SetAttr(attr string, value interface{}) error {
+	return fmt.Errorf("%v attribute of %T not found", attr, listener)
+}
+
+func (listener *AudioListener) GetAttr(attr string) (interface{}, error) {
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, listener)
+}
+
+func (listener *AudioListener) GetType() string {
+	return "AudioListener"
+}
+
+func initAudioListener(args []interface{}) Component {
+	return NewAudioListener()
+}
+
+func init() {
+	RegisterComponent("AudioSource", initAudioSource)
+	RegisterComponent("AudioListener", initAudioListener)
+}
@@ -0,0 +1,258 @@
+package gozmo
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jfreymuth/oggvorbis"
+)
+
+// A MusicTrack is a long sound of a scene, like background music, decoded
+// while playing instead of being loaded in memory. Each playback opens its
+// own decoder on the file.
+type MusicTrack struct {
+	Name     string
+	fileName string
+	// Tracks loop unless disabled.
+	Loop bool
+}
+
+// An audioInput provides the frames of a voice, from a clip in memory or
+// from the decoder of a track.
+type audioInput interface {
+	sampleRate() int
+	// frame returns the left and right samples of a frame, false past the
+	// end. The frames of streams are requested in increasing order.
+	frame(index int) (float32, float32, bool)
+	// rewind restarts the input, returning the number of frames it had.
+	rewind() int
+	close()
+}
+
+type clipInput struct {
+	clip *AudioClip
+}
+
+func (input clipInput) sampleRate() int {
+	return input.clip.SampleRate
+}
+
+func (input clipInput) frame(index int) (float32, float32, bool) {
+	clip := input.clip
+	if index >= clip.Frames() {
+		return 0, 0, false
+	}
+	if clip.Channels == 1 {
+		return clip.samples[index], clip.samples[index], true
+	}
+	return clip.samples[index*2], clip.samples[index*2+1], true
+}
+
+func (input clipInput) rewind() int {
+	return input.clip.Frames()
+}
+
+func (input clipInput) close() {
+}
+
+// An audioDecoder reads interleaved samples from a file, like the Ogg
+// Vorbis reader.
+type audioDecoder interface {
+	Read(samples []float32) (int, error)
+	SetPosition(frame int64) error
+	SampleRate() int
+	Channels() int
+}
+
+// streamFrames is the number of frames decoded at once.
+const streamFrames = 4096
+
+type streamInput struct {
+	name    string
+	file    io.ReadSeekCloser
+	decoder audioDecoder
+	// The decoded samples, buffer[0] is the frame start.
+	buffer []float32
+	chunk  []float32
+	start  int
+	eof    bool
+}
+
+func (input *streamInput) sampleRate() int {
+	return input.decoder.SampleRate()
+}
+
+func (input *streamInput) frame(index int) (float32, float32, bool) {
+	channels := input.decoder.Channels()
+	frames := len(input.buffer) / channels
+	for index >= input.start+frames {
+		if input.eof {
+			return 0, 0, false
+		}
+		// The frame before the requested one is kept for the interpolation.
+		drop := index - 1 - input.start
+		if drop > frames {
+			drop = frames
+		}
+		if drop > 0 {
+			input.buffer = append(input.buffer[:0], input.buffer[drop*channels:]...)
+			input.start += drop
+		}
+		n, err := input.decoder.Read(input.chunk)
+		input.buffer = append(input.buffer, input.chunk[:n]...)
+		if err != nil {
+			if err != io.EOF {
+				fmt.Println("music track", input.name, err)
+			}
+			input.eof = true
+		}
+		frames = len(input.buffer) / channels
+	}
+	if index < input.start {
+		return 0, 0, false
+	}
+	offset := (index - input.start) * channels
+	if channels == 1 {
+		return input.buffer[offset], input.buffer[offset], true
+	}
+	return input.buffer[offset], input.buffer[offset+1], true
+}
+
+func (input *streamInput) rewind() int {
+	frames := input.start + len(input.buffer)/input.decoder.Channels()
+	err := input.decoder.SetPosition(0)
+	if err != nil {
+		fmt.Println("music track", input.name, err)
+		return 0
+	}
+	input.buffer = input.buffer[:0]
+	input.start = 0
+	input.eof = false
+	return frames
+}
+
+func (input *streamInput) close() {
+	input.file.Close()
+}
+
+// wavDecoder reads the samples of a WAV file as needed.
+type wavDecoder struct {
+	file      io.ReadSeeker
+	wav       wavFormat
+	dataStart int64
+	frames    int64
+	position  int64
+	pcm       []byte
+}
+
+func newWAVDecoder(file io.ReadSeeker) (*wavDecoder, error) {
+	wav, size, err := readWAVHeader(file)
+	if err != nil {
+		return nil, err
+	}
+	start, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	decoder := wavDecoder{file: file, wav: wav, dataStart: start}
+	decoder.frames = size / int64(wav.frameSize())
+	return &decoder, nil
+}
+
+func (decoder *wavDecoder) Read(samples []float32) (int, error) {
+	channels := int(decoder.wav.channels)
+	frames := int64(len(samples) / channels)
+	if remaining := decoder.frames - decoder.position; frames > remaining {
+		frames = remaining
+	}
+	if frames <= 0 {
+		return 0, io.EOF
+	}
+	size := int(frames) * decoder.wav.frameSize()
+	if cap(decoder.pcm) < size {
+		decoder.pcm = make([]byte, size)
+	}
+	n, err := io.ReadFull(decoder.file, decoder.pcm[:size])
+	n = n / decoder.wav.frameSize()
+	decoder.position += int64(n)
+	copied := copy(samples, decoder.wav.convert(decoder.pcm[:n*decoder.wav.frameSize()]))
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return copied, err
+}
+
+func (decoder *wavDecoder) SetPosition(frame int64) error {
+	_, err := decoder.file.Seek(decoder.dataStart+frame*int64(decoder.wav.frameSize()), io.SeekStart)
+	if err != nil {
+		return err
+	}
+	decoder.position = frame
+	return nil
+}
+
+func (decoder *wavDecoder) SampleRate() int {
+	return int(decoder.wav.sampleRate)
+}
+
+func (decoder *wavDecoder) Channels() int {
+	return int(decoder.wav.channels)
+}
+
+// open starts decoding the track, recognized by its header.
+func (track *MusicTrack) open() (*streamInput, error) {
+	file, err := fileSystem.openSeeker(track.fileName)
+	if err != nil {
+		return nil, err
+	}
+	var header [4]byte
+	_, err = io.ReadFull(file, header[:])
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("music track %v %v", track.Name, err)
+	}
+
+	var decoder audioDecoder
+	switch string(header[:]) {
+	case "RIFF":
+		decoder, err = newWAVDecoder(file)
+	case "OggS":
+		decoder, err = oggvorbis.NewReader(file)
+	default:
+		err = fmt.Errorf("is neither WAV nor Ogg Vorbis")
+	}
+	if err == nil && decoder.Channels() != 1 && decoder.Channels() != 2 {
+		err = fmt.Errorf("has %v channels, only mono and stereo are supported", decoder.Channels())
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("music track %v %v", track.Name, err)
+	}
+	input := streamInput{name: track.Name, file: file, decoder: decoder}
+	input.chunk = make([]float32, streamFrames*decoder.Channels())
+	return &input, nil
+}
+
+// NewMusicTrackFromFilename adds a track to the scene, checking that the
+// file can be decoded.
+func (scene *Scene) NewMusicTrackFromFilename(name string, fileName string) (*MusicTrack, error) {
+	track := MusicTrack{Name: name, fileName: fileName, Loop: true}
+	input, err := track.open()
+	if err != nil {
+		return nil, err
+	}
+	input.close()
+	scene.musicTracks[name] = &track
+	return &track, nil
+}
+
+func (scene *Scene) GetMusicTrack(name string) *MusicTrack {
+	track, ok := scene.musicTracks[name]
+	if !ok {
+		return nil
+	}
+	return track
+}
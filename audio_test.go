@@ -0,0 +1,422 @@
+package gozmo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// encodeWAV builds a WAV file from raw sample data.
+func encodeWAV(format, channels uint16, sampleRate uint32, bits uint16, data []byte) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("RIFF")
+	binary.Write(&buffer, binary.LittleEndian, uint32(4+8+16+8+len(data)))
+	buffer.WriteString("WAVEfmt ")
+	binary.Write(&buffer, binary.LittleEndian, uint32(16))
+	blockAlign := channels * bits / 8
+	binary.Write(&buffer, binary.LittleEndian, struct {
+		Format, Channels       uint16
+		SampleRate, ByteRate   uint32
+		BlockAlign, SampleBits uint16
+	}{format, channels, sampleRate, sampleRate * uint32(blockAlign), blockAlign, bits})
+	buffer.WriteString("data")
+	binary.Write(&buffer, binary.LittleEndian, uint32(len(data)))
+	buffer.Write(data)
+	return buffer.Bytes()
+}
+
+func TestDecodeWAV(t *testing.T) {
+	var pcm bytes.Buffer
+	binary.Write(&pcm, binary.LittleEndian, []int16{0, 16384, -32768, 16384})
+	clip, err := DecodeAudioClip("stereo", encodeWAV(wavFormatPCM, 2, 22050, 16, pcm.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clip.Channels != 2 || clip.SampleRate != 22050 || clip.Frames() != 2 {
+		t.Fatal("Unexpected format", clip.Channels, clip.SampleRate, clip.Frames())
+	}
+	expected := []float32{0, 0.5, -1, 0.5}
+	for i, value := range expected {
+		if clip.samples[i] != value {
+			t.Error("Expected", value, "got", clip.samples[i])
+		}
+	}
+
+	clip, err = DecodeAudioClip("mono", encodeWAV(wavFormatPCM, 1, 8000, 8, []byte{128, 192, 0}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clip.samples[1] != 0.5 || clip.samples[2] != -1 {
+		t.Error("Unexpected samples", clip.samples)
+	}
+
+	pcm.Reset()
+	binary.Write(&pcm, binary.LittleEndian, []float32{0.25, -0.75})
+	clip, err = DecodeAudioClip("float", encodeWAV(wavFormatFloat, 1, 8000, 32, pcm.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clip.samples[0] != 0.25 || clip.samples[1] != -0.75 {
+		t.Error("Unexpected samples", clip.samples)
+	}
+
+	_, err = DecodeAudioClip("surround", encodeWAV(wavFormatPCM, 6, 8000, 8, make([]byte, 12)))
+	if err == nil {
+		t.Error("Expected an error for 6 channels")
+	}
+	_, err = DecodeAudioClip("text", []byte("not a sound"))
+	if err == nil {
+		t.Error("Expected an error for unknown data")
+	}
+	_, err = DecodeAudioClip("broken", []byte("OggS broken"))
+	if err == nil {
+		t.Error("Expected an error for broken Ogg data")
+	}
+}
+
+func TestAudioMixer(t *testing.T) {
+	clip, _ := NewAudioClip("ramp", 100, 1, []float32{0, 0.5, 1, 0.5})
+	mixer := NewAudioMixer(200)
+
+	// Half the sample rate interpolates, panning right silences the left.
+	voice := mixer.play(clip, nil)
+	voice.set(1, 1, 1, false)
+	out := mixer.RenderFrames(10)
+	expected := []float32{0, 0.25, 0.5, 0.75, 1, 0.75, 0.5, 0.5, 0, 0}
+	for i, value := range expected {
+		if out[i*2] != 0 || out[i*2+1] != value {
+			t.Error("Frame", i, "expected 0", value, "got", out[i*2], out[i*2+1])
+		}
+	}
+	if !voice.isFinished() || mixer.Playing() != 0 {
+		t.Error("Expected the voice to be finished")
+	}
+
+	// Looping voices wrap around, and are summed and clipped.
+	first := mixer.play(clip, nil)
+	first.set(1, 2, 0, true)
+	second := mixer.play(clip, nil)
+	second.set(1, 2, 0, true)
+	out = mixer.RenderFrames(6)
+	expected = []float32{0, 1, 1, 1, 0, 1}
+	for i, value := range expected {
+		if out[i*2] != value || out[i*2+1] != value {
+			t.Error("Frame", i, "expected", value, "got", out[i*2], out[i*2+1])
+		}
+	}
+	first.stop()
+	mixer.Bus("master").SetVolume(0.5)
+	mixer.RenderFrames(1)
+	if mixer.Playing() != 1 {
+		t.Error("Expected 1, got", mixer.Playing())
+	}
+	second.stop()
+}
+
+func TestAudioSource(t *testing.T) {
+	scene := NewScene("Test")
+	clip, _ := NewAudioClip("beep", 44100, 1, []float32{1, 1, 1, 1})
+	scene.AddAudioClip(clip)
+
+	camera := scene.NewGameObject("Camera")
+	camera.AddComponent("listener", NewAudioListener())
+	defer camera.Destroy()
+
+	gameObject := scene.NewGameObject("Torch")
+	gameObject.SetPosition(5.5, 0)
+	source := initAudioSource([]interface{}{"beep"}).(*AudioSource)
+	gameObject.AddComponent("sound", source)
+	defer gameObject.Destroy()
+	source.SetAttr("spatial", true)
+
+	err := gameObject.SetAttr("sound", "play", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playing, _ := gameObject.GetAttr("sound", "playing")
+	if playing != true {
+		t.Fatal("Expected the source to play")
+	}
+
+	// Halfway between the distances, on the right.
+	out := DefaultAudioMixer().RenderFrames(1)
+	if out[1] != 0.5 || out[0] >= out[1] {
+		t.Error("Expected a louder right at 0.5, got", out[0], out[1])
+	}
+
+	gameObject.SetAttr("sound", "stop", true)
+	playing, _ = gameObject.GetAttr("sound", "playing")
+	if playing != false || DefaultAudioMixer().Playing() != 0 {
+		t.Error("Expected the source to be stopped")
+	}
+
+	if gameObject.SetAttr("sound", "play", "missing") != nil || source.IsPlaying() {
+		t.Error("Expected a missing clip to wait")
+	}
+}
+
+// testAudioBackend renders the mixer from its own goroutine, as sound devices
+// do, recording the loudest sample.
+type testAudioBackend struct {
+	mixer    *AudioMixer
+	rendered chan bool
+	stop     chan bool
+	done     chan bool
+	peak     float32
+}
+
+func (backend *testAudioBackend) Start(mixer *AudioMixer) error {
+	backend.mixer = mixer
+	backend.rendered = make(chan bool)
+	backend.stop = make(chan bool)
+	backend.done = make(chan bool)
+	go func() {
+		defer close(backend.done)
+		for {
+			select {
+			case <-backend.stop:
+				return
+			case backend.rendered <- true:
+			default:
+			}
+			for _, value := range mixer.RenderFrames(64) {
+				if value > backend.peak {
+					backend.peak = value
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (backend *testAudioBackend) Stop() error {
+	close(backend.stop)
+	<-backend.done
+	return nil
+}
+
+// manualAudioBackend leaves the rendering to the tests.
+type manualAudioBackend struct{}
+
+func (backend manualAudioBackend) Start(mixer *AudioMixer) error { return nil }
+func (backend manualAudioBackend) Stop() error                   { return nil }
+
+func TestAudioBackend(t *testing.T) {
+	scene := NewScene("Test")
+	clip, _ := NewAudioClip("beep", 44100, 1, []float32{1, 1, 1, 1})
+	scene.AddAudioClip(clip)
+	gameObject := scene.NewGameObject("Torch")
+	source := initAudioSource([]interface{}{"beep"}).(*AudioSource)
+	gameObject.AddComponent("sound", source)
+	defer gameObject.Destroy()
+
+	first := &testAudioBackend{}
+	err := SetAudioBackend(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.mixer != DefaultAudioMixer() {
+		t.Fatal("Expected the backend to be started with the default mixer")
+	}
+	// A silent source is never heard, not even before its volume is set.
+	gameObject.SetAttr("sound", "volume", 0)
+	gameObject.SetAttr("sound", "loop", true)
+	gameObject.SetAttr("sound", "play", true)
+	<-first.rendered
+	<-first.rendered
+
+	second := &testAudioBackend{}
+	err = SetAudioBackend(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Stop waits for the goroutine, so its fields can be read.
+	if first.peak != 0 {
+		t.Error("Expected silence, got", first.peak)
+	}
+	<-second.rendered
+	source.Stop()
+	err = SetAudioBackend(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-second.done:
+	default:
+		t.Error("Expected the second backend to be stopped")
+	}
+}
+
+func writeTestTrack(t *testing.T, dir string, name string, samples []int16) string {
+	var pcm bytes.Buffer
+	binary.Write(&pcm, binary.LittleEndian, samples)
+	fileName := filepath.Join(dir, name)
+	err := ioutil.WriteFile(fileName, encodeWAV(wavFormatPCM, 1, 100, 16, pcm.Bytes()), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileName
+}
+
+func TestMusicStreaming(t *testing.T) {
+	dir, err := ioutil.TempDir("", "music")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Longer than a decoded chunk, so that it is read in pieces.
+	samples := make([]int16, streamFrames*2+10)
+	for i := range samples {
+		samples[i] = int16(i % 2 * 16384)
+	}
+	scene := NewScene("Test")
+	track, err := scene.NewMusicTrackFromFilename("theme", writeTestTrack(t, dir, "theme.wav", samples))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mixer := NewAudioMixer(100)
+	err = mixer.PlayMusic(track, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := mixer.RenderFrames(len(samples) + 2)
+	for i := 0; i < len(out)/2; i++ {
+		expected := float32(i%len(samples)%2) * 0.5
+		if out[i*2] != expected {
+			t.Fatal("Frame", i, "expected", expected, "got", out[i*2])
+		}
+	}
+	if mixer.Music() != "theme" {
+		t.Error("Expected theme, got", mixer.Music())
+	}
+
+	// Crossfading to another track fades the current one out, then stops it.
+	// The fades need a backend rendering the mixer, here the test.
+	mixer.SetBackend(manualAudioBackend{})
+	other := *track
+	other.Name = "other"
+	other.fileName = writeTestTrack(t, dir, "other.wav", []int16{16384, 16384})
+	err = mixer.PlayMusic(&other, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mixer.RenderFrames(50)
+	if mixer.Playing() != 2 {
+		t.Error("Expected 2, got", mixer.Playing())
+	}
+	mixer.RenderFrames(60)
+	if mixer.Playing() != 1 || mixer.Music() != "other" {
+		t.Error("Expected other alone, got", mixer.Playing(), mixer.Music())
+	}
+	out = mixer.RenderFrames(1)
+	if out[0] != 0.5 {
+		t.Error("Expected 0.5, got", out[0])
+	}
+	// Stopped voices leave the mixer at once, closing their files.
+	mixer.StopMusic(0)
+	if len(mixer.voices) != 0 {
+		t.Error("Expected no voices, got", len(mixer.voices))
+	}
+	// Without a backend the fades are skipped.
+	mixer.SetBackend(nil)
+	mixer.PlayMusic(track, 0)
+	mixer.StopMusic(1)
+	if len(mixer.voices) != 0 {
+		t.Error("Expected no voices, got", len(mixer.voices))
+	}
+
+	if _, err := scene.NewMusicTrackFromFilename("missing", filepath.Join(dir, "missing.ogg")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestAudioBuses(t *testing.T) {
+	clip, _ := NewAudioClip("tone", 100, 1, []float32{1, -1, 1, -1, 1, -1, 1, -1})
+	mixer := NewAudioMixer(100)
+
+	voice := mixer.play(clip, mixer.Bus("sfx"))
+	mixer.Bus("sfx").SetVolume(0.5)
+	out := mixer.RenderFrames(1)
+	if out[0] != 0.5 {
+		t.Error("Expected 0.5, got", out[0])
+	}
+	mixer.Bus("master").SetMuted(true)
+	out = mixer.RenderFrames(1)
+	if out[0] != 0 {
+		t.Error("Expected 0, got", out[0])
+	}
+	mixer.Bus("master").SetMuted(false)
+
+	// The low-pass filter removes most of the highest frequency.
+	mixer.Bus("sfx").SetLowPass(5)
+	out = mixer.RenderFrames(4)
+	for _, value := range out {
+		if value > 0.25 || value < -0.25 {
+			t.Error("Expected a filtered sample, got", value)
+		}
+	}
+	voice.stop()
+	mixer.Bus("sfx").SetLowPass(0)
+
+	// Music is ducked while dialogue plays.
+	music, _ := NewAudioClip("music", 100, 1, []float32{1, 1, 1, 1})
+	line, _ := NewAudioClip("line", 100, 1, []float32{0, 0})
+	mixer.Bus("music").SetDucking("dialogue", 0.5, 0, 0)
+	voice = mixer.play(music, mixer.Bus("music"))
+	voice.set(1, 1, 0, true)
+	mixer.play(line, mixer.Bus("dialogue"))
+	out = mixer.RenderFrames(1)
+	if out[0] != 0.5 {
+		t.Error("Expected 0.5, got", out[0])
+	}
+	mixer.RenderFrames(2)
+	out = mixer.RenderFrames(1)
+	if out[0] != 1 {
+		t.Error("Expected 1, got", out[0])
+	}
+	voice.stop()
+}
+
+func TestAudioSettings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "settings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func() { settings.fileName = "" }()
+
+	fileName := filepath.Join(dir, "game", "settings.json")
+	err = LoadSettings(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buses := NewAudioBuses()
+	err = buses.SetAttr("music.volume", 0.25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer DefaultAudioMixer().Bus("music").SetVolume(1)
+	if buses.SetAttr("missing.volume", 1) == nil {
+		t.Error("Expected an error for a missing bus")
+	}
+	err = SaveSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	DefaultAudioMixer().Bus("music").volume = 1
+	err = LoadSettings(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volume, _ := buses.GetAttr("music.volume")
+	if volume != float32(0.25) {
+		t.Error("Expected 0.25, got", volume)
+	}
+}
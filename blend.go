@@ -0,0 +1,71 @@
+package gozmo
+
+import (
+	"fmt"
+)
+
+// BlendMode selects how a drawn color is combined with the target.
+type BlendMode int
+
+const (
+	BlendAlpha BlendMode = iota
+	BlendAdditive
+	// BlendMultiply darkens the target by colors multiplied by alpha, so that
+	// transparent texels leave it unchanged. The engine shaders premultiply
+	// the colors of straight alpha textures, material shaders get a
+	// "premultiply" uniform (1 or 0) to do the same.
+	BlendMultiply
+	BlendPremultiplied
+)
+
+// Internal modes of the lighting passes: replacing the target, and adding a
+// light masked by the alpha of the target.
+const (
+	blendReplace BlendMode = iota + 100
+	blendMasked
+)
+
+var blendModeNames = map[string]BlendMode{
+	"alpha":         BlendAlpha,
+	"additive":      BlendAdditive,
+	"multiply":      BlendMultiply,
+	"premultiplied": BlendPremultiplied,
+}
+
+func (mode BlendMode) String() string {
+	for name, value := range blendModeNames {
+		if value == mode {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func ParseBlendMode(name string) (BlendMode, error) {
+	mode, ok := blendModeNames[name]
+	if !ok {
+		return BlendAlpha, fmt.Errorf("unknown blend mode %v", name)
+	}
+	return mode, nil
+}
+
+// GLInit starts with alpha blending.
+var currentBlendMode BlendMode
+
+// premultiplyFor tells (as 1 or 0) if the shader must multiply the colors of
+// a texture by alpha for a blend mode.
+func premultiplyFor(mode BlendMode, texture *Texture) float32 {
+	if mode == BlendMultiply && (texture == nil || !texture.Options.Premultiplied) {
+		return 1
+	}
+	return 0
+}
+
+// setBlendMode changes the blend function only when needed.
+func setBlendMode(mode BlendMode) {
+	if mode == currentBlendMode {
+		return
+	}
+	GLBlendMode(mode)
+	currentBlendMode = mode
+}
@@ -44,7 +44,9 @@ func NewBoxRenderer(width, height float32) *BoxRenderer {
 	return &box
 }
 
-func (box *BoxRenderer) Update(gameObject *GameObject) {
+func (box *BoxRenderer) Update(gameObject *GameObject) {}
+
+func (box *BoxRenderer) Draw(gameObject *GameObject, camera *Camera) {
 
 	model := mgl32.Translate3D(gameObject.Position[0], gameObject.Position[1], 0)
 
@@ -52,10 +54,11 @@ func (box *BoxRenderer) Update(gameObject *GameObject) {
 
 	model = model.Mul4(mgl32.HomogRotate3DZ(gameObject.Rotation))
 
-	view := Engine.Window.View.Mul4(model)
+	view := camera.View.Mul4(model)
 
-	ortho := Engine.Window.Projection.Mul4(view)
+	ortho := camera.Projection.Mul4(view)
 
+	setBlendMode(BlendAlpha)
 	GLDraw(box.mesh, uint32(shader), box.Width/2, box.Height/2, -1, 0, 0, 0, 0, ortho)
 }
 
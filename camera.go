@@ -1,33 +1,612 @@
 package gozmo
 
 import (
+	"fmt"
+	"math"
+	"sort"
+
 	"github.com/go-gl/mathgl/mgl32"
 )
 
-// The Camera component sets the current view matrix for rendering, allowing
-// different views to coexist. It ensures that cameras are always managed
-// before other items by setting them at at lower place.
-type Camera struct{}
+// The Camera component renders the scene with its own view and projection
+// into a viewport, a rectangle of the window in normalized coordinates (0..1,
+// starting from the bottom left). Cameras are drawn by increasing depth, each
+// one drawing only the GameObjects whose Layer is in its culling mask, which
+// allows split screens, minimaps and fixed HUD layers. A camera can draw
+// into a render texture instead of the window.
+//
+// The view is centered on the camera GameObject, and rotated with it. A
+// camera can follow another GameObject (by name), staying within a dead zone
+// and moving smoothly, clamped to world bounds. Screen shakes are driven by
+// a trauma value (0..1) decaying over time, added with AddTrauma.
+type Camera struct {
+	gameObject *GameObject
+
+	View       mgl32.Mat4
+	Projection mgl32.Mat4
+
+	viewport    [4]float32
+	depth       int
+	cullingMask uint32
+	clear       bool
+	clearColor  mgl32.Vec4
+	// The name of a render texture of the scene.
+	targetTexture string
+
+	// Half height of the view in world units, before the zoom.
+	size float32
+	zoom float32
+
+	target       string
+	targetObject *GameObject
+	// Seconds required to cover most of the distance to the target, 0 snaps.
+	smoothing float32
+	// Half extents of the area where the target can move without scrolling.
+	deadZone mgl32.Vec2
+
+	hasBounds    bool
+	boundsObject string
+	bounds       [4]float32
+
+	trauma      float32
+	traumaDecay float32
+	// Offset (world units) and angle (degrees) at full trauma.
+	shakeOffset    float32
+	shakeAngle     float32
+	shakeFrequency float32
+	shakeTime      float32
+
+	lighting lightingTargets
+}
+
+// The half height of the view of the windows, in world units.
+const defaultOrthographicSize = 10
+
+func (camera *Camera) Start(gameObject *GameObject) {
+	camera.gameObject = gameObject
+	if camera.size == 0 {
+		camera.size = defaultOrthographicSize
+		if Engine.Window != nil {
+			camera.size = Engine.Window.OrthographicSize
+		}
+	}
+	gameObject.Scene.cameras = append(gameObject.Scene.cameras, camera)
+}
+
+func (camera *Camera) Destroy(gameObject *GameObject) {
+	camera.lighting.release()
+	cameras := gameObject.Scene.cameras
+	for i, c := range cameras {
+		if c == camera {
+			gameObject.Scene.cameras = append(cameras[:i], cameras[i+1:]...)
+			return
+		}
+	}
+}
 
-func (camera *Camera) Start(gameObject *GameObject) {}
 func (camera *Camera) Update(gameObject *GameObject) {
-	Engine.Window.View = mgl32.LookAt(gameObject.Position[0], gameObject.Position[1], 1, gameObject.Position[0], gameObject.Position[1], 0, 0, 1, 0)
+	camera.follow(gameObject)
+	camera.clamp(gameObject)
+
+	position := gameObject.Position
+	angle := gameObject.Rotation
+
+	if camera.trauma > 0 {
+		camera.shakeTime += gameObject.DeltaTime
+		shake := camera.trauma * camera.trauma
+		t := camera.shakeTime * camera.shakeFrequency
+		position[0] += camera.shakeOffset * shake * shakeNoise(1, t)
+		position[1] += camera.shakeOffset * shake * shakeNoise(2, t)
+		angle += camera.shakeAngle * math.Pi / 180 * shake * shakeNoise(3, t)
+		camera.trauma -= camera.traumaDecay * gameObject.DeltaTime
+		if camera.trauma < 0 {
+			camera.trauma = 0
+		}
+	}
+
+	// Rotating the up vector rotates the view.
+	upX := float32(-math.Sin(float64(angle)))
+	upY := float32(math.Cos(float64(angle)))
+	camera.View = mgl32.LookAt(position[0], position[1], 1, position[0], position[1], 0, upX, upY, 0)
+
+	halfHeight := camera.OrthographicSize()
+	halfWidth := halfHeight * camera.AspectRatio()
+	camera.Projection = mgl32.Ortho2D(-halfWidth, halfWidth, -halfHeight, halfHeight)
+}
+
+// OrthographicSize is the half height of the view in world units.
+func (camera *Camera) OrthographicSize() float32 {
+	return camera.size / camera.zoom
+}
+
+func (camera *Camera) AspectRatio() float32 {
+	return camera.surfaceAspectRatio() * camera.viewport[2] / camera.viewport[3]
+}
+
+// The aspect ratio of the texture or of the window the camera draws on.
+func (camera *Camera) surfaceAspectRatio() float32 {
+	target := camera.renderTarget()
+	if target != nil {
+		return float32(target.Width) / float32(target.Height)
+	}
+	if camera.gameObject != nil && camera.gameObject.Scene.postProcess != nil {
+		aspectRatio, ok := camera.gameObject.Scene.postProcess.aspectRatio()
+		if ok {
+			return aspectRatio
+		}
+	}
+	if Engine.Window == nil {
+		return 1
+	}
+	return Engine.Window.AspectRatio
+}
+
+// renderTarget returns the render texture of the camera, nil when drawing on the
+// window.
+func (camera *Camera) renderTarget() *Texture {
+	if camera.targetTexture == "" || camera.gameObject == nil {
+		return nil
+	}
+	texture, ok := camera.gameObject.Scene.textures[camera.targetTexture]
+	if !ok || !texture.IsRenderTexture() {
+		return nil
+	}
+	return texture
+}
+
+// Draws whatever GameObject is in the layer.
+func (camera *Camera) sees(gameObject *GameObject) bool {
+	return camera.cullingMask&(1<<gameObject.Layer) != 0
+}
+
+// render draws the GameObjects of the scene seen by the camera on a surface,
+// a render texture or the window when nil.
+func (camera *Camera) render(scene *Scene, surface *Texture) {
+	surfaceWidth, surfaceHeight := bindRenderTarget(surface)
+	width := float32(surfaceWidth)
+	height := float32(surfaceHeight)
+	x := int32(camera.viewport[0] * width)
+	y := int32(camera.viewport[1] * height)
+	w := int32(camera.viewport[2] * width)
+	h := int32(camera.viewport[3] * height)
+
+	GLViewport(x, y, w, h)
+	if camera.clear {
+		GLClearRect(x, y, w, h, camera.clearColor)
+	}
+
+	for _, order := range scene.orderedKeys {
+		for _, gameObject := range scene.orderedGameObjects[order] {
+			if !gameObject.enabled || !camera.sees(gameObject) {
+				continue
+			}
+			gameObject.Draw(camera)
+		}
+	}
+	camera.renderLighting(scene, surface, x, y, w, h)
+}
+
+// sortCameras orders cameras by depth, keeping the creation order for equal
+// depths.
+func sortCameras(cameras []*Camera) []*Camera {
+	sorted := append([]*Camera(nil), cameras...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].depth < sorted[j].depth
+	})
+	return sorted
+}
+
+// The default camera draws scenes without cameras, with the window view and
+// projection.
+func (window *Window) defaultCamera() *Camera {
+	camera := NewCamera()
+	camera.size = window.OrthographicSize
+	camera.View = window.View
+	camera.Projection = window.Projection
+	return camera
+}
+
+func (camera *Camera) findTarget(gameObject *GameObject) *GameObject {
+	if camera.target == "" {
+		return nil
+	}
+	// Look for it again if it has been destroyed.
+	if camera.targetObject == nil || camera.targetObject.Scene == nil {
+		camera.targetObject = gameObject.Scene.FindGameObject(camera.target)
+	}
+	return camera.targetObject
+}
+
+func (camera *Camera) follow(gameObject *GameObject) {
+	target := camera.findTarget(gameObject)
+	if target == nil {
+		return
+	}
+
+	position := gameObject.Position
+	goal := position
+	for i := 0; i < 2; i++ {
+		delta := target.Position[i] - position[i]
+		if delta > camera.deadZone[i] {
+			goal[i] = target.Position[i] - camera.deadZone[i]
+		} else if delta < -camera.deadZone[i] {
+			goal[i] = target.Position[i] + camera.deadZone[i]
+		}
+	}
+
+	if camera.smoothing > 0 {
+		t := 1 - float32(math.Exp(float64(-gameObject.DeltaTime/camera.smoothing)))
+		goal = position.Add(goal.Sub(position).Mul(t))
+	}
+	gameObject.Position = goal
+}
+
+// clamp keeps the view within the bounds, centering it when they are smaller
+// than the view.
+func (camera *Camera) clamp(gameObject *GameObject) {
+	if camera.boundsObject != "" {
+		camera.boundsFromTileMap(gameObject)
+	}
+	if !camera.hasBounds {
+		return
+	}
+
+	halfHeight := camera.OrthographicSize()
+	halfWidth := halfHeight * camera.AspectRatio()
+	half := mgl32.Vec2{halfWidth, halfHeight}
+
+	for i := 0; i < 2; i++ {
+		min := camera.bounds[i] + half[i]
+		max := camera.bounds[i+2] - half[i]
+		if min > max {
+			gameObject.Position[i] = (camera.bounds[i] + camera.bounds[i+2]) / 2
+			continue
+		}
+		if gameObject.Position[i] < min {
+			gameObject.Position[i] = min
+		}
+		if gameObject.Position[i] > max {
+			gameObject.Position[i] = max
+		}
+	}
+}
+
+func (camera *Camera) boundsFromTileMap(gameObject *GameObject) {
+	object := gameObject.Scene.FindGameObject(camera.boundsObject)
+	if object == nil {
+		return
+	}
+	tilemap, ok := object.GetComponentByType("TileMap").(*TileMap)
+	if !ok {
+		return
+	}
+	camera.bounds = tilemap.Bounds(object)
+	camera.hasBounds = true
+}
+
+// AddTrauma increases the shake intensity, trauma is clamped to 0..1.
+func (camera *Camera) AddTrauma(amount float32) {
+	camera.trauma += amount
+	if camera.trauma > 1 {
+		camera.trauma = 1
+	}
+	if camera.trauma < 0 {
+		camera.trauma = 0
+	}
+}
+
+func (camera *Camera) SetBounds(minX, minY, maxX, maxY float32) {
+	camera.bounds = [4]float32{minX, minY, maxX, maxY}
+	camera.hasBounds = true
+	camera.boundsObject = ""
+}
+
+func (camera *Camera) ClearBounds() {
+	camera.hasBounds = false
+	camera.boundsObject = ""
+}
+
+// shakeNoise is a smooth value noise in -1..1, different for every seed.
+func shakeNoise(seed int, t float32) float32 {
+	i := int(math.Floor(float64(t)))
+	f := t - float32(i)
+	f = f * f * (3 - 2*f)
+	a := latticeNoise(seed, i)
+	b := latticeNoise(seed, i+1)
+	return a + (b-a)*f
+}
+
+func latticeNoise(seed int, i int) float32 {
+	n := uint32(i)*374761393 + uint32(seed)*668265263
+	n = (n ^ (n >> 13)) * 1274126177
+	n = n ^ (n >> 16)
+	return float32(n)/float32(math.MaxUint32)*2 - 1
+}
+
+// ScreenToWorld converts window coordinates (like the cursor or touch ones)
+// into world coordinates, using the camera of the main scene with the highest
+// depth whose viewport contains them.
+func (window *Window) ScreenToWorld(x, y float64) mgl32.Vec2 {
+	nx := float32(x / float64(window.width))
+	ny := 1 - float32(y/float64(window.height))
+
+	camera := window.defaultCamera()
+	scene := sceneManager.ActiveScene()
+	if scene != nil {
+		if scene.postProcess != nil {
+			nx, ny = scene.postProcess.windowToImage(nx, ny)
+		}
+		cameras := sortCameras(scene.cameras)
+		for i := len(cameras) - 1; i >= 0; i-- {
+			if cameras[i].gameObject.enabled && cameras[i].targetTexture == "" && cameras[i].containsPoint(nx, ny) {
+				camera = cameras[i]
+				break
+			}
+		}
+	}
+	return camera.viewportToWorld(nx, ny)
+}
+
+func (camera *Camera) containsPoint(nx, ny float32) bool {
+	return nx >= camera.viewport[0] && nx <= camera.viewport[0]+camera.viewport[2] &&
+		ny >= camera.viewport[1] && ny <= camera.viewport[1]+camera.viewport[3]
+}
+
+// viewportToWorld converts normalized window coordinates into world ones.
+func (camera *Camera) viewportToWorld(nx, ny float32) mgl32.Vec2 {
+	vecScreen := mgl32.Vec4{(nx-camera.viewport[0])/camera.viewport[2]*2 - 1, (ny-camera.viewport[1])/camera.viewport[3]*2 - 1, 0, 1}
+	vecWorld := camera.Projection.Mul4(camera.View).Inv().Mul4x1(vecScreen)
+	return mgl32.Vec2{vecWorld[0], vecWorld[1]}
+}
+
+// DebugOutline draws the view (rotated), the dead zone and the bounds.
+func (camera *Camera) DebugOutline(gameObject *GameObject) {
+	if !DebugOutlines(DebugCameras) {
+		return
+	}
+	color := mgl32.Vec4{0, 0.5, 1, 1}
+	inverse := camera.Projection.Mul4(camera.View).Inv()
+	corners := []mgl32.Vec4{{-1, -1, 0, 1}, {1, -1, 0, 1}, {1, 1, 0, 1}, {-1, 1, 0, 1}}
+	for i, corner := range corners {
+		from := inverse.Mul4x1(corner)
+		to := inverse.Mul4x1(corners[(i+1)%len(corners)])
+		DebugLine(from[0], from[1], to[0], to[1], color, 0)
+	}
+	if camera.deadZone[0] > 0 || camera.deadZone[1] > 0 {
+		position := gameObject.Position
+		DebugRect(position[0]-camera.deadZone[0], position[1]-camera.deadZone[1], position[0]+camera.deadZone[0], position[1]+camera.deadZone[1], color, 0)
+	}
+	if camera.hasBounds {
+		DebugRect(camera.bounds[0], camera.bounds[1], camera.bounds[2], camera.bounds[3], mgl32.Vec4{1, 0, 1, 1}, 0)
+	}
+}
+
+// ViewBounds returns the world area covered by the camera (minX, minY, maxX,
+// maxY), enlarged to contain it when the view is rotated.
+func (camera *Camera) ViewBounds() [4]float32 {
+	inverse := camera.Projection.Mul4(camera.View).Inv()
+	bounds := [4]float32{math.MaxFloat32, math.MaxFloat32, -math.MaxFloat32, -math.MaxFloat32}
+	for _, corner := range []mgl32.Vec4{{-1, -1, 0, 1}, {1, -1, 0, 1}, {1, 1, 0, 1}, {-1, 1, 0, 1}} {
+		point := inverse.Mul4x1(corner)
+		bounds[0] = float32(math.Min(float64(bounds[0]), float64(point[0])))
+		bounds[1] = float32(math.Min(float64(bounds[1]), float64(point[1])))
+		bounds[2] = float32(math.Max(float64(bounds[2]), float64(point[0])))
+		bounds[3] = float32(math.Max(float64(bounds[3]), float64(point[1])))
+	}
+	return bounds
+}
+
+func castFloat32List(value interface{}, size int) ([]float32, error) {
+	items, ok := value.([]interface{})
+	if !ok || len(items) != size {
+		return nil, fmt.Errorf("expects a list of %d numbers", size)
+	}
+	var numbers []float32
+	for _, item := range items {
+		number, err := CastFloat32(item)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+// A culling mask is a bitmask of layers, or a list of layers.
+func castCullingMask(value interface{}) (uint32, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return CastUInt32(value)
+	}
+	var mask uint32
+	for _, item := range items {
+		layer, err := CastInt(item)
+		if err != nil || layer < 0 || layer > 31 {
+			return 0, fmt.Errorf("expects layers between 0 and 31")
+		}
+		mask |= 1 << layer
+	}
+	return mask, nil
 }
 
+// Attributes are "size", "zoom", "target", "smoothing", "deadZoneX",
+// "deadZoneY", "bounds" (a list of minX, minY, maxX, maxY, or the name of a
+// GameObject with a TileMap), "rotation" (degrees), "trauma", "addTrauma",
+// "traumaDecay", "shakeOffset", "shakeAngle", "shakeFrequency", "viewport" (a
+// list of x, y, width, height), "depth", "cullingMask" (a bitmask or a list of
+// layers), "clear", "clearColor" (a list of r, g, b, a) and "targetTexture"
+// (the name of a render texture).
 func (camera *Camera) SetAttr(attr string, value interface{}) error {
+	switch attr {
+	case "viewport":
+		viewport, err := castFloat32List(value, 4)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, camera, err)
+		}
+		if viewport[2] <= 0 || viewport[3] <= 0 {
+			return fmt.Errorf("%v attribute of %T requires a positive size", attr, camera)
+		}
+		copy(camera.viewport[:], viewport)
+		return nil
+	case "cullingMask":
+		mask, err := castCullingMask(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, camera, err)
+		}
+		camera.cullingMask = mask
+		return nil
+	case "clear":
+		clear, err := CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, camera, err)
+		}
+		camera.clear = clear
+		return nil
+	case "clearColor":
+		color, err := castFloat32List(value, 4)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, camera, err)
+		}
+		copy(camera.clearColor[:], color)
+		camera.clear = true
+		return nil
+	case "targetTexture":
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, camera)
+		}
+		camera.targetTexture = name
+		// Render textures keep their content otherwise.
+		camera.clear = true
+		return nil
+	case "depth":
+		depth, err := CastInt(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, camera, err)
+		}
+		camera.depth = depth
+		return nil
+	case "target":
+		target, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, camera)
+		}
+		camera.target = target
+		camera.targetObject = nil
+		return nil
+	case "bounds":
+		name, ok := value.(string)
+		if ok {
+			camera.boundsObject = name
+			camera.hasBounds = false
+			return nil
+		}
+		bounds, err := castFloat32List(value, 4)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, camera, err)
+		}
+		camera.SetBounds(bounds[0], bounds[1], bounds[2], bounds[3])
+		return nil
+	}
+
+	number, err := CastFloat32(value)
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, camera, err)
+	}
+
+	switch attr {
+	case "size":
+		camera.size = number
+	case "zoom":
+		if number <= 0 {
+			return fmt.Errorf("%v attribute of %T must be positive", attr, camera)
+		}
+		camera.zoom = number
+	case "smoothing":
+		camera.smoothing = number
+	case "deadZoneX":
+		camera.deadZone[0] = number
+	case "deadZoneY":
+		camera.deadZone[1] = number
+	case "rotation":
+		camera.gameObject.SetEuler(number)
+	case "trauma":
+		camera.trauma = 0
+		camera.AddTrauma(number)
+	case "addTrauma":
+		camera.AddTrauma(number)
+	case "traumaDecay":
+		camera.traumaDecay = number
+	case "shakeOffset":
+		camera.shakeOffset = number
+	case "shakeAngle":
+		camera.shakeAngle = number
+	case "shakeFrequency":
+		camera.shakeFrequency = number
+	default:
+		return fmt.Errorf("%v attribute of %T not found", attr, camera)
+	}
 	return nil
 }
 
+func (camera *Camera) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "size":
+		return camera.size, nil
+	case "zoom":
+		return camera.zoom, nil
+	case "target":
+		return camera.target, nil
+	case "smoothing":
+		return camera.smoothing, nil
+	case "deadZoneX":
+		return camera.deadZone[0], nil
+	case "deadZoneY":
+		return camera.deadZone[1], nil
+	case "bounds":
+		return camera.bounds, nil
+	case "rotation":
+		return camera.gameObject.Rotation * 180 / math.Pi, nil
+	case "trauma":
+		return camera.trauma, nil
+	case "traumaDecay":
+		return camera.traumaDecay, nil
+	case "shakeOffset":
+		return camera.shakeOffset, nil
+	case "shakeAngle":
+		return camera.shakeAngle, nil
+	case "shakeFrequency":
+		return camera.shakeFrequency, nil
+	case "viewport":
+		return camera.viewport, nil
+	case "depth":
+		return camera.depth, nil
+	case "cullingMask":
+		return camera.cullingMask, nil
+	case "clear":
+		return camera.clear, nil
+	case "clearColor":
+		return camera.clearColor, nil
+	case "targetTexture":
+		return camera.targetTexture, nil
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, camera)
+}
+
 func (camera *Camera) GetName() string {
 	return "Camera"
 }
 
-func (camera *Camera) GetAttr(attr string) (interface{}, error) {
-	return 0, nil
+func (camera *Camera) GetType() string {
+	return "Camera"
 }
 
 func NewCamera() *Camera {
-	camera := Camera{}
+	camera := Camera{zoom: 1, viewport: [4]float32{0, 0, 1, 1}, cullingMask: 0xffffffff}
+	camera.traumaDecay = 1
+	camera.shakeOffset = 0.5
+	camera.shakeAngle = 5
+	camera.shakeFrequency = 15
 	return &camera
 }
 
@@ -0,0 +1,85 @@
+package gozmo
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestCameraFollow(t *testing.T) {
+	Engine.Window = &Window{OrthographicSize: 10, AspectRatio: 1}
+	defer func() { Engine.Window = nil }()
+
+	scene := NewScene("Test")
+	player := scene.NewGameObject("Player")
+	cameraObject := scene.NewGameObject("Camera")
+	camera := NewCamera()
+	cameraObject.AddComponent("camera", camera)
+	camera.SetAttr("target", "Player")
+	camera.SetAttr("deadZoneX", 2)
+
+	player.SetPosition(1, 3)
+	camera.Update(cameraObject)
+	if cameraObject.Position[0] != 0 || cameraObject.Position[1] != 3 {
+		t.Error("Expected 0 3, got", cameraObject.Position)
+	}
+
+	camera.SetAttr("bounds", []interface{}{-20.0, -20.0, 20.0, 12.0})
+	camera.SetAttr("zoom", 2)
+	player.SetPosition(30, 30)
+	camera.Update(cameraObject)
+	if camera.OrthographicSize() != 5 {
+		t.Error("Expected 5, got", camera.OrthographicSize())
+	}
+	if cameraObject.Position[0] != 15 || cameraObject.Position[1] != 7 {
+		t.Error("Expected 15 7, got", cameraObject.Position)
+	}
+}
+
+func TestCameraViewports(t *testing.T) {
+	Engine.Window = &Window{width: 200, height: 100, OrthographicSize: 10, AspectRatio: 2}
+	defer func() { Engine.Window = nil }()
+
+	scene := NewScene("Test")
+	sceneManager.SetScene(scene)
+	defer sceneManager.SetScene(nil)
+
+	left := NewCamera()
+	scene.NewGameObject("Left").AddComponent("camera", left)
+	left.SetAttr("viewport", []interface{}{0, 0, 0.5, 1})
+
+	rightObject := scene.NewGameObject("Right")
+	rightObject.SetPosition(100, 0)
+	right := NewCamera()
+	rightObject.AddComponent("camera", right)
+	right.SetAttr("viewport", []interface{}{0.5, 0, 0.5, 1})
+	right.SetAttr("cullingMask", []interface{}{1})
+
+	left.Update(left.gameObject)
+	right.Update(rightObject)
+
+	// The center of each half is the position of its camera.
+	position := Engine.Window.ScreenToWorld(150, 50)
+	if !position.ApproxEqualThreshold(mgl32.Vec2{100, 0}, 1e-4) {
+		t.Error("Expected 100 0, got", position)
+	}
+	position = Engine.Window.ScreenToWorld(50, 50)
+	if !position.ApproxEqualThreshold(mgl32.Vec2{0, 0}, 1e-4) {
+		t.Error("Expected 0 0, got", position)
+	}
+
+	hud := scene.NewGameObject("HUD")
+	hud.SetAttr("", "layer", 1)
+	if left.sees(hud) != true || right.sees(hud) != true || right.sees(left.gameObject) {
+		t.Error("Unexpected culling")
+	}
+}
+
+func TestCameraDefaultSize(t *testing.T) {
+	scene := NewScene("Test")
+	camera := NewCamera()
+	scene.NewGameObject("Camera").AddComponent("camera", camera)
+	if camera.OrthographicSize() != 10 {
+		t.Error("Expected 10 without a window, got", camera.OrthographicSize())
+	}
+}
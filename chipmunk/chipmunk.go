@@ -4,8 +4,10 @@ package chipmunk
 
 import (
 	"fmt"
+	"math"
 
 	goz "github.com/20tab/gozmo"
+	"github.com/go-gl/mathgl/mgl32"
 	"github.com/vova616/chipmunk"
 	"github.com/vova616/chipmunk/vect"
 )
@@ -14,6 +16,9 @@ var space *chipmunk.Space
 
 var Gravity goz.Vector2 = goz.Vector2{0, -9.8}
 
+// The color of the shapes outlined in debug builds.
+var debugColor = mgl32.Vec4{1, 0.5, 0, 1}
+
 func checkSpace() {
 	if space == nil {
 		space = chipmunk.NewSpace()
@@ -35,6 +40,11 @@ func (rbody *RigidBody) Start(gameObject *goz.GameObject) {
 	space.AddBody(rbody.body)
 }
 
+// Destroy removes the body, with its shapes, from the space.
+func (rbody *RigidBody) Destroy(gameObject *goz.GameObject) {
+	space.RemoveBody(rbody.body)
+}
+
 func (rbody *RigidBody) Update(gameObject *goz.GameObject) {
 	if !rbody.initialized {
 		pos := gameObject.Position
@@ -93,6 +103,10 @@ func (sbody *StaticBody) Start(gameObject *goz.GameObject) {
 	space.AddBody(sbody.body)
 }
 
+func (sbody *StaticBody) Destroy(gameObject *goz.GameObject) {
+	space.RemoveBody(sbody.body)
+}
+
 func (sbody *StaticBody) Update(gameObject *goz.GameObject) {
 	if !sbody.initialized {
 		pos := gameObject.Position
@@ -123,6 +137,7 @@ func initStaticBody(args []interface{}) goz.Component {
 type ShapeCircle struct {
 	shape       *chipmunk.CircleShape
 	initialized bool
+	castShadows bool
 }
 
 func (circle *ShapeCircle) Start(gameObject *goz.GameObject) {
@@ -165,12 +180,44 @@ func (circle *ShapeCircle) Update(gameObject *goz.GameObject) {
 	}
 }
 
+func (circle *ShapeCircle) DebugOutline(gameObject *goz.GameObject) {
+	if !goz.DebugOutlines(goz.DebugPhysics) {
+		return
+	}
+	x, y := gameObject.Position[0], gameObject.Position[1]
+	radius := float32(circle.shape.Radius)
+	goz.DebugCircle(x, y, radius, debugColor, 0)
+	// The radius shows the rotation.
+	angle := float64(gameObject.Rotation)
+	goz.DebugLine(x, y, x+radius*float32(math.Cos(angle)), y+radius*float32(math.Sin(angle)), debugColor, 0)
+}
+
+// The shadows of circles are cast by a 16-sided polygon.
+func (circle *ShapeCircle) ShadowPolygons(gameObject *goz.GameObject) [][]mgl32.Vec2 {
+	if !circle.castShadows {
+		return nil
+	}
+	radius := float64(circle.shape.Radius)
+	polygon := make([]mgl32.Vec2, 16)
+	for i := range polygon {
+		angle := float64(i) * 2 * math.Pi / float64(len(polygon))
+		polygon[i] = mgl32.Vec2{float32(radius * math.Cos(angle)), float32(radius * math.Sin(angle))}.Add(gameObject.Position)
+	}
+	return [][]mgl32.Vec2{polygon}
+}
+
 func (circle *ShapeCircle) SetAttr(attr string, value interface{}) error {
 	switch attr {
 	case "radius":
 		radius, _ := goz.CastFloat32(value)
 		circle.shape.Radius = vect.Float(radius)
 		circle.shape.Shape.Update()
+	case "castShadows":
+		flag, err := goz.CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T expects a bool", attr, circle)
+		}
+		circle.castShadows = flag
 	}
 	return nil
 }
@@ -179,6 +226,8 @@ func (circle *ShapeCircle) GetAttr(attr string) (interface{}, error) {
 	switch attr {
 	case "radius":
 		return float32(circle.shape.Radius), nil
+	case "castShadows":
+		return circle.castShadows, nil
 	}
 	return nil, fmt.Errorf("%v attribute of %T not found", attr, circle)
 }
@@ -199,6 +248,7 @@ func initShapeCircle(args []interface{}) goz.Component {
 type ShapeBox struct {
 	shape       *chipmunk.BoxShape
 	initialized bool
+	castShadows bool
 }
 
 func (box *ShapeBox) Start(gameObject *goz.GameObject) {
@@ -241,6 +291,36 @@ func (box *ShapeBox) Update(gameObject *goz.GameObject) {
 	}
 }
 
+// corners returns the rotated box in world coordinates.
+func (box *ShapeBox) corners(gameObject *goz.GameObject) []mgl32.Vec2 {
+	halfWidth := float32(box.shape.Width) / 2
+	halfHeight := float32(box.shape.Height) / 2
+	rotation := mgl32.Rotate2D(gameObject.Rotation)
+	corners := []mgl32.Vec2{{-halfWidth, -halfHeight}, {halfWidth, -halfHeight}, {halfWidth, halfHeight}, {-halfWidth, halfHeight}}
+	for i, corner := range corners {
+		corners[i] = rotation.Mul2x1(corner).Add(gameObject.Position)
+	}
+	return corners
+}
+
+func (box *ShapeBox) DebugOutline(gameObject *goz.GameObject) {
+	if !goz.DebugOutlines(goz.DebugPhysics) {
+		return
+	}
+	corners := box.corners(gameObject)
+	for i, from := range corners {
+		to := corners[(i+1)%len(corners)]
+		goz.DebugLine(from[0], from[1], to[0], to[1], debugColor, 0)
+	}
+}
+
+func (box *ShapeBox) ShadowPolygons(gameObject *goz.GameObject) [][]mgl32.Vec2 {
+	if !box.castShadows {
+		return nil
+	}
+	return [][]mgl32.Vec2{box.corners(gameObject)}
+}
+
 func (box *ShapeBox) SetAttr(attr string, value interface{}) error {
 	switch attr {
 	case "width":
@@ -251,6 +331,12 @@ func (box *ShapeBox) SetAttr(attr string, value interface{}) error {
 		h, _ := goz.CastFloat32(value)
 		box.shape.Height = vect.Float(h)
 		box.shape.UpdatePoly()
+	case "castShadows":
+		flag, err := goz.CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T expects a bool", attr, box)
+		}
+		box.castShadows = flag
 	}
 	return nil
 }
@@ -261,6 +347,8 @@ func (box *ShapeBox) GetAttr(attr string) (interface{}, error) {
 		return float32(box.shape.Width), nil
 	case "height":
 		return float32(box.shape.Height), nil
+	case "castShadows":
+		return box.castShadows, nil
 	}
 	return nil, fmt.Errorf("%v attribute of %T not found", attr, box)
 }
@@ -276,11 +364,16 @@ func initShapeBox(args []interface{}) goz.Component {
 	return NewShapeBox()
 }
 
-// updateWorld is called at each world update.
+// updateWorld is called at each world update. The space is shared by the
+// loaded scenes, so it only steps with the main one.
 func updateWorld(scene *goz.Scene, deltaTime float32) {
 	if space == nil {
 		return
 	}
+	active := goz.DefaultSceneManager().ActiveScene()
+	if active != nil && scene != active {
+		return
+	}
 	space.Step(vect.Float(deltaTime))
 }
 
@@ -0,0 +1,85 @@
+// The gozmopack command packs asset directories into an archive, which games
+// mount with DefaultFileSystem().MountArchive. The files keep their paths:
+//
+//	gozmopack -o assets.zip assets scenes
+//
+// Compressed formats (PNG, JPEG, Ogg) are stored as they are.
+package main
+
+import (
+	"archive/zip"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	output := flag.String("o", "assets.zip", "the archive to write")
+	root := flag.String("C", ".", "the directory the paths are relative to")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: gozmopack [-o archive] [-C dir] dir...")
+		os.Exit(2)
+	}
+
+	err := pack(*output, os.DirFS(*root), flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func pack(archiveName string, fsys fs.FS, dirs []string) error {
+	file, err := os.Create(archiveName)
+	if err != nil {
+		return err
+	}
+	archive := zip.NewWriter(file)
+	for _, dir := range dirs {
+		dir = path.Clean(filepath.ToSlash(dir))
+		err = fs.WalkDir(fsys, dir, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			return packFile(archive, fsys, name)
+		})
+		if err != nil {
+			break
+		}
+	}
+	closeErr := archive.Close()
+	if err == nil {
+		err = closeErr
+	}
+	closeErr = file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func packFile(archive *zip.Writer, fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	header := zip.FileHeader{Name: name, Method: zip.Deflate}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".png", ".jpg", ".jpeg", ".ogg":
+		header.Method = zip.Store
+	}
+	info, err := fs.Stat(fsys, name)
+	if err == nil {
+		header.Modified = info.ModTime()
+	}
+	writer, err := archive.CreateHeader(&header)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
@@ -22,6 +22,12 @@ type ComponentDestroy interface {
 	Destroy(gameObject *GameObject)
 }
 
+// Components drawing something implement ComponentDraw, which is called by
+// every camera seeing the GameObject, after all of the updates.
+type ComponentDraw interface {
+	Draw(gameObject *GameObject, camera *Camera)
+}
+
 type ComponentType interface {
 	GetType() string
 }
@@ -0,0 +1,268 @@
+package gozmo
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// A Coroutine is a scripted behaviour attached to a GameObject that can
+// suspend itself with the Wait* methods, avoiding hand-written state machines
+// in Component.Update.
+//
+// Each coroutine body runs in its own goroutine, but control is handed back
+// and forth with the main loop so that only one of them is running at any
+// given time: the engine state is never accessed concurrently. Coroutines are
+// stepped by Scene.Update after the components of their GameObject, and are
+// cancelled when the GameObject is disabled or destroyed.
+//
+// As the body does not run on the locked OS thread, the engine work needing it
+// (loading textures and scenes, destroying textures, the GL wrappers) goes
+// through Call, which runs it on the main goroutine; setting attributes and
+// adding components is fine. A panic in the body ends the coroutine and is
+// raised again on the main goroutine, by the Scene.Update stepping it.
+//
+// A waiting coroutine keeps its goroutine until it is cancelled, which
+// destroying its GameObject (or its scene) does.
+type Coroutine struct {
+	gameObject *GameObject
+	// true resumes the body, false cancels it.
+	resume chan bool
+	yield  chan bool
+	// Functions of the body to run on the main goroutine, see Call.
+	calls  chan func()
+	called chan interface{}
+	// The panic of the body, raised again by step.
+	panicked interface{}
+
+	running   bool
+	cancelled bool
+	done      bool
+
+	wait     coroutineWait
+	seconds  float32
+	frames   int
+	event    string
+	received *Event
+	cond     func() bool
+}
+
+type coroutineWait int
+
+const (
+	waitNone coroutineWait = iota
+	waitSeconds
+	waitFrames
+	waitEvent
+	waitUntil
+)
+
+// StartCoroutine runs body immediately until its first wait, then keeps
+// stepping it once per frame.
+func (gameObject *GameObject) StartCoroutine(body func(co *Coroutine)) *Coroutine {
+	co := Coroutine{gameObject: gameObject}
+	co.resume = make(chan bool)
+	co.yield = make(chan bool)
+	co.calls = make(chan func())
+	co.called = make(chan interface{})
+
+	gameObject.coroutines = append(gameObject.coroutines, &co)
+
+	go func() {
+		// Always give control back, even when cancelled via Goexit (which
+		// recover ignores).
+		defer func() {
+			r := recover()
+			if r != nil {
+				stack := make([]byte, 8192)
+				stack = stack[:runtime.Stack(stack, false)]
+				co.panicked = fmt.Sprintf("coroutine of %v: %v\n%s", gameObject.Name, r, stack)
+			}
+			co.done = true
+			co.yield <- true
+		}()
+		if !<-co.resume {
+			return
+		}
+		body(&co)
+	}()
+
+	co.step(true)
+	return &co
+}
+
+// StopCoroutine cancels a running coroutine. Deferred functions in its body
+// are executed.
+func (gameObject *GameObject) StopCoroutine(co *Coroutine) {
+	co.Stop()
+}
+
+// StopAllCoroutines cancels every coroutine of the GameObject.
+func (gameObject *GameObject) StopAllCoroutines() {
+	for _, co := range gameObject.coroutines {
+		co.Stop()
+	}
+	gameObject.coroutines = nil
+}
+
+// Stop cancels the coroutine. A coroutine stopping itself terminates at its
+// next wait.
+func (co *Coroutine) Stop() {
+	if co.done || co.cancelled {
+		return
+	}
+	co.cancelled = true
+	// We are inside the body itself, we cannot hand off to ourselves.
+	if co.running {
+		return
+	}
+	co.step(false)
+}
+
+// Done reports whether the coroutine has finished or has been cancelled.
+func (co *Coroutine) Done() bool {
+	return co.done
+}
+
+func (co *Coroutine) GetGameObject() *GameObject {
+	return co.gameObject
+}
+
+// WaitSeconds suspends the coroutine for the given amount of game time.
+func (co *Coroutine) WaitSeconds(seconds float32) {
+	co.wait = waitSeconds
+	co.seconds = seconds
+	co.suspend()
+}
+
+// WaitFrames suspends the coroutine for the given number of frames.
+func (co *Coroutine) WaitFrames(frames int) {
+	co.wait = waitFrames
+	co.frames = frames
+	co.suspend()
+}
+
+// Yield suspends the coroutine until the next frame.
+func (co *Coroutine) Yield() {
+	co.WaitFrames(1)
+}
+
+// WaitForEvent suspends the coroutine until an event with the given message
+// is received by its GameObject, and returns it.
+func (co *Coroutine) WaitForEvent(msg string) *Event {
+	co.wait = waitEvent
+	co.event = msg
+	co.received = nil
+	co.suspend()
+	event := co.received
+	co.received = nil
+	return event
+}
+
+// WaitUntil suspends the coroutine until cond returns true. The condition is
+// checked once per frame on the main loop.
+func (co *Coroutine) WaitUntil(cond func() bool) {
+	co.wait = waitUntil
+	co.cond = cond
+	co.suspend()
+}
+
+// Call runs fn on the main goroutine, which is waiting for the body, and
+// returns once it is done. A panic of fn is raised again in the body.
+func (co *Coroutine) Call(fn func()) {
+	co.calls <- fn
+	r := <-co.called
+	if r != nil {
+		panic(r)
+	}
+}
+
+// step hands control to the body and blocks until it waits or ends, running
+// its calls meanwhile.
+func (co *Coroutine) step(proceed bool) {
+	co.running = true
+	co.resume <- proceed
+	for waiting := true; waiting; {
+		select {
+		case fn := <-co.calls:
+			co.called <- co.call(fn)
+		case <-co.yield:
+			waiting = false
+		}
+	}
+	co.running = false
+	if co.panicked != nil {
+		r := co.panicked
+		co.panicked = nil
+		panic(r)
+	}
+}
+
+// call runs a function of the body, returning its panic.
+func (co *Coroutine) call(fn func()) (r interface{}) {
+	defer func() {
+		r = recover()
+	}()
+	fn()
+	return nil
+}
+
+// suspend hands control back to the main loop and blocks until resumed.
+func (co *Coroutine) suspend() {
+	if co.cancelled {
+		runtime.Goexit()
+	}
+	co.yield <- true
+	if !<-co.resume {
+		runtime.Goexit()
+	}
+	co.wait = waitNone
+}
+
+// ready checks (and advances) the wait condition of the coroutine.
+func (co *Coroutine) ready(deltaTime float32) bool {
+	switch co.wait {
+	case waitSeconds:
+		co.seconds -= deltaTime
+		return co.seconds <= 0
+	case waitFrames:
+		co.frames--
+		return co.frames <= 0
+	case waitEvent:
+		return co.received != nil
+	case waitUntil:
+		return co.cond()
+	}
+	return true
+}
+
+// deliverEvent wakes up coroutines waiting for the event.
+func (gameObject *GameObject) deliverEvent(event *Event) {
+	for _, co := range gameObject.coroutines {
+		if co.wait == waitEvent && co.received == nil && co.event == event.Msg {
+			co.received = event
+		}
+	}
+}
+
+// UpdateCoroutines steps every coroutine whose wait is over, and forgets the
+// finished ones.
+func (gameObject *GameObject) UpdateCoroutines() {
+	// Coroutines started during this loop already ran until their first wait.
+	coroutines := gameObject.coroutines
+	for _, co := range coroutines {
+		if co.done || co.cancelled {
+			continue
+		}
+		if co.ready(gameObject.DeltaTime) {
+			co.step(true)
+		}
+	}
+
+	alive := gameObject.coroutines[:0]
+	for _, co := range gameObject.coroutines {
+		if !co.done {
+			alive = append(alive, co)
+		}
+	}
+	gameObject.coroutines = alive
+}
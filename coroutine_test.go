@@ -0,0 +1,161 @@
+package gozmo
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCoroutineWaitFrames(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	steps := 0
+	gameObject.StartCoroutine(func(co *Coroutine) {
+		steps++
+		co.WaitFrames(2)
+		steps++
+	})
+	if steps != 1 {
+		t.Error("Expected 1, got", steps)
+	}
+	scene.Update(0)
+	if steps != 1 {
+		t.Error("Expected 1, got", steps)
+	}
+	scene.Update(0)
+	if steps != 2 {
+		t.Error("Expected 2, got", steps)
+	}
+	if len(gameObject.coroutines) != 0 {
+		t.Error("Expected 0, got", len(gameObject.coroutines))
+	}
+}
+
+func TestCoroutineWaitSeconds(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	done := false
+	gameObject.StartCoroutine(func(co *Coroutine) {
+		co.WaitSeconds(1)
+		done = true
+	})
+	scene.Update(0.5)
+	if done {
+		t.Error("Expected false, got", done)
+	}
+	scene.Update(1.0)
+	if !done {
+		t.Error("Expected true, got", done)
+	}
+}
+
+func TestCoroutineWaitForEvent(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject001 := scene.NewGameObject("Object 1")
+	gameObject002 := scene.NewGameObject("Object 2")
+	var sender *GameObject
+	gameObject001.StartCoroutine(func(co *Coroutine) {
+		event := co.WaitForEvent("hit")
+		sender = event.Sender
+	})
+	gameObject001.EnqueueEvent(gameObject002, "miss")
+	scene.Update(0)
+	if sender != nil {
+		t.Error("Expected nil, got", sender)
+	}
+	gameObject001.EnqueueEvent(gameObject002, "hit")
+	scene.Update(0)
+	if sender != gameObject002 {
+		t.Error("Expected", gameObject002, "got", sender)
+	}
+}
+
+func TestCoroutineCancelOnDisable(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	cleaned := false
+	reached := false
+	co := gameObject.StartCoroutine(func(co *Coroutine) {
+		defer func() { cleaned = true }()
+		co.WaitUntil(func() bool { return false })
+		reached = true
+	})
+	gameObject.SetEnabled(false)
+	if !co.Done() {
+		t.Error("Expected true, got", co.Done())
+	}
+	if !cleaned {
+		t.Error("Expected true, got", cleaned)
+	}
+	if reached {
+		t.Error("Expected false, got", reached)
+	}
+}
+
+func TestCoroutineSceneDestroy(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	cleaned := false
+	co := gameObject.StartCoroutine(func(co *Coroutine) {
+		defer func() { cleaned = true }()
+		co.WaitForEvent("never")
+	})
+	// The goroutine ends with the scene.
+	scene.Destroy()
+	if !co.Done() || !cleaned {
+		t.Error("Expected the coroutine to be cancelled")
+	}
+}
+
+// goroutineName is the header of the stack of the current goroutine, like
+// "goroutine 7".
+func goroutineName() string {
+	buffer := make([]byte, 64)
+	buffer = buffer[:runtime.Stack(buffer, false)]
+	return strings.SplitN(string(buffer), " [", 2)[0]
+}
+
+func TestCoroutineCall(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	main := goroutineName()
+	var body, called string
+	co := gameObject.StartCoroutine(func(co *Coroutine) {
+		body = goroutineName()
+		co.Yield()
+		co.Call(func() { called = goroutineName() })
+	})
+	if called != "" || body == main {
+		t.Fatal("Expected the body on its own goroutine")
+	}
+	scene.Update(0)
+	if called != main || !co.Done() {
+		t.Error("Expected the call on the main goroutine, got", called, main)
+	}
+}
+
+func TestCoroutinePanic(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	cleaned := false
+	co := gameObject.StartCoroutine(func(co *Coroutine) {
+		defer func() { cleaned = true }()
+		co.Yield()
+		co.Call(func() { panic("missing texture") })
+	})
+
+	// Raised on the main goroutine by the frame stepping the coroutine.
+	func() {
+		defer func() {
+			r := recover()
+			message, _ := r.(string)
+			if !strings.Contains(message, "missing texture") {
+				t.Error("Expected the panic of the call, got", r)
+			}
+		}()
+		scene.Update(0)
+	}()
+	if !co.Done() || !cleaned {
+		t.Error("Expected the coroutine to end")
+	}
+}
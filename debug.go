@@ -0,0 +1,27 @@
+package gozmo
+
+// Debug drawing shows lines, shapes and text over the scene, for a frame or a
+// number of seconds, to inspect colliders, cameras and gameplay values. Every
+// primitive is batched and drawn after the GameObjects of each window camera.
+//
+// Outlines of the engine objects (HitBoxes, physics shapes, camera views and
+// TileMaps) are enabled by category with SetDebugOutlines. Building with the
+// "release" tag turns every debug function into a no-op.
+
+// DebugFlags select the categories of automatic outlines.
+type DebugFlags uint32
+
+const (
+	DebugHitBoxes DebugFlags = 1 << iota
+	DebugPhysics
+	DebugCameras
+	DebugTileMaps
+
+	DebugAll = DebugHitBoxes | DebugPhysics | DebugCameras | DebugTileMaps
+)
+
+// A DebugOutliner is a component drawing its own outline (with the Debug
+// functions) when the outlines are enabled.
+type DebugOutliner interface {
+	DebugOutline(gameObject *GameObject)
+}
@@ -0,0 +1,214 @@
+// +build !release
+
+package gozmo
+
+import (
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type debugLine struct {
+	from, to mgl32.Vec2
+	color    mgl32.Vec4
+	expire   float64
+	scene    *Scene
+}
+
+type debugText struct {
+	position mgl32.Vec2
+	text     string
+	color    mgl32.Vec4
+	expire   float64
+	scene    *Scene
+}
+
+var debug struct {
+	outlines DebugFlags
+	lines    []debugLine
+	texts    []debugText
+	// The time of the last drawn frame.
+	now float64
+	// The scene running its frame, whose cameras draw the new primitives.
+	scene *Scene
+
+	lineMesh *Mesh
+	textMesh *Mesh
+}
+
+// Debug builds support drawing.
+const DebugEnabled = true
+
+func SetDebugOutlines(flags DebugFlags) {
+	debug.outlines = flags
+}
+
+// DebugOutlines checks if all of the categories are outlined.
+func DebugOutlines(flags DebugFlags) bool {
+	return debug.outlines&flags == flags
+}
+
+// DebugLine draws a segment in world coordinates, a zero duration keeps it
+// for a single frame.
+func DebugLine(x1, y1, x2, y2 float32, color mgl32.Vec4, duration float32) {
+	debug.lines = append(debug.lines, debugLine{mgl32.Vec2{x1, y1}, mgl32.Vec2{x2, y2}, color, debug.now + float64(duration), debug.scene})
+}
+
+func DebugRect(minX, minY, maxX, maxY float32, color mgl32.Vec4, duration float32) {
+	DebugLine(minX, minY, maxX, minY, color, duration)
+	DebugLine(maxX, minY, maxX, maxY, color, duration)
+	DebugLine(maxX, maxY, minX, maxY, color, duration)
+	DebugLine(minX, maxY, minX, minY, color, duration)
+}
+
+// Circles are drawn as polygons of 32 sides.
+func DebugCircle(x, y, radius float32, color mgl32.Vec4, duration float32) {
+	const sides = 32
+	previous := mgl32.Vec2{x + radius, y}
+	for i := 1; i <= sides; i++ {
+		angle := float64(i) * 2 * math.Pi / sides
+		point := mgl32.Vec2{x + radius*float32(math.Cos(angle)), y + radius*float32(math.Sin(angle))}
+		DebugLine(previous[0], previous[1], point[0], point[1], color, duration)
+		previous = point
+	}
+}
+
+// DebugText writes a string with a built-in font, its top left corner at
+// the point, at the same size on screen regardless of the camera zoom.
+func DebugText(x, y float32, text string, color mgl32.Vec4, duration float32) {
+	debug.texts = append(debug.texts, debugText{mgl32.Vec2{x, y}, text, color, debug.now + float64(duration), debug.scene})
+}
+
+// setDebugScene sets the scene owning the primitives drawn from now on.
+func setDebugScene(scene *Scene) {
+	debug.scene = scene
+}
+
+// collectDebugOutlines asks the components to outline themselves for the
+// current frame.
+func collectDebugOutlines(scene *Scene) {
+	debug.scene = scene
+	if debug.outlines == 0 {
+		return
+	}
+	for _, gameObject := range scene.gameObjects {
+		if !gameObject.enabled {
+			continue
+		}
+		for _, component := range gameObject.components {
+			outliner, ok := component.(DebugOutliner)
+			if ok {
+				outliner.DebugOutline(gameObject)
+			}
+		}
+	}
+}
+
+// drawDebug draws the primitives of a scene with the view of one of its
+// cameras, over the surface it has just rendered.
+func drawDebug(scene *Scene, camera *Camera, surface *Texture) {
+	if (len(debug.lines) == 0 && len(debug.texts) == 0) || !compileParticleProgram() {
+		return
+	}
+
+	if debug.lineMesh == nil {
+		debug.lineMesh = newDynamicMesh()
+		debug.textMesh = newDynamicMesh()
+	}
+
+	setBlendMode(BlendAlpha)
+	GLUseProgram(particleProgram.id)
+	GLUniform(particleProgram.ortho, camera.Projection.Mul4(camera.View))
+
+	if len(debug.lines) > 0 {
+		mesh := debug.lineMesh
+		mesh.vertices = mesh.vertices[:0]
+		mesh.uvs = mesh.uvs[:0]
+		mesh.colors = mesh.colors[:0]
+		for _, line := range debug.lines {
+			if line.scene != nil && line.scene != scene {
+				continue
+			}
+			mesh.vertices = append(mesh.vertices, line.from[0], line.from[1], line.to[0], line.to[1])
+			mesh.uvs = append(mesh.uvs, 0, 0, 0, 0)
+			color := line.color
+			mesh.colors = append(mesh.colors, color[0], color[1], color[2], color[3], color[0], color[1], color[2], color[3])
+		}
+		if len(mesh.vertices) > 0 {
+			mesh.uploadDynamic()
+			GLUniform(particleProgram.textured, float32(0))
+			IncPerFrameStats("GL.DrawCalls", 1)
+			GLDrawLines(mesh)
+		}
+	}
+
+	if len(debug.texts) > 0 {
+		drawDebugTexts(scene, camera, surface)
+	}
+}
+
+func drawDebugTexts(scene *Scene, camera *Camera, surface *Texture) {
+	font := builtinFont()
+	texture := fontTexture(font)
+
+	// Font pixels match the pixels of the camera viewport.
+	surfaceHeight := float32(Engine.Window.framebufferHeight)
+	if surface != nil {
+		surfaceHeight = float32(surface.Height)
+	}
+	scale := camera.OrthographicSize() * 2 / (camera.viewport[3] * surfaceHeight)
+
+	textureWidth := float32(texture.Width)
+	textureHeight := float32(texture.Height)
+
+	mesh := debug.textMesh
+	mesh.vertices = mesh.vertices[:0]
+	mesh.uvs = mesh.uvs[:0]
+	mesh.colors = mesh.colors[:0]
+	for _, text := range debug.texts {
+		if text.scene != nil && text.scene != scene {
+			continue
+		}
+		glyphs, _, _ := font.layout(text.text, 0, TextLeft, 1)
+		for _, placed := range glyphs {
+			glyph := placed.glyph
+			left := text.position[0] + placed.x*scale
+			top := text.position[1] - placed.y*scale
+			mesh.vertices, mesh.uvs = appendQuad(mesh.vertices, mesh.uvs, left, top-glyph.Height*scale, left+glyph.Width*scale, top,
+				glyph.X/textureWidth, glyph.Y/textureHeight,
+				(glyph.X+glyph.Width)/textureWidth, (glyph.Y+glyph.Height)/textureHeight)
+			for i := 0; i < 6; i++ {
+				mesh.colors = append(mesh.colors, text.color[0], text.color[1], text.color[2], text.color[3])
+			}
+		}
+	}
+	if len(mesh.vertices) == 0 {
+		return
+	}
+	mesh.uploadDynamic()
+	GLUniform(particleProgram.textured, float32(1))
+	IncPerFrameStats("GL.DrawCalls", 1)
+	GLDrawMesh(mesh, texture.tid)
+}
+
+// endDebugFrame removes the primitives expired at the end of a frame, once
+// all of the scenes are drawn.
+func endDebugFrame(now float64) {
+	debug.now = now
+
+	lines := debug.lines[:0]
+	for _, line := range debug.lines {
+		if line.expire > debug.now {
+			lines = append(lines, line)
+		}
+	}
+	debug.lines = lines
+
+	texts := debug.texts[:0]
+	for _, text := range debug.texts {
+		if text.expire > debug.now {
+			texts = append(texts, text)
+		}
+	}
+	debug.texts = texts
+}
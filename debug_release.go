@@ -0,0 +1,41 @@
+// +build release
+
+package gozmo
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Release builds strip debug drawing.
+const DebugEnabled = false
+
+func SetDebugOutlines(flags DebugFlags) {
+}
+
+func DebugOutlines(flags DebugFlags) bool {
+	return false
+}
+
+func DebugLine(x1, y1, x2, y2 float32, color mgl32.Vec4, duration float32) {
+}
+
+func DebugRect(minX, minY, maxX, maxY float32, color mgl32.Vec4, duration float32) {
+}
+
+func DebugCircle(x, y, radius float32, color mgl32.Vec4, duration float32) {
+}
+
+func DebugText(x, y float32, text string, color mgl32.Vec4, duration float32) {
+}
+
+func collectDebugOutlines(scene *Scene) {
+}
+
+func setDebugScene(scene *Scene) {
+}
+
+func drawDebug(scene *Scene, camera *Camera, surface *Texture) {
+}
+
+func endDebugFrame(now float64) {
+}
@@ -0,0 +1,91 @@
+// +build !release
+
+package gozmo
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestDebugExpiration(t *testing.T) {
+	scene := NewScene("Test")
+	debug.lines = nil
+	debug.now = 0
+
+	DebugRect(0, 0, 1, 1, mgl32.Vec4{1, 1, 1, 1}, 0)
+	DebugLine(0, 0, 1, 1, mgl32.Vec4{1, 1, 1, 1}, 1)
+	if len(debug.lines) != 5 {
+		t.Fatal("Expected 5 lines, got", len(debug.lines))
+	}
+
+	// Single frame primitives are removed after the first frame.
+	scene.Update(0.5)
+	if len(debug.lines) != 1 {
+		t.Error("Expected 1 line, got", len(debug.lines))
+	}
+	scene.Update(1)
+	if len(debug.lines) != 0 {
+		t.Error("Expected no lines, got", len(debug.lines))
+	}
+}
+
+func TestDebugAdditiveScenes(t *testing.T) {
+	manager := SceneManager{}
+	level := NewScene("Level")
+	hud := NewScene("HUD")
+	manager.SetScene(level)
+	manager.LoadScene(hud, LoadAdditive, Transition{})
+	debug.lines = nil
+	debug.now = 0
+
+	// The primitives belong to the scene running its frame.
+	drawer := &debugDrawer{}
+	hud.NewGameObject("Drawer").AddComponent("drawer", drawer)
+	manager.update(1)
+	if len(debug.lines) != 0 {
+		t.Error("Expected the single frame line to expire, got", len(debug.lines))
+	}
+	drawer.duration = 2
+	manager.update(1.5)
+	if len(debug.lines) != 1 || debug.lines[0].scene != hud {
+		t.Fatal("Expected a line of HUD, got", debug.lines)
+	}
+	manager.update(2)
+	if len(debug.lines) != 2 {
+		t.Error("Expected 2 lines, got", len(debug.lines))
+	}
+	debug.lines = nil
+}
+
+type debugDrawer struct {
+	duration float32
+}
+
+func (drawer *debugDrawer) Start(gameObject *GameObject) {}
+
+func (drawer *debugDrawer) Update(gameObject *GameObject) {
+	if drawer.duration > 0 {
+		DebugLine(0, 0, 1, 1, mgl32.Vec4{1, 1, 1, 1}, drawer.duration)
+	}
+}
+
+func TestDebugOutlines(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Player")
+	hitbox := NewHitBox(0, 0, 2, 2)
+	gameObject.AddComponent("hitbox", hitbox)
+	debug.lines = nil
+
+	collectDebugOutlines(scene)
+	if len(debug.lines) != 0 {
+		t.Error("Expected no outlines, got", len(debug.lines))
+	}
+
+	SetDebugOutlines(DebugHitBoxes)
+	defer SetDebugOutlines(0)
+	collectDebugOutlines(scene)
+	if len(debug.lines) != 4 || debug.lines[0].from != (mgl32.Vec2{-1, -1}) {
+		t.Error("Expected the outline of the HitBox, got", debug.lines)
+	}
+}
@@ -9,6 +9,7 @@ type EngineSingleton struct {
 	registeredUpdaters   []func(scene *Scene, deltaTime float32)
 	scenes               map[string]*Scene
 	perFrameStats        map[string]float64
+	globalStats          map[string]float64
 }
 
 var Engine EngineSingleton
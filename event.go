@@ -6,6 +6,8 @@ package gozmo
 type Event struct {
 	Sender *GameObject
 	Msg    string
+	// Optional payload, like the key of a "keyDown" event.
+	Data interface{}
 }
 
 type ComponentEvent interface {
@@ -17,10 +19,16 @@ func (gameObject *GameObject) EnqueueEvent(sender *GameObject, msg string) {
 	gameObject.events = append(gameObject.events, &event)
 }
 
+func (gameObject *GameObject) EnqueueEventWithData(sender *GameObject, msg string, data interface{}) {
+	event := Event{Sender: sender, Msg: msg, Data: data}
+	gameObject.events = append(gameObject.events, &event)
+}
+
 // iterate the GameObject event queue and call OnEvent on
 // each component implementing it
 func (gameObject *GameObject) ManageEvents() {
 	for _, event := range gameObject.events {
+		gameObject.deliverEvent(event)
 		for _, componentName := range gameObject.componentsKeys {
 			component := gameObject.components[componentName]
 			componentEvent, ok := component.(ComponentEvent)
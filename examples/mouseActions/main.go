@@ -11,7 +11,9 @@ type ShowPos struct {
 
 func (showPos *ShowPos) Start(gameObject *goz.GameObject) {}
 func (showPos *ShowPos) Update(gameObject *goz.GameObject) {
-	fmt.Println("x =", showPos.mouse.X())
+	if showPos.mouse.GetButtonDown(goz.MouseButtonLeft) {
+		fmt.Println("x =", showPos.mouse.X(), "y =", showPos.mouse.Y())
+	}
 }
 
 func main() {
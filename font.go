@@ -0,0 +1,451 @@
+package gozmo
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// A Font is a texture atlas of glyphs, loaded from a BMFont (text format)
+// file or rasterized from a TrueType/OpenType font. Sizes are in pixels, Y
+// grows downward from the top of the line.
+type Font struct {
+	Name       string
+	LineHeight float32
+	// Distance of the baseline from the top of the line.
+	Base float32
+
+	texture *Texture
+	glyphs  map[rune]Glyph
+	kerning map[[2]rune]float32
+	// Vector fonts compute kerning on demand.
+	face font.Face
+}
+
+// A Glyph is a rectangle of the atlas, placed relatively to the pen position
+// at the top of the line.
+type Glyph struct {
+	X, Y, Width, Height float32
+	XOffset, YOffset    float32
+	XAdvance            float32
+}
+
+// The characters rasterized when a vector font is loaded without a charset:
+// printable ASCII and Latin-1.
+var DefaultFontCharset = func() string {
+	var runes []rune
+	for r := rune(32); r < 127; r++ {
+		runes = append(runes, r)
+	}
+	for r := rune(160); r < 256; r++ {
+		runes = append(runes, r)
+	}
+	return string(runes)
+}()
+
+func (scene *Scene) GetFont(name string) *Font {
+	font, ok := scene.fonts[name]
+	if !ok {
+		return nil
+	}
+	return font
+}
+
+func (font *Font) Texture() *Texture {
+	return font.texture
+}
+
+func (font *Font) Glyph(r rune) (Glyph, bool) {
+	glyph, ok := font.glyphs[r]
+	return glyph, ok
+}
+
+// Kern returns the horizontal adjustment between two characters.
+func (font *Font) Kern(previous, current rune) float32 {
+	if font.face != nil {
+		return fixedToFloat32(font.face.Kern(previous, current))
+	}
+	return font.kerning[[2]rune{previous, current}]
+}
+
+func fixedToFloat32(value fixed.Int26_6) float32 {
+	return float32(value) / 64
+}
+
+// NewFontFromBMFont loads an AngelCode BMFont in text format with its (single)
+// page texture, searched in the directory of the font file.
+func (scene *Scene) NewFontFromBMFont(name string, fileName string) (*Font, error) {
+	file, err := fileSystem.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	font, page, err := parseBMFont(file)
+	if err != nil {
+		return nil, fmt.Errorf("font %v %v", fileName, err)
+	}
+	font.Name = name
+
+	texture, err := scene.NewTextureFromFilename(name+".font", filepath.Join(filepath.Dir(fileName), page))
+	if err != nil {
+		return nil, err
+	}
+	font.texture = texture
+
+	scene.fonts[name] = font
+	return font, nil
+}
+
+// bmFontValues splits a BMFont line in its tag and key=value pairs.
+func bmFontValues(line string) (string, map[string]string) {
+	values := make(map[string]string)
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", values
+	}
+	// Quoted values can contain spaces.
+	var key string
+	var quoted []string
+	for _, field := range fields[1:] {
+		if quoted != nil {
+			quoted = append(quoted, field)
+			if strings.HasSuffix(field, "\"") {
+				values[key] = strings.Trim(strings.Join(quoted, " "), "\"")
+				quoted = nil
+			}
+			continue
+		}
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key = parts[0]
+		if strings.HasPrefix(parts[1], "\"") && (len(parts[1]) == 1 || !strings.HasSuffix(parts[1], "\"")) {
+			quoted = []string{parts[1]}
+			continue
+		}
+		values[key] = strings.Trim(parts[1], "\"")
+	}
+	return fields[0], values
+}
+
+func bmFontNumber(values map[string]string, key string) float32 {
+	number, _ := strconv.ParseFloat(values[key], 32)
+	return float32(number)
+}
+
+// parseBMFont reads the glyphs and kerning pairs, returning the file name of
+// the first page.
+func parseBMFont(reader io.Reader) (*Font, string, error) {
+	font := Font{glyphs: make(map[rune]Glyph), kerning: make(map[[2]rune]float32)}
+	var page string
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		tag, values := bmFontValues(scanner.Text())
+		switch tag {
+		case "common":
+			font.LineHeight = bmFontNumber(values, "lineHeight")
+			font.Base = bmFontNumber(values, "base")
+			if bmFontNumber(values, "pages") > 1 {
+				return nil, "", fmt.Errorf("has more than one page")
+			}
+		case "page":
+			if values["id"] == "0" {
+				page = values["file"]
+			}
+		case "char":
+			glyph := Glyph{}
+			glyph.X = bmFontNumber(values, "x")
+			glyph.Y = bmFontNumber(values, "y")
+			glyph.Width = bmFontNumber(values, "width")
+			glyph.Height = bmFontNumber(values, "height")
+			glyph.XOffset = bmFontNumber(values, "xoffset")
+			glyph.YOffset = bmFontNumber(values, "yoffset")
+			glyph.XAdvance = bmFontNumber(values, "xadvance")
+			font.glyphs[rune(bmFontNumber(values, "id"))] = glyph
+		case "kerning":
+			pair := [2]rune{rune(bmFontNumber(values, "first")), rune(bmFontNumber(values, "second"))}
+			font.kerning[pair] = bmFontNumber(values, "amount")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	if page == "" {
+		return nil, "", fmt.Errorf("has no page")
+	}
+	return &font, page, nil
+}
+
+// NewFontFromTTF rasterizes the characters of a TrueType or OpenType font at
+// the given size (in pixels) into an atlas, charset can be empty.
+func (scene *Scene) NewFontFromTTF(name string, fileName string, size float64, charset string) (*Font, error) {
+	data, err := fileSystem.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := opentype.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("font %v %v", fileName, err)
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, fmt.Errorf("font %v %v", fileName, err)
+	}
+	if charset == "" {
+		charset = DefaultFontCharset
+	}
+
+	vectorFont, atlas := rasterizeFont(face, charset)
+	vectorFont.Name = name
+	vectorFont.texture = scene.NewTextureFromImage(name+".font", atlas)
+
+	scene.fonts[name] = vectorFont
+	return vectorFont, nil
+}
+
+// The width of the atlases of vector fonts.
+const fontAtlasWidth = 512
+
+// rasterizeFont draws the glyphs in white on rows of the atlas, whose height
+// is the next power of two.
+func rasterizeFont(face font.Face, charset string) (*Font, *image.RGBA) {
+	vectorFont := Font{glyphs: make(map[rune]Glyph), face: face}
+	metrics := face.Metrics()
+	vectorFont.LineHeight = fixedToFloat32(metrics.Height)
+	vectorFont.Base = fixedToFloat32(metrics.Ascent)
+
+	type placement struct {
+		r      rune
+		bounds image.Rectangle
+		x, y   int
+	}
+	var placements []placement
+
+	// The first pass places the glyphs, leaving a pixel of padding.
+	x, y, rowHeight := 1, 1, 0
+	for _, r := range charset {
+		if _, ok := vectorFont.glyphs[r]; ok {
+			continue
+		}
+		bounds, advance, ok := face.GlyphBounds(r)
+		if !ok {
+			continue
+		}
+		rect := image.Rect(bounds.Min.X.Floor(), bounds.Min.Y.Floor(), bounds.Max.X.Ceil(), bounds.Max.Y.Ceil())
+		if x+rect.Dx()+1 > fontAtlasWidth {
+			x = 1
+			y += rowHeight + 1
+			rowHeight = 0
+		}
+		placements = append(placements, placement{r, rect, x, y})
+
+		vectorFont.glyphs[r] = Glyph{X: float32(x), Y: float32(y),
+			Width: float32(rect.Dx()), Height: float32(rect.Dy()),
+			XOffset: float32(rect.Min.X), YOffset: vectorFont.Base + float32(rect.Min.Y),
+			XAdvance: fixedToFloat32(advance)}
+
+		x += rect.Dx() + 1
+		if rect.Dy() > rowHeight {
+			rowHeight = rect.Dy()
+		}
+	}
+
+	height := 1
+	for height < y+rowHeight+1 {
+		height *= 2
+	}
+	atlas := image.NewRGBA(image.Rect(0, 0, fontAtlasWidth, height))
+
+	drawer := font.Drawer{Dst: atlas, Src: image.White, Face: face}
+	for _, p := range placements {
+		drawer.Dot = fixed.P(p.x-p.bounds.Min.X, p.y-p.bounds.Min.Y)
+		drawer.DrawString(string(p.r))
+	}
+
+	return &vectorFont, atlas
+}
+
+// The built-in font (7x13 pixels) is used by debug texts and by UI widgets
+// without a font.
+var builtin struct {
+	font  *Font
+	atlas *image.RGBA
+}
+
+func builtinFont() *Font {
+	if builtin.font == nil {
+		builtin.font, builtin.atlas = rasterizeFont(basicfont.Face7x13, DefaultFontCharset)
+	}
+	return builtin.font
+}
+
+// fontTexture returns the atlas of a font, uploading the one of the built-in
+// font on first use.
+func fontTexture(textFont *Font) *Texture {
+	if textFont.texture == nil && textFont == builtin.font {
+		textFont.texture = newTextureFromImage("builtin.font", builtin.atlas)
+		builtin.atlas = nil
+	}
+	return textFont.texture
+}
+
+// A TextAlign is the horizontal alignment of the lines of a Text.
+type TextAlign int
+
+const (
+	TextLeft TextAlign = iota
+	TextCenter
+	TextRight
+)
+
+// Vertical alignments share the values of the horizontal ones.
+const (
+	TextTop    = TextLeft
+	TextMiddle = TextCenter
+	TextBottom = TextRight
+)
+
+// A placed glyph of a laid out text, in pixels.
+type textGlyph struct {
+	glyph Glyph
+	x, y  float32
+}
+
+func (font *Font) measure(line []rune) float32 {
+	var width float32
+	for i, r := range line {
+		glyph, ok := font.glyphs[r]
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			width += font.Kern(line[i-1], r)
+		}
+		width += glyph.XAdvance
+	}
+	return width
+}
+
+// wrap splits the text in lines, breaking between words when wider than
+// wrapWidth (0 disables wrapping). Words longer than a line are not broken.
+func (font *Font) wrap(text string, wrapWidth float32) [][]rune {
+	var lines [][]rune
+	for _, paragraph := range strings.Split(text, "\n") {
+		if wrapWidth <= 0 {
+			lines = append(lines, []rune(paragraph))
+			continue
+		}
+		var line []rune
+		for _, word := range strings.Split(paragraph, " ") {
+			candidate := []rune(word)
+			if len(line) > 0 {
+				candidate = append(append(append([]rune{}, line...), ' '), candidate...)
+			}
+			if len(line) > 0 && font.measure(candidate) > wrapWidth {
+				lines = append(lines, line)
+				line = []rune(word)
+				continue
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// layout places the glyphs of a text, returning them with the size of the
+// block. The block is aligned around x = 0, y = 0 is its top.
+func (font *Font) layout(text string, wrapWidth float32, align TextAlign, lineSpacing float32) ([]textGlyph, float32, float32) {
+	lines := font.wrap(text, wrapWidth)
+
+	blockWidth := wrapWidth
+	widths := make([]float32, len(lines))
+	for i, line := range lines {
+		widths[i] = font.measure(line)
+		if wrapWidth <= 0 && widths[i] > blockWidth {
+			blockWidth = widths[i]
+		}
+	}
+
+	var glyphs []textGlyph
+	for i, line := range lines {
+		var x float32
+		switch align {
+		case TextCenter:
+			x = -widths[i] / 2
+		case TextRight:
+			x = -widths[i]
+		}
+		y := float32(i) * font.LineHeight * lineSpacing
+		for j, r := range line {
+			glyph, ok := font.glyphs[r]
+			if !ok {
+				continue
+			}
+			if j > 0 {
+				x += font.Kern(line[j-1], r)
+			}
+			if glyph.Width > 0 && glyph.Height > 0 {
+				glyphs = append(glyphs, textGlyph{glyph, x + glyph.XOffset, y + glyph.YOffset})
+			}
+			x += glyph.XAdvance
+		}
+	}
+
+	height := float32(0)
+	if len(lines) > 0 {
+		height = float32(len(lines)-1)*font.LineHeight*lineSpacing + font.LineHeight
+	}
+	return glyphs, blockWidth, height
+}
+
+func loadFonts(scene *Scene, fonts []interface{}) {
+	for _, entry := range fonts {
+		fontMap := entry.(map[string]interface{})
+
+		name, ok := fontMap["name"].(string)
+		if !ok {
+			panic("font requires a name")
+		}
+
+		bmfont, ok := fontMap["bmfont"].(string)
+		if ok {
+			_, err := scene.NewFontFromBMFont(name, bmfont)
+			if err != nil {
+				panic(err)
+			}
+			continue
+		}
+
+		ttf, ok := fontMap["ttf"].(string)
+		if !ok {
+			panic("font requires a bmfont or a ttf file")
+		}
+		size := 32.0
+		value, ok := fontMap["size"]
+		if ok {
+			number, err := CastFloat32(value)
+			if err != nil {
+				panic("font size must be a number")
+			}
+			size = float64(number)
+		}
+		charset, _ := fontMap["charset"].(string)
+		_, err := scene.NewFontFromTTF(name, ttf, size, charset)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
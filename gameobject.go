@@ -16,6 +16,8 @@ type GameObject struct {
 	enabled bool
 	order   int
 	index   int
+	// Set by DontDestroyOnLoad.
+	persistent bool
 
 	Scene *Scene
 
@@ -24,6 +26,8 @@ type GameObject struct {
 	Scale     mgl32.Vec2
 	Pivot     mgl32.Vec2
 	DeltaTime float32
+	// The render layer (0..31) matched against the cameras culling masks.
+	Layer uint32
 
 	components     map[string]Component
 	componentsKeys []string
@@ -31,6 +35,8 @@ type GameObject struct {
 	customAttrs map[string]interface{}
 
 	events []*Event
+
+	coroutines []*Coroutine
 }
 
 func (scene *Scene) NewGameObject(name string) *GameObject {
@@ -122,6 +128,10 @@ func (gameObject *GameObject) AddPosition(x float32, y float32) {
 }
 
 func (gameObject *GameObject) SetEnabled(flag bool) {
+	// Disabling a GameObject cancels its coroutines.
+	if !flag {
+		gameObject.StopAllCoroutines()
+	}
 	gameObject.enabled = flag
 }
 
@@ -161,12 +171,22 @@ func (gameObject *GameObject) setAttr(attr string, value interface{}) error {
 		gameObject.Scale[0], _ = CastFloat32(value)
 	case "scaleY":
 		gameObject.Scale[1], _ = CastFloat32(value)
+	case "pivotX":
+		gameObject.Pivot[0], _ = CastFloat32(value)
+	case "pivotY":
+		gameObject.Pivot[1], _ = CastFloat32(value)
 	case "euler":
 		r, _ := CastFloat32(value)
 		gameObject.SetEuler(r)
 	case "order":
 		o, _ := CastInt(value)
 		gameObject.SetOrder(o)
+	case "layer":
+		layer, err := CastInt(value)
+		if err != nil || layer < 0 || layer > 31 {
+			return fmt.Errorf("%v attribute of %T expects a number between 0 and 31", attr, gameObject)
+		}
+		gameObject.Layer = uint32(layer)
 	// TODO: implement SetName() to maintain mappings and check for duplicates.
 	case "name":
 		name, ok := value.(string)
@@ -191,12 +211,18 @@ func (gameObject *GameObject) getAttr(attr string) (interface{}, error) {
 		return gameObject.Scale[0], nil
 	case "scaleY":
 		return gameObject.Scale[1], nil
+	case "pivotX":
+		return gameObject.Pivot[0], nil
+	case "pivotY":
+		return gameObject.Pivot[1], nil
 	case "euler":
 		return gameObject.Rotation * 180 / math.Pi, nil
 	case "deltaTime":
 		return gameObject.DeltaTime, nil
 	case "order":
 		return gameObject.order, nil
+	case "layer":
+		return gameObject.Layer, nil
 	case "name":
 		return gameObject.Name, nil
 	}
@@ -251,8 +277,24 @@ func (gameObject *GameObject) Update() {
 	}
 }
 
+func (gameObject *GameObject) Draw(camera *Camera) {
+	for _, key := range gameObject.componentsKeys {
+		componentDraw, ok := gameObject.components[key].(ComponentDraw)
+		if ok {
+			componentDraw.Draw(gameObject, camera)
+		}
+	}
+}
+
 func (gameObject *GameObject) Destroy() {
+	gameObject.StopAllCoroutines()
 	// Call Destroy() on all associated components.
+	for _, key := range gameObject.componentsKeys {
+		componentDestroy, ok := gameObject.components[key].(ComponentDestroy)
+		if ok {
+			componentDestroy.Destroy(gameObject)
+		}
+	}
 }
 
 func (gameObject *GameObject) String() string {
@@ -0,0 +1,399 @@
+// +build !android
+
+package gozmo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.1/glfw"
+)
+
+// Gamepads are read with the glfw joystick API, which only reports raw
+// buttons and axes whose order depends on the driver. A GamepadLayout maps
+// them to standard names:
+//
+// buttons: South, East, West, North, LeftShoulder, RightShoulder, Back,
+// Start, Guide, LeftStick, RightStick, DpadUp, DpadDown, DpadLeft, DpadRight;
+// axes: LeftX, LeftY, RightX, RightY (-1..1, up is positive), LeftTrigger,
+// RightTrigger (0..1).
+
+// A GamepadInput locates a standard input among the raw ones.
+type GamepadInput struct {
+	// Raw button index, -1 if the input comes from an axis.
+	Button int
+	// Raw axis index, -1 if the input comes from a button.
+	Axis int
+	// Multiplies the raw axis value, -1 inverts it. Buttons read from an
+	// axis (like a D-pad on Linux) take its positive half.
+	Scale float32
+	// Triggers report -1..1 at rest..pressed, they are remapped to 0..1.
+	Trigger bool
+}
+
+type GamepadLayout map[string]GamepadInput
+
+func gamepadButton(index int) GamepadInput {
+	return GamepadInput{Button: index, Axis: -1, Scale: 1}
+}
+
+func gamepadAxis(index int, scale float32) GamepadInput {
+	return GamepadInput{Button: -1, Axis: index, Scale: scale}
+}
+
+func gamepadTrigger(index int) GamepadInput {
+	return GamepadInput{Button: -1, Axis: index, Scale: 1, Trigger: true}
+}
+
+// The default layout is the one of XInput pads (and of most clones) on Linux.
+var DefaultGamepadLayout GamepadLayout = GamepadLayout{
+	"South":         gamepadButton(0),
+	"East":          gamepadButton(1),
+	"West":          gamepadButton(2),
+	"North":         gamepadButton(3),
+	"LeftShoulder":  gamepadButton(4),
+	"RightShoulder": gamepadButton(5),
+	"Back":          gamepadButton(6),
+	"Start":         gamepadButton(7),
+	"Guide":         gamepadButton(8),
+	"LeftStick":     gamepadButton(9),
+	"RightStick":    gamepadButton(10),
+	"DpadUp":        gamepadAxis(7, -1),
+	"DpadDown":      gamepadAxis(7, 1),
+	"DpadLeft":      gamepadAxis(6, -1),
+	"DpadRight":     gamepadAxis(6, 1),
+	"LeftX":         gamepadAxis(0, 1),
+	"LeftY":         gamepadAxis(1, -1),
+	"RightX":        gamepadAxis(3, 1),
+	"RightY":        gamepadAxis(4, -1),
+	"LeftTrigger":   gamepadTrigger(2),
+	"RightTrigger":  gamepadTrigger(5),
+}
+
+// Layouts for specific devices, by glfw joystick name.
+var gamepadLayouts map[string]GamepadLayout
+
+func SetGamepadLayout(joystickName string, layout GamepadLayout) {
+	if gamepadLayouts == nil {
+		gamepadLayouts = make(map[string]GamepadLayout)
+	}
+	gamepadLayouts[joystickName] = layout
+}
+
+// Axis values below the dead zone are reported as 0. Sticks use a radial
+// dead zone.
+var GamepadDeadZone float32 = 0.2
+
+const maxGamepads = 4
+
+type gamepadState struct {
+	present bool
+	name    string
+	layout  GamepadLayout
+
+	axes        []float32
+	buttons     []byte
+	lastAxes    []float32
+	lastButtons []byte
+}
+
+var gamepads [maxGamepads]gamepadState
+
+var gamepadComponents []*Gamepad
+
+// A GamepadEvent is the Data of "gamepadConnected" and "gamepadDisconnected"
+// events.
+type GamepadEvent struct {
+	Index int
+	Name  string
+}
+
+func readRaw(input GamepadInput, axes []float32, buttons []byte) float32 {
+	if input.Button > -1 {
+		if input.Button < len(buttons) && buttons[input.Button] == byte(glfw.Press) {
+			return 1
+		}
+		return 0
+	}
+	if input.Axis < 0 || input.Axis >= len(axes) {
+		return 0
+	}
+	value := axes[input.Axis]
+	if input.Trigger {
+		return (value + 1) / 2
+	}
+	return value * input.Scale
+}
+
+func applyDeadZone(value float32, deadZone float32) float32 {
+	magnitude := float32(math.Abs(float64(value)))
+	if magnitude < deadZone {
+		return 0
+	}
+	rescaled := (magnitude - deadZone) / (1 - deadZone)
+	if value < 0 {
+		return -rescaled
+	}
+	return rescaled
+}
+
+func stickPair(name string) string {
+	switch name {
+	case "LeftX":
+		return "LeftY"
+	case "LeftY":
+		return "LeftX"
+	case "RightX":
+		return "RightY"
+	case "RightY":
+		return "RightX"
+	}
+	return ""
+}
+
+// read returns the value of a standard input, with the dead zone applied.
+func (state *gamepadState) read(name string, deadZone float32, last bool) float32 {
+	if !state.present {
+		return 0
+	}
+	input, ok := state.layout[name]
+	if !ok {
+		return 0
+	}
+	axes := state.axes
+	buttons := state.buttons
+	if last {
+		axes = state.lastAxes
+		buttons = state.lastButtons
+	}
+
+	value := readRaw(input, axes, buttons)
+	if input.Axis < 0 {
+		return value
+	}
+	// Buttons read from an axis.
+	if !input.Trigger && strings.HasPrefix(name, "Dpad") {
+		if value < 0 {
+			return 0
+		}
+		return value
+	}
+
+	pair := stickPair(name)
+	if pair == "" {
+		return applyDeadZone(value, deadZone)
+	}
+	// Radial dead zone for sticks.
+	pairValue := readRaw(state.layout[pair], axes, buttons)
+	magnitude := float32(math.Sqrt(float64(value*value + pairValue*pairValue)))
+	if magnitude < deadZone {
+		return 0
+	}
+	return value * applyDeadZone(magnitude, deadZone) / magnitude
+}
+
+// pollGamepads reads the joysticks, it is called by Window.Run at the start
+// of every frame to fill the InputFrame.
+func pollGamepads() []InputGamepad {
+	var polled []InputGamepad
+	for i := 0; i < maxGamepads; i++ {
+		joystick := glfw.Joystick1 + glfw.Joystick(i)
+		if !glfw.JoystickPresent(joystick) {
+			polled = append(polled, InputGamepad{})
+			continue
+		}
+		polled = append(polled, InputGamepad{
+			Present: true,
+			Name:    glfw.GetJoystickName(joystick),
+			Axes:    glfw.GetJoystickAxes(joystick),
+			Buttons: glfw.GetJoystickButtons(joystick),
+		})
+	}
+	return polled
+}
+
+// updateGamepads latches the polled joysticks, raising connection events on
+// the GameObjects with a Gamepad component.
+func updateGamepads(frame *InputFrame) {
+	for i := range gamepads {
+		state := &gamepads[i]
+		polled := InputGamepad{}
+		if i < len(frame.Gamepads) {
+			polled = frame.Gamepads[i]
+		}
+
+		if polled.Present != state.present {
+			state.present = polled.Present
+			msg := "gamepadDisconnected"
+			if polled.Present {
+				msg = "gamepadConnected"
+				state.name = polled.Name
+				state.layout = DefaultGamepadLayout
+				layout, ok := gamepadLayouts[state.name]
+				if ok {
+					state.layout = layout
+				}
+			}
+			for _, gamepad := range gamepadComponents {
+				if gamepad.index == i && gamepad.gameObject.enabled {
+					gamepad.gameObject.EnqueueEventWithData(nil, msg, &GamepadEvent{Index: i, Name: state.name})
+				}
+			}
+		}
+
+		state.lastAxes = append(state.lastAxes[:0], state.axes...)
+		state.lastButtons = append(state.lastButtons[:0], state.buttons...)
+		state.axes = append(state.axes[:0], polled.Axes...)
+		state.buttons = append(state.buttons[:0], polled.Buttons...)
+	}
+}
+
+// The Gamepad component reads one of the connected gamepads (the first one by
+// default) through the standard layout names.
+type Gamepad struct {
+	gameObject *GameObject
+	index      int
+	deadZone   float32
+}
+
+func (gamepad *Gamepad) Start(gameObject *GameObject) {
+	gamepad.gameObject = gameObject
+	gamepadComponents = append(gamepadComponents, gamepad)
+}
+
+func (gamepad *Gamepad) Update(gameObject *GameObject) {}
+
+func (gamepad *Gamepad) Destroy(gameObject *GameObject) {
+	for i, g := range gamepadComponents {
+		if g == gamepad {
+			gamepadComponents = append(gamepadComponents[:i], gamepadComponents[i+1:]...)
+			return
+		}
+	}
+}
+
+func (gamepad *Gamepad) Connected() bool {
+	return gamepads[gamepad.index].present
+}
+
+func (gamepad *Gamepad) Name() string {
+	return gamepads[gamepad.index].name
+}
+
+func (gamepad *Gamepad) GetAxis(name string) float32 {
+	return gamepads[gamepad.index].read(name, gamepad.deadZone, false)
+}
+
+func (gamepad *Gamepad) GetButton(name string) bool {
+	return gamepads[gamepad.index].read(name, gamepad.deadZone, false) > inputActionThreshold
+}
+
+// GetButtonDown reports whether the button has been pressed in this frame.
+func (gamepad *Gamepad) GetButtonDown(name string) bool {
+	state := &gamepads[gamepad.index]
+	return state.read(name, gamepad.deadZone, false) > inputActionThreshold && state.read(name, gamepad.deadZone, true) <= inputActionThreshold
+}
+
+// GetButtonUp reports whether the button has been released in this frame.
+func (gamepad *Gamepad) GetButtonUp(name string) bool {
+	state := &gamepads[gamepad.index]
+	return state.read(name, gamepad.deadZone, false) <= inputActionThreshold && state.read(name, gamepad.deadZone, true) > inputActionThreshold
+}
+
+func (gamepad *Gamepad) SetAttr(attr string, value interface{}) error {
+	switch attr {
+	case "index":
+		index, err := CastInt(value)
+		if err != nil || index < 0 || index >= maxGamepads {
+			return fmt.Errorf("%v attribute of %T expects a number between 0 and %v", attr, gamepad, maxGamepads-1)
+		}
+		gamepad.index = index
+	case "deadZone":
+		deadZone, err := CastFloat32(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, gamepad, err)
+		}
+		gamepad.deadZone = deadZone
+	}
+	return nil
+}
+
+// Attributes are "connected", "name", "index", "deadZone", the axes names
+// and the buttons names, optionally followed by ".down" or ".up".
+func (gamepad *Gamepad) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "connected":
+		return gamepad.Connected(), nil
+	case "name":
+		return gamepad.Name(), nil
+	case "index":
+		return gamepad.index, nil
+	case "deadZone":
+		return gamepad.deadZone, nil
+	}
+
+	name := attr
+	query := ""
+	dot := strings.LastIndex(attr, ".")
+	if dot > 0 {
+		name = attr[:dot]
+		query = attr[dot+1:]
+	}
+
+	input, ok := DefaultGamepadLayout[name]
+	if !ok {
+		return nil, fmt.Errorf("%v attribute of %T not found", attr, gamepad)
+	}
+
+	switch query {
+	case "down":
+		return gamepad.GetButtonDown(name), nil
+	case "up":
+		return gamepad.GetButtonUp(name), nil
+	}
+
+	// Sticks and triggers.
+	if input.Axis > -1 && !strings.HasPrefix(name, "Dpad") {
+		return gamepad.GetAxis(name), nil
+	}
+	return gamepad.GetButton(name), nil
+}
+
+func (gamepad *Gamepad) GetType() string {
+	return "Gamepad"
+}
+
+func NewGamepad(index int) *Gamepad {
+	gamepad := Gamepad{index: index, deadZone: GamepadDeadZone}
+	return &gamepad
+}
+
+// An optional argument is the index of the gamepad.
+func initGamepad(args []interface{}) Component {
+	index := 0
+	if len(args) > 0 {
+		index, _ = CastInt(args[0])
+	}
+	if index < 0 || index >= maxGamepads {
+		panic("invalid gamepad index")
+	}
+	return NewGamepad(index)
+}
+
+// The "gamepad" input device reads the first gamepad, "gamepad2" to
+// "gamepad4" the other ones, codes are the standard names.
+func gamepadInputDevice(index int) InputDevice {
+	return func(code string) float32 {
+		return gamepads[index].read(code, GamepadDeadZone, false)
+	}
+}
+
+func init() {
+	RegisterComponent("Gamepad", initGamepad)
+	RegisterInputDevice("gamepad", gamepadInputDevice(0))
+	for i := 1; i < maxGamepads; i++ {
+		RegisterInputDevice(fmt.Sprintf("gamepad%d", i+1), gamepadInputDevice(i))
+	}
+	registerInputUpdater(updateGamepads)
+}
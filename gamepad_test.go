@@ -0,0 +1,132 @@
+// +build !android
+
+package gozmo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/glfw/v3.1/glfw"
+)
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-5
+}
+
+// A pad of the default layout, with the left stick at x, y (up is negative
+// on the raw axis) and the triggers at rest.
+func testGamepad(x, y float32) InputGamepad {
+	axes := []float32{x, y, -1, 0, 0, -1, 0, 0}
+	buttons := make([]byte, 11)
+	return InputGamepad{Present: true, Name: "Test Pad", Axes: axes, Buttons: buttons}
+}
+
+func TestApplyDeadZone(t *testing.T) {
+	if applyDeadZone(0.1, 0.2) != 0 || applyDeadZone(-0.19, 0.2) != 0 {
+		t.Error("Expected 0 within the dead zone")
+	}
+	if value := applyDeadZone(0.6, 0.2); !near(value, 0.5) {
+		t.Error("Expected 0.5, got", value)
+	}
+	if value := applyDeadZone(-1, 0.2); !near(value, -1) {
+		t.Error("Expected -1, got", value)
+	}
+}
+
+func TestGamepadRead(t *testing.T) {
+	defer func() { gamepads = [maxGamepads]gamepadState{} }()
+	gamepad := NewGamepad(0)
+
+	pad := testGamepad(0.12, -0.12)
+	updateGamepads(&InputFrame{Gamepads: []InputGamepad{pad}})
+	if gamepad.GetAxis("LeftX") != 0 || gamepad.GetAxis("LeftY") != 0 {
+		t.Error("Expected the stick in the dead zone")
+	}
+	// The dead zone is radial: each axis alone is within it, the stick is not.
+	pad = testGamepad(0.15, -0.15)
+	updateGamepads(&InputFrame{Gamepads: []InputGamepad{pad}})
+	if gamepad.GetAxis("LeftX") <= 0 || gamepad.GetAxis("LeftY") <= 0 {
+		t.Error("Expected the stick out of the dead zone")
+	}
+	pad = testGamepad(0.3, -0.4)
+	updateGamepads(&InputFrame{Gamepads: []InputGamepad{pad}})
+	// The magnitude 0.5 is rescaled to 0.375, along the same direction.
+	if x, y := gamepad.GetAxis("LeftX"), gamepad.GetAxis("LeftY"); !near(x, 0.225) || !near(y, 0.3) {
+		t.Error("Expected 0.225 0.3, got", x, y)
+	}
+
+	// Triggers at rest read 0, fully pressed 1.
+	if value := gamepad.GetAxis("LeftTrigger"); value != 0 {
+		t.Error("Expected 0, got", value)
+	}
+	pad.Axes[5] = 1
+	updateGamepads(&InputFrame{Gamepads: []InputGamepad{pad}})
+	if value := gamepad.GetAxis("RightTrigger"); value != 1 {
+		t.Error("Expected 1, got", value)
+	}
+
+	// The D-pad is read from axes 6 and 7, up is negative.
+	pad.Axes[7] = -1
+	pad.Buttons[0] = byte(glfw.Press)
+	updateGamepads(&InputFrame{Gamepads: []InputGamepad{pad}})
+	if !gamepad.GetButton("DpadUp") || gamepad.GetButton("DpadDown") {
+		t.Error("Expected only DpadUp")
+	}
+	if !gamepad.GetButtonDown("South") {
+		t.Error("Expected South pressed in this frame")
+	}
+	value, err := gamepad.GetAttr("DpadUp")
+	if err != nil || value != true {
+		t.Error("Expected DpadUp as a button, got", value, err)
+	}
+}
+
+func TestGamepadConnection(t *testing.T) {
+	defer func() { gamepads = [maxGamepads]gamepadState{} }()
+	layout := GamepadLayout{"South": gamepadButton(3)}
+	SetGamepadLayout("Test Pad", layout)
+	defer delete(gamepadLayouts, "Test Pad")
+
+	scene := NewScene("Gamepads")
+	player := scene.NewGameObject("Player2")
+	player.AddComponent("gamepad", NewGamepad(1))
+	defer player.Destroy()
+
+	updateGamepads(&InputFrame{Gamepads: []InputGamepad{{}, testGamepad(0, 0)}})
+	if len(player.events) != 1 || player.events[0].Msg != "gamepadConnected" {
+		t.Fatal("Expected a gamepadConnected event, got", player.events)
+	}
+	event := player.events[0].Data.(*GamepadEvent)
+	if event.Index != 1 || event.Name != "Test Pad" {
+		t.Error("Expected the second gamepad, got", event)
+	}
+	if gamepads[1].layout["South"] != layout["South"] {
+		t.Error("Expected the layout of the device")
+	}
+
+	updateGamepads(&InputFrame{})
+	if len(player.events) != 2 || player.events[1].Msg != "gamepadDisconnected" {
+		t.Error("Expected a gamepadDisconnected event, got", player.events)
+	}
+}
+
+func TestGamepadInputAction(t *testing.T) {
+	defer func() { gamepads = [maxGamepads]gamepadState{} }()
+	jump := NewInputAction("jump", false)
+	jump.Bind("gamepad2:South")
+	defer RemoveInputAction("jump")
+	moveX := NewInputAction("moveX", true)
+	moveX.Bind("gamepad2:LeftX")
+	defer RemoveInputAction("moveX")
+
+	pad := testGamepad(-1, 0)
+	pad.Buttons[0] = byte(glfw.Press)
+	updateGamepads(&InputFrame{Gamepads: []InputGamepad{{}, pad}})
+	UpdateInputActions()
+	if !jump.Pressed() {
+		t.Error("Expected jump to be pressed")
+	}
+	if value := moveX.Value(); !near(value, -1) {
+		t.Error("Expected -1, got", value)
+	}
+}
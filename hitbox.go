@@ -1,7 +1,10 @@
 package gozmo
 
 import (
-	_ "fmt"
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
 )
 
 // The HitBox component is a generic AABB checker that can be used for basic
@@ -13,6 +16,8 @@ type HitBox struct {
 	width      float32
 	height     float32
 	raiseEvent string
+	// Blocks the lights with shadows.
+	castShadows bool
 }
 
 var hitBoxes []*HitBox
@@ -24,23 +29,27 @@ func (hitbox *HitBox) Start(gameObject *GameObject) {
 	hitbox.gameObject = gameObject
 }
 
-func (hitbox *HitBox) Intersect(otherBox *HitBox) bool {
-
-	x1 := hitbox.gameObject.Position[0] + hitbox.xOffset*hitbox.gameObject.Scale[0]
-	x1 -= hitbox.width * hitbox.gameObject.Scale[0] / 2
-	y1 := hitbox.gameObject.Position[1] + hitbox.yOffset*hitbox.gameObject.Scale[1]
-	y1 += hitbox.height * hitbox.gameObject.Scale[1] / 2
+// Bounds returns the left, top, right and bottom world coordinates of the box.
+func (hitbox *HitBox) Bounds() (float32, float32, float32, float32) {
+	gameObject := hitbox.gameObject
+	x := gameObject.Position[0] + hitbox.xOffset*gameObject.Scale[0]
+	y := gameObject.Position[1] + hitbox.yOffset*gameObject.Scale[1]
+	// Negative scales (flipping) must not turn the box inside out.
+	halfWidth := float32(math.Abs(float64(hitbox.width*gameObject.Scale[0]))) / 2
+	halfHeight := float32(math.Abs(float64(hitbox.height*gameObject.Scale[1]))) / 2
+	return x - halfWidth, y + halfHeight, x + halfWidth, y - halfHeight
+}
 
-	w1 := x1 + hitbox.width*hitbox.gameObject.Scale[0]
-	h1 := y1 - hitbox.height*hitbox.gameObject.Scale[1]
+// Contains checks if a point (in world coordinates) is inside the box.
+func (hitbox *HitBox) Contains(x, y float32) bool {
+	left, top, right, bottom := hitbox.Bounds()
+	return x >= left && x <= right && y >= bottom && y <= top
+}
 
-	x2 := otherBox.gameObject.Position[0] + otherBox.xOffset*otherBox.gameObject.Scale[0]
-	x2 -= otherBox.width * otherBox.gameObject.Scale[0] / 2
-	y2 := otherBox.gameObject.Position[1] + otherBox.yOffset*otherBox.gameObject.Scale[1]
-	y2 += otherBox.height * otherBox.gameObject.Scale[1] / 2
+func (hitbox *HitBox) Intersect(otherBox *HitBox) bool {
 
-	w2 := x2 + otherBox.width*otherBox.gameObject.Scale[0]
-	h2 := y2 - otherBox.height*otherBox.gameObject.Scale[1]
+	x1, y1, w1, h1 := hitbox.Bounds()
+	x2, y2, w2, h2 := otherBox.Bounds()
 
 	if w1 < x2 {
 		return false
@@ -81,7 +90,42 @@ func (hitbox *HitBox) Update(gameObject *GameObject) {
 	}
 }
 
+func (hitbox *HitBox) Destroy(gameObject *GameObject) {
+	hitbox.gameObject = nil
+	for i, hbox := range hitBoxes {
+		if hbox == hitbox {
+			hitBoxes = append(hitBoxes[:i], hitBoxes[i+1:]...)
+			return
+		}
+	}
+}
+
+func (hitbox *HitBox) DebugOutline(gameObject *GameObject) {
+	if !DebugOutlines(DebugHitBoxes) {
+		return
+	}
+	left, top, right, bottom := hitbox.Bounds()
+	DebugRect(left, bottom, right, top, mgl32.Vec4{0, 1, 0, 1}, 0)
+}
+
+// ShadowPolygons returns the box, when casting shadows.
+func (hitbox *HitBox) ShadowPolygons(gameObject *GameObject) [][]mgl32.Vec2 {
+	if !hitbox.castShadows || hitbox.gameObject == nil {
+		return nil
+	}
+	left, top, right, bottom := hitbox.Bounds()
+	return [][]mgl32.Vec2{{{left, bottom}, {right, bottom}, {right, top}, {left, top}}}
+}
+
 func (hitbox *HitBox) SetAttr(attr string, value interface{}) error {
+	switch attr {
+	case "castShadows":
+		flag, err := CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T expects a bool", attr, hitbox)
+		}
+		hitbox.castShadows = flag
+	}
 	return nil
 }
 
@@ -90,6 +134,10 @@ func (hitbox *HitBox) GetName() string {
 }
 
 func (hitbox *HitBox) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "castShadows":
+		return hitbox.castShadows, nil
+	}
 	return 0, nil
 }
 
@@ -0,0 +1,295 @@
+// +build !release
+
+package gozmo
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Hot reloading, for development: the window polls the files of the scenes,
+// the textures in the ResourceCache and the files watched with WatchFile, and
+// applies their changes to the running game. Textures are uploaded again in
+// place, animations are rebuilt and restarted, and the attrs changed in a
+// scene file are set on the live GameObjects, without resetting the scene.
+//
+// Release builds (the "release" tag) leave it out.
+type hotReload struct {
+	enabled   bool
+	lastCheck time.Time
+	nextID    int
+	watchers  map[string]map[int]func()
+	stamps    map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// The interval between the checks of the files.
+const hotReloadInterval = 500 * time.Millisecond
+
+var hotReloader = hotReload{
+	watchers: make(map[string]map[int]func()),
+	stamps:   make(map[string]fileStamp),
+}
+
+// SetHotReload enables the hot reloading of the files, disabled by default.
+func SetHotReload(enabled bool) {
+	hotReloader.enabled = enabled
+}
+
+func HotReloadEnabled() bool {
+	return hotReloader.enabled
+}
+
+// WatchFile calls reload on the main thread whenever the file changes, while
+// hot reloading is enabled. It returns the function to stop watching.
+func WatchFile(fileName string, reload func()) func() {
+	id := hotReloader.nextID
+	hotReloader.nextID++
+	watchers, ok := hotReloader.watchers[fileName]
+	if !ok {
+		watchers = make(map[int]func())
+		hotReloader.watchers[fileName] = watchers
+	}
+	watchers[id] = reload
+	// Only the changes after watching count.
+	hotReloader.changed(fileName)
+
+	return func() {
+		// The ids are unique, so a second call is harmless.
+		watchers := hotReloader.watchers[fileName]
+		delete(watchers, id)
+		if len(watchers) == 0 {
+			delete(hotReloader.watchers, fileName)
+		}
+	}
+}
+
+// checkHotReload reloads the changed files, at most every hotReloadInterval.
+func checkHotReload() {
+	if !hotReloader.enabled || time.Since(hotReloader.lastCheck) < hotReloadInterval {
+		return
+	}
+	hotReloader.lastCheck = time.Now()
+	hotReloader.reloadChanged()
+}
+
+func (hot *hotReload) reloadChanged() {
+	for _, resource := range hot.changedTextures() {
+		hot.reload(resource.key.fileName, func() { reloadTexture(resource) })
+	}
+	for fileName, watchers := range hot.watchers {
+		if !hot.changed(fileName) {
+			continue
+		}
+		for _, reload := range watchers {
+			hot.reload(fileName, reload)
+		}
+	}
+}
+
+// changedTextures returns the cached textures whose file changed, checking
+// each file once even when it is cached with several options.
+func (hot *hotReload) changedTextures() []*textureResource {
+	changed := make(map[string]bool)
+	for key := range resourceCache.textures {
+		_, checked := changed[key.fileName]
+		if !checked {
+			changed[key.fileName] = hot.changed(key.fileName)
+		}
+	}
+	var resources []*textureResource
+	for key, resource := range resourceCache.textures {
+		if changed[key.fileName] {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// changed tells if the file changed since the last call. The first call only
+// records its state.
+func (hot *hotReload) changed(fileName string) bool {
+	info, err := fileSystem.Stat(fileName)
+	if err != nil {
+		// Editors may replace files, check them again later.
+		return false
+	}
+	stamp := fileStamp{modTime: info.ModTime(), size: info.Size()}
+	last, ok := hot.stamps[fileName]
+	hot.stamps[fileName] = stamp
+	return ok && last != stamp
+}
+
+// reload reports the errors of a reload, invalid files should not stop the
+// game.
+func (hot *hotReload) reload(fileName string, reload func()) {
+	defer func() {
+		r := recover()
+		if r != nil {
+			fmt.Println("hot reload", fileName, r)
+		}
+	}()
+	reload()
+}
+
+// reloadTexture uploads the image again to the same texture, updating the
+// size of all of the scene textures sharing it.
+func reloadTexture(resource *textureResource) {
+	options := resource.key.options
+	decoded, err := decodeImageFile(resource.key.fileName, options.Premultiplied)
+	if err != nil {
+		panic(err)
+	}
+	GLUpdateTexture(resource.tid, decoded.pixels, int32(decoded.width), int32(decoded.height))
+	GLTextureFilter(resource.tid, options.Filter, options.Mipmaps)
+	countTexture(resource.width, resource.height, -1)
+	resource.width = uint32(decoded.width)
+	resource.height = uint32(decoded.height)
+	countTexture(resource.width, resource.height, 1)
+
+	for _, scene := range Engine.scenes {
+		for _, texture := range scene.textures {
+			if texture.resource == resource {
+				texture.Width = resource.width
+				texture.Height = resource.height
+			}
+		}
+	}
+}
+
+// watchScene reloads the scene when its file changes.
+func watchScene(scene *Scene, fileName string) {
+	scene.unwatch = WatchFile(fileName, func() {
+		data, err := fileSystem.ReadFile(fileName)
+		if err != nil {
+			panic(err)
+		}
+		var parsed map[string]interface{}
+		err = json.Unmarshal(data, &parsed)
+		if err != nil {
+			panic(err)
+		}
+		scene.reload(parsed)
+	})
+}
+
+// reload applies the differences between the parsed file and the one the
+// scene was built from. Changed textures and animations are loaded again,
+// new GameObjects and components are added, removed GameObjects are
+// destroyed and only the changed attrs are set, so that the rest of the
+// state is kept.
+func (scene *Scene) reload(parsed map[string]interface{}) {
+	source := scene.source
+	scene.source = parsed
+
+	oldTextures := entriesByName(source["textures"])
+	for name, entry := range entriesByName(parsed["textures"]) {
+		if reflect.DeepEqual(entry, oldTextures[name]) {
+			continue
+		}
+		old := scene.textures[name]
+		loadTextures(scene, []interface{}{entry}, nil)
+		if old != nil && old != scene.textures[name] {
+			old.Destroy()
+		}
+	}
+
+	oldAnimations := entriesByName(source["animations"])
+	for name, entry := range entriesByName(parsed["animations"]) {
+		if reflect.DeepEqual(entry, oldAnimations[name]) {
+			continue
+		}
+		loadAnimations(scene, []interface{}{entry})
+		scene.restartAnimation(name)
+	}
+
+	oldObjects := entriesByName(source["objects"])
+	newObjects := entriesByName(parsed["objects"])
+	for name := range oldObjects {
+		gameObject, ok := scene.gameObjects[name]
+		_, kept := newObjects[name]
+		if ok && !kept {
+			gameObject.Destroy()
+			scene.removeGameObject(gameObject)
+		}
+	}
+	// Keep the order of the file for the new GameObjects.
+	objects, _ := parsed["objects"].([]interface{})
+	for _, obj := range objects {
+		objMap := obj.(map[string]interface{})
+		name, _ := objMap["name"].(string)
+		gameObject, ok := scene.gameObjects[name]
+		if !ok {
+			loadObjects(scene, []interface{}{obj})
+			continue
+		}
+		gameObject.reload(objMap, oldObjects[name])
+	}
+}
+
+// reload adds the new components of the GameObject and sets its changed
+// attrs. Components removed from the file are kept.
+func (gameObject *GameObject) reload(objMap map[string]interface{}, old map[string]interface{}) {
+	components, _ := objMap["components"].([]interface{})
+	for _, component := range components {
+		componentName, _ := component.(map[string]interface{})["name"].(string)
+		_, ok := gameObject.components[componentName]
+		if !ok {
+			addComponents(gameObject, []interface{}{component})
+		}
+	}
+
+	oldAttrs := make(map[[2]interface{}]interface{})
+	attrs, _ := old["attrs"].([]interface{})
+	for _, attr := range attrs {
+		attrMap := attr.(map[string]interface{})
+		oldAttrs[[2]interface{}{attrMap["component"], attrMap["key"]}] = attrMap["value"]
+	}
+	attrs, _ = objMap["attrs"].([]interface{})
+	for _, attr := range attrs {
+		attrMap := attr.(map[string]interface{})
+		oldValue, ok := oldAttrs[[2]interface{}{attrMap["component"], attrMap["key"]}]
+		if !ok || !reflect.DeepEqual(attrMap["value"], oldValue) {
+			setAttrs(gameObject, []interface{}{attr})
+		}
+	}
+
+	persistent, _ := objMap["dontDestroyOnLoad"].(bool)
+	gameObject.persistent = persistent
+}
+
+// restartAnimation plays a reloaded animation from its first frame.
+func (scene *Scene) restartAnimation(name string) {
+	for _, gameObject := range scene.gameObjects {
+		for _, component := range gameObject.components {
+			animator, ok := component.(*Animator)
+			if ok && animator.currentAnimation == name {
+				animator.SetAnimation(name)
+			}
+		}
+	}
+}
+
+// entriesByName indexes a list of scene entries (textures, objects...) by
+// their "name" key.
+func entriesByName(list interface{}) map[string]map[string]interface{} {
+	entries := make(map[string]map[string]interface{})
+	items, _ := list.([]interface{})
+	for _, item := range items {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := entry["name"].(string)
+		if ok {
+			entries[name] = entry
+		}
+	}
+	return entries
+}
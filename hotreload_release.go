@@ -0,0 +1,22 @@
+// +build release
+
+package gozmo
+
+// Hot reloading is left out of release builds.
+
+func SetHotReload(enabled bool) {
+}
+
+func HotReloadEnabled() bool {
+	return false
+}
+
+func WatchFile(fileName string, reload func()) func() {
+	return func() {}
+}
+
+func checkHotReload() {
+}
+
+func watchScene(scene *Scene, fileName string) {
+}
@@ -0,0 +1,143 @@
+// +build !release
+
+package gozmo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// touchFile rewrites a file with a later modification time, so that the
+// change is seen even within the resolution of the filesystem.
+func touchFile(t *testing.T, fileName string, data string, seconds int) {
+	err := ioutil.WriteFile(fileName, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	when := time.Now().Add(time.Duration(seconds) * time.Second)
+	err = os.Chtimes(fileName, when, when)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hotreload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "fall.lua")
+	touchFile(t, fileName, "speed = 1", 0)
+
+	reloads := 0
+	unwatch := WatchFile(fileName, func() { reloads++ })
+	hotReloader.reloadChanged()
+	if reloads != 0 {
+		t.Error("Expected no reload, got", reloads)
+	}
+	touchFile(t, fileName, "speed = 2", 10)
+	hotReloader.reloadChanged()
+	hotReloader.reloadChanged()
+	if reloads != 1 {
+		t.Error("Expected 1 reload, got", reloads)
+	}
+
+	unwatch()
+	touchFile(t, fileName, "speed = 3", 20)
+	hotReloader.reloadChanged()
+	if reloads != 1 || hotReloader.watchers[fileName] != nil {
+		t.Error("Expected the file not to be watched")
+	}
+}
+
+func TestChangedTextures(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hotreload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "sd_idle.png")
+	touchFile(t, fileName, "png", 0)
+
+	// The same file cached with two options.
+	linear := textureKey{fileName: fileName}
+	nearest := textureKey{fileName: fileName, options: TextureOptions{Filter: TextureNearest}}
+	resourceCache.textures[linear] = &textureResource{key: linear}
+	resourceCache.textures[nearest] = &textureResource{key: nearest}
+	defer func() {
+		delete(resourceCache.textures, linear)
+		delete(resourceCache.textures, nearest)
+	}()
+
+	if changed := hotReloader.changedTextures(); len(changed) != 0 {
+		t.Error("Expected no changes, got", len(changed))
+	}
+	touchFile(t, fileName, "png2", 10)
+	if changed := hotReloader.changedTextures(); len(changed) != 2 {
+		t.Error("Expected both textures to change, got", len(changed))
+	}
+}
+
+func TestSceneHotReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hotreload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "Scene001.json")
+	touchFile(t, fileName, `{"name": "HotReload",
+	"animations": [{"name": "fall", "fps": 10, "frames": [[{"component": "animator", "key": "play", "value": true}]]}],
+	"objects": [
+		{"name": "Player", "components": [{"name": "animator", "type": "Animator"}],
+			"attrs": [{"component": "animator", "key": "animation", "value": "fall"}, {"component": "animator", "key": "play", "value": true}]},
+		{"name": "Rock"}]}`, 0)
+	scene := NewSceneFromFilename(fileName)
+	defer scene.Destroy()
+
+	player := scene.FindGameObject("Player")
+	animator := player.GetComponent("animator").(*Animator)
+	animator.currentFrame = 0
+	// Stopped at runtime, the attr is unchanged in the file.
+	animator.Stop()
+
+	touchFile(t, fileName, `{"name": "HotReload",
+	"animations": [{"name": "fall", "fps": 20, "frames": [[{"component": "animator", "key": "play", "value": true}]]}],
+	"objects": [
+		{"name": "Player", "components": [{"name": "animator", "type": "Animator"}, {"name": "camera", "type": "Camera"}],
+			"attrs": [{"component": "animator", "key": "animation", "value": "fall"}, {"component": "animator", "key": "play", "value": true},
+				{"component": "camera", "key": "size", "value": 5}]},
+		{"name": "Enemy"}]}`, 10)
+	hotReloader.reloadChanged()
+
+	if scene.FindGameObject("Player") != player {
+		t.Fatal("Expected the same Player")
+	}
+	if animator.isPlaying {
+		t.Error("Expected the unchanged attr not to be set again")
+	}
+	if animator.currentFrame != -1 || scene.animations["fall"].Fps != 20 {
+		t.Error("Expected the changed animation to restart")
+	}
+	if player.GetComponent("camera") == nil {
+		t.Error("Expected the new camera")
+	}
+	if scene.FindGameObject("Rock") != nil || scene.FindGameObject("Enemy") == nil {
+		t.Error("Expected Rock to be replaced by Enemy")
+	}
+
+	// Invalid files are reported without changing the scene.
+	touchFile(t, fileName, `{"name": `, 20)
+	hotReloader.reloadChanged()
+	if scene.FindGameObject("Enemy") == nil {
+		t.Error("Expected the scene to be kept")
+	}
+
+	scene.Destroy()
+	if hotReloader.watchers[fileName] != nil {
+		t.Error("Expected the destroyed scene not to be watched")
+	}
+}
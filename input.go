@@ -0,0 +1,419 @@
+package gozmo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// Input actions decouple gameplay code from physical devices: a named action
+// ("jump", "moveX") is bound to one or more device inputs, written as
+// "device:code" strings like "key:Space", "mouse:Left" or "gamepad:South". A
+// leading "-" negates the input, which is how axes are built from two keys.
+//
+// Devices register a reader returning the current value of a code, 0..1 for
+// buttons and -1..1 for axes. Bindings to unregistered devices read 0.
+
+type InputDevice func(code string) float32
+
+type InputBinding struct {
+	Device string
+	Code   string
+	Scale  float32
+}
+
+// An InputAction is either a button (Held, Pressed, Released) or an axis
+// (Value), depending on how it is read.
+type InputAction struct {
+	Name     string
+	Axis     bool
+	Bindings []*InputBinding
+
+	value     float32
+	lastValue float32
+}
+
+// Values above this threshold count as a button being held.
+const inputActionThreshold = 0.5
+
+var inputDevices map[string]InputDevice
+
+var inputActions map[string]*InputAction
+
+var inputUpdaters []func(frame *InputFrame)
+
+func RegisterInputDevice(name string, reader InputDevice) {
+	// Create the map if required.
+	if inputDevices == nil {
+		inputDevices = make(map[string]InputDevice)
+	}
+	inputDevices[name] = reader
+}
+
+// Input updaters feed the raw events of a frame to a device, and latch its
+// state, at the start of every frame.
+func registerInputUpdater(updater func(frame *InputFrame)) {
+	inputUpdaters = append(inputUpdaters, updater)
+}
+
+func ParseInputBinding(binding string) (*InputBinding, error) {
+	var scale float32 = 1
+	if strings.HasPrefix(binding, "-") {
+		scale = -1
+		binding = binding[1:]
+	}
+	items := strings.SplitN(binding, ":", 2)
+	if len(items) != 2 || items[1] == "" {
+		return nil, fmt.Errorf("invalid input binding %v, expects device:code", binding)
+	}
+	// The device is looked up when reading, those missing on a platform (like
+	// gamepads on Android) read 0, so that configs are shared.
+	return &InputBinding{Device: items[0], Code: items[1], Scale: scale}, nil
+}
+
+func (binding *InputBinding) String() string {
+	if binding.Scale < 0 {
+		return "-" + binding.Device + ":" + binding.Code
+	}
+	return binding.Device + ":" + binding.Code
+}
+
+func (binding *InputBinding) read() float32 {
+	device, ok := inputDevices[binding.Device]
+	if !ok {
+		return 0
+	}
+	return device(binding.Code) * binding.Scale
+}
+
+// NewInputAction creates (or replaces) a named action.
+func NewInputAction(name string, axis bool) *InputAction {
+	if inputActions == nil {
+		inputActions = make(map[string]*InputAction)
+	}
+	action := InputAction{Name: name, Axis: axis}
+	inputActions[name] = &action
+	return &action
+}
+
+func GetInputAction(name string) *InputAction {
+	action, ok := inputActions[name]
+	if !ok {
+		return nil
+	}
+	return action
+}
+
+func RemoveInputAction(name string) {
+	delete(inputActions, name)
+}
+
+// Bind adds a device input to the action.
+func (action *InputAction) Bind(binding string) error {
+	parsed, err := ParseInputBinding(binding)
+	if err != nil {
+		return err
+	}
+	action.Bindings = append(action.Bindings, parsed)
+	return nil
+}
+
+// Rebind replaces all of the action bindings, leaving them untouched on error.
+func (action *InputAction) Rebind(bindings ...string) error {
+	var parsedList []*InputBinding
+	for _, binding := range bindings {
+		parsed, err := ParseInputBinding(binding)
+		if err != nil {
+			return err
+		}
+		parsedList = append(parsedList, parsed)
+	}
+	action.Bindings = parsedList
+	return nil
+}
+
+func (action *InputAction) Unbind() {
+	action.Bindings = nil
+}
+
+func (action *InputAction) update() {
+	action.lastValue = action.value
+	var value float32
+	for _, binding := range action.Bindings {
+		value += binding.read()
+	}
+	if value > 1 {
+		value = 1
+	}
+	if value < -1 {
+		value = -1
+	}
+	action.value = value
+}
+
+// Value returns the action state clamped to -1..1.
+func (action *InputAction) Value() float32 {
+	return action.value
+}
+
+func isHeld(value float32) bool {
+	return value > inputActionThreshold || value < -inputActionThreshold
+}
+
+func (action *InputAction) Held() bool {
+	return isHeld(action.value)
+}
+
+// Pressed is true only in the frame the action started being held.
+func (action *InputAction) Pressed() bool {
+	return isHeld(action.value) && !isHeld(action.lastValue)
+}
+
+// Released is true only in the frame the action stopped being held.
+func (action *InputAction) Released() bool {
+	return !isHeld(action.value) && isHeld(action.lastValue)
+}
+
+// UpdateInputActions samples all devices once per frame.
+func UpdateInputActions() {
+	for _, action := range inputActions {
+		action.update()
+	}
+}
+
+func GetInputActionHeld(name string) bool {
+	action := GetInputAction(name)
+	return action != nil && action.Held()
+}
+
+func GetInputActionPressed(name string) bool {
+	action := GetInputAction(name)
+	return action != nil && action.Pressed()
+}
+
+func GetInputActionReleased(name string) bool {
+	action := GetInputAction(name)
+	return action != nil && action.Released()
+}
+
+func GetInputActionValue(name string) float32 {
+	action := GetInputAction(name)
+	if action == nil {
+		return 0
+	}
+	return action.Value()
+}
+
+// LoadInputActions reads actions from a JSON config like:
+//
+//	{ "actions": [
+//	    { "name": "jump", "bindings": ["key:Space", "gamepad:South"] },
+//	    { "name": "moveX", "axis": true, "bindings": ["key:Right", "-key:Left"] }
+//	] }
+func LoadInputActions(data []byte) error {
+	var parsed map[string]interface{}
+
+	err := json.Unmarshal(data, &parsed)
+	if err != nil {
+		return err
+	}
+
+	actions, ok := parsed["actions"].([]interface{})
+	if !ok {
+		return fmt.Errorf("input config requires a list of actions")
+	}
+
+	for _, item := range actions {
+		actionMap, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("input action must be an object")
+		}
+
+		name, ok := actionMap["name"].(string)
+		if !ok {
+			return fmt.Errorf("input action requires a name")
+		}
+
+		axis, _ := actionMap["axis"].(bool)
+
+		action := NewInputAction(name, axis)
+
+		bindings, _ := actionMap["bindings"].([]interface{})
+		for _, binding := range bindings {
+			bindingString, ok := binding.(string)
+			if !ok {
+				return fmt.Errorf("bindings of input action %v must be strings", name)
+			}
+			err = action.Bind(bindingString)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func LoadInputActionsFromFilename(fileName string) error {
+	data, err := fileSystem.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	return LoadInputActions(data)
+}
+
+// SaveInputActionsToFilename stores the current (possibly rebound) actions in
+// the same format read by LoadInputActions.
+func SaveInputActionsToFilename(fileName string) error {
+	var names []string
+	for name := range inputActions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var actions []interface{}
+	for _, name := range names {
+		action := inputActions[name]
+		bindings := []string{}
+		for _, binding := range action.Bindings {
+			bindings = append(bindings, binding.String())
+		}
+		actionMap := map[string]interface{}{"name": name, "bindings": bindings}
+		if action.Axis {
+			actionMap["axis"] = true
+		}
+		actions = append(actions, actionMap)
+	}
+
+	data, err := json.MarshalIndent(map[string]interface{}{"actions": actions}, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fileName, data, 0644)
+}
+
+// The Input component exposes actions through the attribute system, so that
+// gameplay code (JSON, animations, Lua) never names physical inputs.
+//
+// GetAttr("jump") returns whether the action is held (or its value for axes),
+// "jump.pressed", "jump.released" and "jump.value" are available too.
+// SetAttr("jump", "key:Space") rebinds the action (a list of strings is
+// accepted as well), creating it when needed. Axes are set as in the actions
+// config: SetAttr("moveX", {"axis": true, "bindings": ["gamepad:LeftX"]}).
+type Input struct{}
+
+func (input *Input) Start(gameObject *GameObject)  {}
+func (input *Input) Update(gameObject *GameObject) {}
+
+func (input *Input) SetAttr(attr string, value interface{}) error {
+	var bindings []string
+	var axis, setAxis bool
+	var err error
+	switch value.(type) {
+	case string:
+		bindings = append(bindings, value.(string))
+	case []interface{}:
+		bindings, err = castBindings(value)
+	case map[string]interface{}:
+		actionMap := value.(map[string]interface{})
+		axis, _ = actionMap["axis"].(bool)
+		setAxis = true
+		bindings, err = castBindings(actionMap["bindings"])
+	default:
+		return fmt.Errorf("%v attribute of %T expects a string", attr, input)
+	}
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, input, err)
+	}
+
+	action := GetInputAction(attr)
+	if action == nil {
+		action = NewInputAction(attr, axis)
+	}
+	err = action.Rebind(bindings...)
+	if err != nil {
+		return err
+	}
+	if setAxis {
+		action.Axis = axis
+	}
+	return nil
+}
+
+// castBindings converts a list of binding strings.
+func castBindings(value interface{}) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expects a list of strings")
+	}
+	var bindings []string
+	for _, item := range items {
+		binding, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("expects a list of strings")
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+func (input *Input) GetAttr(attr string) (interface{}, error) {
+	name := attr
+	query := ""
+	dot := strings.LastIndex(attr, ".")
+	if dot > -1 {
+		name = attr[:dot]
+		query = attr[dot+1:]
+	}
+
+	action := GetInputAction(name)
+	if action == nil {
+		return nil, fmt.Errorf("%v attribute of %T not found", attr, input)
+	}
+
+	switch query {
+	case "":
+		if action.Axis {
+			return action.Value(), nil
+		}
+		return action.Held(), nil
+	case "pressed":
+		return action.Pressed(), nil
+	case "released":
+		return action.Released(), nil
+	case "held":
+		return action.Held(), nil
+	case "value":
+		return action.Value(), nil
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, input)
+}
+
+func (input *Input) GetType() string {
+	return "Input"
+}
+
+func NewInput() *Input {
+	input := Input{}
+	return &input
+}
+
+// An optional argument is the filename of the actions config.
+func initInput(args []interface{}) Component {
+	if len(args) > 0 {
+		fileName, ok := args[0].(string)
+		if !ok {
+			panic("the Input argument must be a filename")
+		}
+		err := LoadInputActionsFromFilename(fileName)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return NewInput()
+}
+
+func init() {
+	RegisterComponent("Input", initInput)
+}
@@ -0,0 +1,127 @@
+package gozmo
+
+import (
+	"testing"
+)
+
+var fakeInputState map[string]float32 = map[string]float32{}
+
+func init() {
+	RegisterInputDevice("fake", func(code string) float32 {
+		return fakeInputState[code]
+	})
+}
+
+func TestInputActionEdges(t *testing.T) {
+	action := NewInputAction("jump", false)
+	action.Bind("fake:Space")
+
+	fakeInputState["Space"] = 1
+	UpdateInputActions()
+	if !action.Pressed() || !action.Held() {
+		t.Error("Expected pressed and held, got", action.Pressed(), action.Held())
+	}
+
+	UpdateInputActions()
+	if action.Pressed() || !action.Held() {
+		t.Error("Expected only held, got", action.Pressed(), action.Held())
+	}
+
+	fakeInputState["Space"] = 0
+	UpdateInputActions()
+	if !action.Released() || action.Held() {
+		t.Error("Expected released, got", action.Released(), action.Held())
+	}
+	RemoveInputAction("jump")
+}
+
+func TestInputActionAxis(t *testing.T) {
+	err := LoadInputActions([]byte(`{"actions": [{"name": "moveX", "axis": true, "bindings": ["fake:Right", "-fake:Left"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := NewInput()
+
+	fakeInputState["Left"] = 1
+	UpdateInputActions()
+	value, _ := input.GetAttr("moveX")
+	if value.(float32) != -1 {
+		t.Error("Expected -1, got", value)
+	}
+
+	fakeInputState["Right"] = 1
+	UpdateInputActions()
+	value, _ = input.GetAttr("moveX")
+	if value.(float32) != 0 {
+		t.Error("Expected 0, got", value)
+	}
+	fakeInputState["Left"] = 0
+	fakeInputState["Right"] = 0
+	RemoveInputAction("moveX")
+}
+
+func TestInputActionRebind(t *testing.T) {
+	input := NewInput()
+	err := input.SetAttr("fire", "fake:X")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = input.SetAttr("fire", []interface{}{"fake:Y", "X"})
+	if err == nil {
+		t.Error("Expected an error, got nil")
+	}
+	// A failed rebind must keep the previous bindings.
+	if GetInputAction("fire").Bindings[0].String() != "fake:X" {
+		t.Error("Expected fake:X, got", GetInputAction("fire").Bindings[0])
+	}
+	fakeInputState["X"] = 1
+	UpdateInputActions()
+	if !IsTrue(input.GetAttr("fire.pressed")) {
+		t.Error("Expected true, got false")
+	}
+	fakeInputState["X"] = 0
+	RemoveInputAction("fire")
+}
+
+func TestInputUnknownDevice(t *testing.T) {
+	action := NewInputAction("pause", false)
+	defer RemoveInputAction("pause")
+	err := action.Rebind("joystick:Start", "fake:P")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeInputState["P"] = 1
+	UpdateInputActions()
+	if !action.Held() {
+		t.Error("Expected the other bindings to be read")
+	}
+	fakeInputState["P"] = 0
+	UpdateInputActions()
+	if action.Held() || action.Bindings[0].String() != "joystick:Start" {
+		t.Error("Expected the unknown device to be kept and read 0")
+	}
+}
+
+func TestInputAxisAttr(t *testing.T) {
+	input := NewInput()
+	err := input.SetAttr("throttle", map[string]interface{}{"axis": true, "bindings": []interface{}{"fake:T"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer RemoveInputAction("throttle")
+	if !GetInputAction("throttle").Axis {
+		t.Fatal("Expected an axis")
+	}
+	fakeInputState["T"] = 0.5
+	UpdateInputActions()
+	value, _ := input.GetAttr("throttle")
+	if value != float32(0.5) {
+		t.Error("Expected 0.5, got", value)
+	}
+	fakeInputState["T"] = 0
+
+	err = input.SetAttr("throttle", map[string]interface{}{"bindings": []interface{}{1}})
+	if err == nil {
+		t.Error("Expected an error for a binding that is not a string")
+	}
+}
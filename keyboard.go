@@ -1,52 +1,275 @@
-// +build !android
-
 package gozmo
 
 import (
-	"github.com/go-gl/glfw/v3.1/glfw"
-)
-
-// A Key is a keyboard mapping. By default we use the glfw names, but override
-// them to support more platforms in the future. Not bound on Android.
-type Key glfw.Key
-
-const (
-	KeyRight Key = Key(glfw.KeyRight)
-	KeyLeft  Key = Key(glfw.KeyLeft)
-	KeyUp    Key = Key(glfw.KeyUp)
-	KeyDown  Key = Key(glfw.KeyDown)
-
-	KeyA Key = Key(glfw.KeyA)
-	KeyB Key = Key(glfw.KeyB)
-	KeyC Key = Key(glfw.KeyC)
-	KeyD Key = Key(glfw.KeyD)
-	KeyP Key = Key(glfw.KeyP)
-	KeyS Key = Key(glfw.KeyS)
-	KeyT Key = Key(glfw.KeyT)
-	KeyW Key = Key(glfw.KeyW)
-
-	KeyEsc Key = Key(glfw.KeyEscape)
+	"fmt"
+	"strings"
 )
 
-// Rather ugly mapping, but it will simplify the "compositor's" life.
+// KeyboardAttr maps key names (the constant names without the Key prefix) to
+// keys, it is used by the attribute system and by input action bindings.
 var KeyboardAttr map[string]Key = map[string]Key{
-	"A":     KeyA,
-	"D":     KeyD,
-	"S":     KeyS,
-	"W":     KeyW,
-	"Right": KeyRight,
-	"Left":  KeyLeft,
-	"Up":    KeyUp,
-	"Down":  KeyDown,
+	"Space":        KeySpace,
+	"Apostrophe":   KeyApostrophe,
+	"Comma":        KeyComma,
+	"Minus":        KeyMinus,
+	"Period":       KeyPeriod,
+	"Slash":        KeySlash,
+	"0":            Key0,
+	"1":            Key1,
+	"2":            Key2,
+	"3":            Key3,
+	"4":            Key4,
+	"5":            Key5,
+	"6":            Key6,
+	"7":            Key7,
+	"8":            Key8,
+	"9":            Key9,
+	"Semicolon":    KeySemicolon,
+	"Equal":        KeyEqual,
+	"A":            KeyA,
+	"B":            KeyB,
+	"C":            KeyC,
+	"D":            KeyD,
+	"E":            KeyE,
+	"F":            KeyF,
+	"G":            KeyG,
+	"H":            KeyH,
+	"I":            KeyI,
+	"J":            KeyJ,
+	"K":            KeyK,
+	"L":            KeyL,
+	"M":            KeyM,
+	"N":            KeyN,
+	"O":            KeyO,
+	"P":            KeyP,
+	"Q":            KeyQ,
+	"R":            KeyR,
+	"S":            KeyS,
+	"T":            KeyT,
+	"U":            KeyU,
+	"V":            KeyV,
+	"W":            KeyW,
+	"X":            KeyX,
+	"Y":            KeyY,
+	"Z":            KeyZ,
+	"LeftBracket":  KeyLeftBracket,
+	"Backslash":    KeyBackslash,
+	"RightBracket": KeyRightBracket,
+	"GraveAccent":  KeyGraveAccent,
+	"World1":       KeyWorld1,
+	"World2":       KeyWorld2,
+	"Escape":       KeyEscape,
+	"Esc":          KeyEsc,
+	"Enter":        KeyEnter,
+	"Tab":          KeyTab,
+	"Backspace":    KeyBackspace,
+	"Insert":       KeyInsert,
+	"Delete":       KeyDelete,
+	"Right":        KeyRight,
+	"Left":         KeyLeft,
+	"Down":         KeyDown,
+	"Up":           KeyUp,
+	"PageUp":       KeyPageUp,
+	"PageDown":     KeyPageDown,
+	"Home":         KeyHome,
+	"End":          KeyEnd,
+	"CapsLock":     KeyCapsLock,
+	"ScrollLock":   KeyScrollLock,
+	"NumLock":      KeyNumLock,
+	"PrintScreen":  KeyPrintScreen,
+	"Pause":        KeyPause,
+	"F1":           KeyF1,
+	"F2":           KeyF2,
+	"F3":           KeyF3,
+	"F4":           KeyF4,
+	"F5":           KeyF5,
+	"F6":           KeyF6,
+	"F7":           KeyF7,
+	"F8":           KeyF8,
+	"F9":           KeyF9,
+	"F10":          KeyF10,
+	"F11":          KeyF11,
+	"F12":          KeyF12,
+	"F13":          KeyF13,
+	"F14":          KeyF14,
+	"F15":          KeyF15,
+	"F16":          KeyF16,
+	"F17":          KeyF17,
+	"F18":          KeyF18,
+	"F19":          KeyF19,
+	"F20":          KeyF20,
+	"F21":          KeyF21,
+	"F22":          KeyF22,
+	"F23":          KeyF23,
+	"F24":          KeyF24,
+	"F25":          KeyF25,
+	"KP0":          KeyKP0,
+	"KP1":          KeyKP1,
+	"KP2":          KeyKP2,
+	"KP3":          KeyKP3,
+	"KP4":          KeyKP4,
+	"KP5":          KeyKP5,
+	"KP6":          KeyKP6,
+	"KP7":          KeyKP7,
+	"KP8":          KeyKP8,
+	"KP9":          KeyKP9,
+	"KPDecimal":    KeyKPDecimal,
+	"KPDivide":     KeyKPDivide,
+	"KPMultiply":   KeyKPMultiply,
+	"KPSubtract":   KeyKPSubtract,
+	"KPAdd":        KeyKPAdd,
+	"KPEnter":      KeyKPEnter,
+	"KPEqual":      KeyKPEqual,
+	"LeftShift":    KeyLeftShift,
+	"LeftControl":  KeyLeftControl,
+	"LeftAlt":      KeyLeftAlt,
+	"LeftSuper":    KeyLeftSuper,
+	"RightShift":   KeyRightShift,
+	"RightControl": KeyRightControl,
+	"RightAlt":     KeyRightAlt,
+	"RightSuper":   KeyRightSuper,
+	"Menu":         KeyMenu,
+}
+
+var keyNames map[Key]string
+
+// KeyName returns the KeyboardAttr name of a key.
+func KeyName(key Key) string {
+	if keyNames == nil {
+		keyNames = make(map[Key]string)
+		for name, k := range KeyboardAttr {
+			// Prefer the full names over the aliases.
+			old, ok := keyNames[k]
+			if !ok || len(name) > len(old) {
+				keyNames[k] = name
+			}
+		}
+	}
+	return keyNames[key]
+}
+
+// A KeyEvent is the Data of the "keyDown" and "keyUp" events.
+type KeyEvent struct {
+	Key  Key
+	Name string
+}
+
+// The keyboard state is fed by the platform window callbacks between frames,
+// and latched at the start of every frame, so that a key pressed and
+// released within the same frame is not lost.
+type keyboardState struct {
+	held map[Key]bool
+	down []Key
+	up   []Key
+	text string
+
+	pendingDown []Key
+	pendingUp   []Key
+	pendingText []rune
+
+	components []*Keyboard
+}
+
+var keyboardInput = keyboardState{held: make(map[Key]bool)}
+
+func keyboardKeyEvent(key Key, pressed bool) {
+	if pressed {
+		if !keyboardInput.held[key] {
+			keyboardInput.pendingDown = append(keyboardInput.pendingDown, key)
+		}
+		keyboardInput.held[key] = true
+		return
+	}
+	if keyboardInput.held[key] {
+		keyboardInput.pendingUp = append(keyboardInput.pendingUp, key)
+	}
+	keyboardInput.held[key] = false
+}
+
+func keyboardCharEvent(char rune) {
+	keyboardInput.pendingText = append(keyboardInput.pendingText, char)
+}
+
+// updateKeyboard latches the events received since the last frame, and
+// enqueues them on the GameObjects with a Keyboard component in the loaded
+// scenes.
+func updateKeyboard(frame *InputFrame) {
+	for _, key := range frame.Keys {
+		keyboardKeyEvent(key.Key, key.Pressed)
+	}
+	for _, char := range frame.Chars {
+		keyboardCharEvent(char)
+	}
+
+	keyboardInput.down = keyboardInput.pendingDown
+	keyboardInput.up = keyboardInput.pendingUp
+	keyboardInput.text = string(keyboardInput.pendingText)
+	keyboardInput.pendingDown = nil
+	keyboardInput.pendingUp = nil
+	keyboardInput.pendingText = nil
+
+	for _, keyboard := range keyboardInput.components {
+		if !keyboard.events || !keyboard.gameObject.enabled {
+			continue
+		}
+		if Engine.Window != nil && !sceneManager.IsLoaded(keyboard.gameObject.Scene) {
+			continue
+		}
+		for _, key := range keyboardInput.down {
+			keyboard.gameObject.EnqueueEventWithData(nil, "keyDown", &KeyEvent{Key: key, Name: KeyName(key)})
+		}
+		for _, key := range keyboardInput.up {
+			keyboard.gameObject.EnqueueEventWithData(nil, "keyUp", &KeyEvent{Key: key, Name: KeyName(key)})
+		}
+		if keyboardInput.text != "" {
+			keyboard.gameObject.EnqueueEventWithData(nil, "text", keyboardInput.text)
+		}
+	}
 }
 
-// A Keyboard works by polling at a given time.
-type Keyboard struct{}
+func containsKey(keys []Key, key Key) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// A Keyboard reports the key state of the current frame. Unless its "events"
+// attribute is false, it also enqueues "keyDown", "keyUp" (with a *KeyEvent
+// as Data) and "text" (with the typed string as Data) events on its
+// GameObject.
+type Keyboard struct {
+	gameObject *GameObject
+	events     bool
+}
+
+func (keyboard *Keyboard) Start(gameObject *GameObject) {
+	keyboard.gameObject = gameObject
+	keyboardInput.components = append(keyboardInput.components, keyboard)
+}
 
-func (keyboard *Keyboard) Start(gameObject *GameObject)  {}
 func (keyboard *Keyboard) Update(gameObject *GameObject) {}
 
+func (keyboard *Keyboard) Destroy(gameObject *GameObject) {
+	for i, k := range keyboardInput.components {
+		if k == keyboard {
+			keyboardInput.components = append(keyboardInput.components[:i], keyboardInput.components[i+1:]...)
+			return
+		}
+	}
+}
+
 func (keyboard *Keyboard) SetAttr(attr string, value interface{}) error {
+	switch attr {
+	case "events":
+		flag, err := CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, keyboard, err)
+		}
+		keyboard.events = flag
+	}
 	return nil
 }
 
@@ -54,21 +277,61 @@ func (keyboard *Keyboard) GetName() string {
 	return "Keyboard"
 }
 
+// GetKey reports whether the key is held.
 func (keyboard *Keyboard) GetKey(key Key) bool {
-	return Engine.Window.getKey(key)
+	return keyboardInput.held[key]
+}
+
+// GetKeyDown reports whether the key has been pressed in this frame.
+func (keyboard *Keyboard) GetKeyDown(key Key) bool {
+	return containsKey(keyboardInput.down, key)
+}
+
+// GetKeyUp reports whether the key has been released in this frame.
+func (keyboard *Keyboard) GetKeyUp(key Key) bool {
+	return containsKey(keyboardInput.up, key)
 }
 
+// GetText returns the characters typed in this frame.
+func (keyboard *Keyboard) GetText() string {
+	return keyboardInput.text
+}
+
+// Attributes are key names ("Space"), optionally followed by ".down" or
+// ".up". "text" returns the characters typed in this frame.
 // TODO: what if the user specifies an unknown key?
 func (keyboard *Keyboard) GetAttr(attr string) (interface{}, error) {
-	key, ok := KeyboardAttr[attr]
+	switch attr {
+	case "text":
+		return keyboard.GetText(), nil
+	case "events":
+		return keyboard.events, nil
+	}
+
+	name := attr
+	query := ""
+	dot := strings.LastIndex(attr, ".")
+	if dot > 0 {
+		name = attr[:dot]
+		query = attr[dot+1:]
+	}
+
+	key, ok := KeyboardAttr[name]
 	if !ok {
 		return false, nil
 	}
-	return Engine.Window.getKey(key), nil
+
+	switch query {
+	case "down":
+		return keyboard.GetKeyDown(key), nil
+	case "up":
+		return keyboard.GetKeyUp(key), nil
+	}
+	return keyboard.GetKey(key), nil
 }
 
 func NewKeyboard() *Keyboard {
-	keyboard := Keyboard{}
+	keyboard := Keyboard{events: true}
 	return &keyboard
 }
 
@@ -76,6 +339,20 @@ func initKeyboard(args []interface{}) Component {
 	return NewKeyboard()
 }
 
+// readKeyInput is the "key" input device, codes are KeyboardAttr names.
+func readKeyInput(code string) float32 {
+	key, ok := KeyboardAttr[code]
+	if !ok {
+		return 0
+	}
+	if keyboardInput.held[key] {
+		return 1
+	}
+	return 0
+}
+
 func init() {
 	RegisterComponent("Keyboard", initKeyboard)
+	RegisterInputDevice("key", readKeyInput)
+	registerInputUpdater(updateKeyboard)
 }
@@ -0,0 +1,90 @@
+package gozmo
+
+import (
+	"testing"
+)
+
+func TestKeyboardDownUp(t *testing.T) {
+	keyboard := NewKeyboard()
+
+	keyboardKeyEvent(KeySpace, true)
+	updateKeyboard(&InputFrame{})
+	if !keyboard.GetKeyDown(KeySpace) || !keyboard.GetKey(KeySpace) {
+		t.Error("Expected down and held, got", keyboard.GetKeyDown(KeySpace), keyboard.GetKey(KeySpace))
+	}
+
+	updateKeyboard(&InputFrame{})
+	if keyboard.GetKeyDown(KeySpace) || !keyboard.GetKey(KeySpace) {
+		t.Error("Expected only held, got", keyboard.GetKeyDown(KeySpace), keyboard.GetKey(KeySpace))
+	}
+
+	// Press and release within the same frame.
+	keyboardKeyEvent(KeySpace, false)
+	keyboardKeyEvent(KeySpace, true)
+	keyboardKeyEvent(KeySpace, false)
+	updateKeyboard(&InputFrame{})
+	if !keyboard.GetKeyDown(KeySpace) || !keyboard.GetKeyUp(KeySpace) || keyboard.GetKey(KeySpace) {
+		t.Error("Expected down, up and not held")
+	}
+	updateKeyboard(&InputFrame{})
+}
+
+func TestKeyboardEvents(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	keyboard := NewKeyboard()
+	gameObject.AddComponent("kbd", keyboard)
+
+	component := &TestComponentForEvent{}
+	gameObject.AddComponent("test", component)
+
+	keyboardKeyEvent(KeyA, true)
+	keyboardCharEvent('a')
+	updateKeyboard(&InputFrame{})
+
+	if len(gameObject.events) != 2 {
+		t.Fatal("Expected 2, got", len(gameObject.events))
+	}
+	if gameObject.events[0].Msg != "keyDown" || gameObject.events[0].Data.(*KeyEvent).Name != "A" {
+		t.Error("Expected keyDown A, got", gameObject.events[0].Msg, gameObject.events[0].Data)
+	}
+	if gameObject.events[1].Msg != "text" || gameObject.events[1].Data.(string) != "a" {
+		t.Error("Expected text a, got", gameObject.events[1].Msg, gameObject.events[1].Data)
+	}
+
+	scene.Update(0)
+	if component.counter != 2 {
+		t.Error("Expected 2, got", component.counter)
+	}
+
+	gameObject.Destroy()
+	keyboardKeyEvent(KeyA, false)
+	updateKeyboard(&InputFrame{})
+	if len(gameObject.events) != 0 {
+		t.Error("Expected 0, got", len(gameObject.events))
+	}
+	updateKeyboard(&InputFrame{})
+}
+
+func TestKeyboardLoadedScenes(t *testing.T) {
+	Engine.Window = &Window{}
+	defer func() { Engine.Window = nil }()
+	scene := NewScene("Menu")
+	gameObject := scene.NewGameObject("Object")
+	gameObject.AddComponent("kbd", NewKeyboard())
+	defer gameObject.Destroy()
+
+	keyboardKeyEvent(KeyA, true)
+	updateKeyboard(&InputFrame{})
+	if len(gameObject.events) != 0 {
+		t.Error("Expected no events in an unloaded scene, got", len(gameObject.events))
+	}
+
+	sceneManager.SetScene(scene)
+	defer sceneManager.SetScene(nil)
+	keyboardKeyEvent(KeyA, false)
+	updateKeyboard(&InputFrame{})
+	if len(gameObject.events) != 1 || gameObject.events[0].Msg != "keyUp" {
+		t.Error("Expected keyUp, got", gameObject.events)
+	}
+}
@@ -0,0 +1,255 @@
+// +build android
+
+package gozmo
+
+import (
+	"golang.org/x/mobile/event/key"
+)
+
+// A Key mirrors the desktop (glfw) key values, so that key codes are the
+// same on every platform.
+type Key int
+
+const (
+	KeySpace        Key = 32
+	KeyApostrophe   Key = 39
+	KeyComma        Key = 44
+	KeyMinus        Key = 45
+	KeyPeriod       Key = 46
+	KeySlash        Key = 47
+	Key0            Key = 48
+	Key1            Key = 49
+	Key2            Key = 50
+	Key3            Key = 51
+	Key4            Key = 52
+	Key5            Key = 53
+	Key6            Key = 54
+	Key7            Key = 55
+	Key8            Key = 56
+	Key9            Key = 57
+	KeySemicolon    Key = 59
+	KeyEqual        Key = 61
+	KeyA            Key = 65
+	KeyB            Key = 66
+	KeyC            Key = 67
+	KeyD            Key = 68
+	KeyE            Key = 69
+	KeyF            Key = 70
+	KeyG            Key = 71
+	KeyH            Key = 72
+	KeyI            Key = 73
+	KeyJ            Key = 74
+	KeyK            Key = 75
+	KeyL            Key = 76
+	KeyM            Key = 77
+	KeyN            Key = 78
+	KeyO            Key = 79
+	KeyP            Key = 80
+	KeyQ            Key = 81
+	KeyR            Key = 82
+	KeyS            Key = 83
+	KeyT            Key = 84
+	KeyU            Key = 85
+	KeyV            Key = 86
+	KeyW            Key = 87
+	KeyX            Key = 88
+	KeyY            Key = 89
+	KeyZ            Key = 90
+	KeyLeftBracket  Key = 91
+	KeyBackslash    Key = 92
+	KeyRightBracket Key = 93
+	KeyGraveAccent  Key = 96
+	KeyWorld1       Key = 161
+	KeyWorld2       Key = 162
+	KeyEscape       Key = 256
+	KeyEnter        Key = 257
+	KeyTab          Key = 258
+	KeyBackspace    Key = 259
+	KeyInsert       Key = 260
+	KeyDelete       Key = 261
+	KeyRight        Key = 262
+	KeyLeft         Key = 263
+	KeyDown         Key = 264
+	KeyUp           Key = 265
+	KeyPageUp       Key = 266
+	KeyPageDown     Key = 267
+	KeyHome         Key = 268
+	KeyEnd          Key = 269
+	KeyCapsLock     Key = 280
+	KeyScrollLock   Key = 281
+	KeyNumLock      Key = 282
+	KeyPrintScreen  Key = 283
+	KeyPause        Key = 284
+	KeyF1           Key = 290
+	KeyF2           Key = 291
+	KeyF3           Key = 292
+	KeyF4           Key = 293
+	KeyF5           Key = 294
+	KeyF6           Key = 295
+	KeyF7           Key = 296
+	KeyF8           Key = 297
+	KeyF9           Key = 298
+	KeyF10          Key = 299
+	KeyF11          Key = 300
+	KeyF12          Key = 301
+	KeyF13          Key = 302
+	KeyF14          Key = 303
+	KeyF15          Key = 304
+	KeyF16          Key = 305
+	KeyF17          Key = 306
+	KeyF18          Key = 307
+	KeyF19          Key = 308
+	KeyF20          Key = 309
+	KeyF21          Key = 310
+	KeyF22          Key = 311
+	KeyF23          Key = 312
+	KeyF24          Key = 313
+	KeyF25          Key = 314
+	KeyKP0          Key = 320
+	KeyKP1          Key = 321
+	KeyKP2          Key = 322
+	KeyKP3          Key = 323
+	KeyKP4          Key = 324
+	KeyKP5          Key = 325
+	KeyKP6          Key = 326
+	KeyKP7          Key = 327
+	KeyKP8          Key = 328
+	KeyKP9          Key = 329
+	KeyKPDecimal    Key = 330
+	KeyKPDivide     Key = 331
+	KeyKPMultiply   Key = 332
+	KeyKPSubtract   Key = 333
+	KeyKPAdd        Key = 334
+	KeyKPEnter      Key = 335
+	KeyKPEqual      Key = 336
+	KeyLeftShift    Key = 340
+	KeyLeftControl  Key = 341
+	KeyLeftAlt      Key = 342
+	KeyLeftSuper    Key = 343
+	KeyRightShift   Key = 344
+	KeyRightControl Key = 345
+	KeyRightAlt     Key = 346
+	KeyRightSuper   Key = 347
+	KeyMenu         Key = 348
+
+	// Kept for compatibility.
+	KeyEsc Key = KeyEscape
+)
+
+// Translation from the USB HID codes of x/mobile key events.
+var mobileKeys map[key.Code]Key = map[key.Code]Key{
+	key.CodeA:                  KeyA,
+	key.CodeB:                  KeyB,
+	key.CodeC:                  KeyC,
+	key.CodeD:                  KeyD,
+	key.CodeE:                  KeyE,
+	key.CodeF:                  KeyF,
+	key.CodeG:                  KeyG,
+	key.CodeH:                  KeyH,
+	key.CodeI:                  KeyI,
+	key.CodeJ:                  KeyJ,
+	key.CodeK:                  KeyK,
+	key.CodeL:                  KeyL,
+	key.CodeM:                  KeyM,
+	key.CodeN:                  KeyN,
+	key.CodeO:                  KeyO,
+	key.CodeP:                  KeyP,
+	key.CodeQ:                  KeyQ,
+	key.CodeR:                  KeyR,
+	key.CodeS:                  KeyS,
+	key.CodeT:                  KeyT,
+	key.CodeU:                  KeyU,
+	key.CodeV:                  KeyV,
+	key.CodeW:                  KeyW,
+	key.CodeX:                  KeyX,
+	key.CodeY:                  KeyY,
+	key.CodeZ:                  KeyZ,
+	key.Code0:                  Key0,
+	key.Code1:                  Key1,
+	key.Code2:                  Key2,
+	key.Code3:                  Key3,
+	key.Code4:                  Key4,
+	key.Code5:                  Key5,
+	key.Code6:                  Key6,
+	key.Code7:                  Key7,
+	key.Code8:                  Key8,
+	key.Code9:                  Key9,
+	key.CodeReturnEnter:        KeyEnter,
+	key.CodeEscape:             KeyEscape,
+	key.CodeDeleteBackspace:    KeyBackspace,
+	key.CodeTab:                KeyTab,
+	key.CodeSpacebar:           KeySpace,
+	key.CodeHyphenMinus:        KeyMinus,
+	key.CodeEqualSign:          KeyEqual,
+	key.CodeLeftSquareBracket:  KeyLeftBracket,
+	key.CodeRightSquareBracket: KeyRightBracket,
+	key.CodeBackslash:          KeyBackslash,
+	key.CodeSemicolon:          KeySemicolon,
+	key.CodeApostrophe:         KeyApostrophe,
+	key.CodeGraveAccent:        KeyGraveAccent,
+	key.CodeComma:              KeyComma,
+	key.CodeFullStop:           KeyPeriod,
+	key.CodeSlash:              KeySlash,
+	key.CodeCapsLock:           KeyCapsLock,
+	key.CodeF1:                 KeyF1,
+	key.CodeF2:                 KeyF2,
+	key.CodeF3:                 KeyF3,
+	key.CodeF4:                 KeyF4,
+	key.CodeF5:                 KeyF5,
+	key.CodeF6:                 KeyF6,
+	key.CodeF7:                 KeyF7,
+	key.CodeF8:                 KeyF8,
+	key.CodeF9:                 KeyF9,
+	key.CodeF10:                KeyF10,
+	key.CodeF11:                KeyF11,
+	key.CodeF12:                KeyF12,
+	key.CodeF13:                KeyF13,
+	key.CodeF14:                KeyF14,
+	key.CodeF15:                KeyF15,
+	key.CodeF16:                KeyF16,
+	key.CodeF17:                KeyF17,
+	key.CodeF18:                KeyF18,
+	key.CodeF19:                KeyF19,
+	key.CodeF20:                KeyF20,
+	key.CodeF21:                KeyF21,
+	key.CodeF22:                KeyF22,
+	key.CodeF23:                KeyF23,
+	key.CodeF24:                KeyF24,
+	key.CodePause:              KeyPause,
+	key.CodeInsert:             KeyInsert,
+	key.CodeHome:               KeyHome,
+	key.CodePageUp:             KeyPageUp,
+	key.CodeDeleteForward:      KeyDelete,
+	key.CodeEnd:                KeyEnd,
+	key.CodePageDown:           KeyPageDown,
+	key.CodeRightArrow:         KeyRight,
+	key.CodeLeftArrow:          KeyLeft,
+	key.CodeDownArrow:          KeyDown,
+	key.CodeUpArrow:            KeyUp,
+	key.CodeKeypadNumLock:      KeyNumLock,
+	key.CodeKeypadSlash:        KeyKPDivide,
+	key.CodeKeypadAsterisk:     KeyKPMultiply,
+	key.CodeKeypadHyphenMinus:  KeyKPSubtract,
+	key.CodeKeypadPlusSign:     KeyKPAdd,
+	key.CodeKeypadEnter:        KeyKPEnter,
+	key.CodeKeypad0:            KeyKP0,
+	key.CodeKeypad1:            KeyKP1,
+	key.CodeKeypad2:            KeyKP2,
+	key.CodeKeypad3:            KeyKP3,
+	key.CodeKeypad4:            KeyKP4,
+	key.CodeKeypad5:            KeyKP5,
+	key.CodeKeypad6:            KeyKP6,
+	key.CodeKeypad7:            KeyKP7,
+	key.CodeKeypad8:            KeyKP8,
+	key.CodeKeypad9:            KeyKP9,
+	key.CodeKeypadFullStop:     KeyKPDecimal,
+	key.CodeKeypadEqualSign:    KeyKPEqual,
+	key.CodeLeftControl:        KeyLeftControl,
+	key.CodeLeftShift:          KeyLeftShift,
+	key.CodeLeftAlt:            KeyLeftAlt,
+	key.CodeLeftGUI:            KeyLeftSuper,
+	key.CodeRightControl:       KeyRightControl,
+	key.CodeRightShift:         KeyRightShift,
+	key.CodeRightAlt:           KeyRightAlt,
+	key.CodeRightGUI:           KeyRightSuper,
+}
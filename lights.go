@@ -0,0 +1,617 @@
+package gozmo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The Light2D component lights the scene: a camera seeing at least one light
+// multiplies its image by a light map, cleared to the sum of the global
+// (ambient) lights and accumulating the point and spot ones.
+//
+// Point and spot lights fade to 0 at "radius" world units, with a "falloff"
+// exponent (1 is linear). Spot lights point along the X axis of their
+// GameObject, rotated with it, within a cone of "angle" degrees. Sprites with
+// a normal map (the "normalMap" attribute of Renderer) are lit depending on
+// the direction of the light, which is "height" world units above them.
+// Lights with "shadows" are blocked by the ShadowCaster components, like
+// HitBoxes with "castShadows".
+
+type LightType int
+
+const (
+	LightPoint LightType = iota
+	LightSpot
+	LightGlobal
+)
+
+var lightTypeNames = map[string]LightType{
+	"point":  LightPoint,
+	"spot":   LightSpot,
+	"global": LightGlobal,
+}
+
+func (lightType LightType) String() string {
+	for name, value := range lightTypeNames {
+		if value == lightType {
+			return name
+		}
+	}
+	return ""
+}
+
+// A ShadowCaster blocks the lights with polygons, in world coordinates.
+type ShadowCaster interface {
+	ShadowPolygons(gameObject *GameObject) [][]mgl32.Vec2
+}
+
+type Light2D struct {
+	gameObject *GameObject
+	lightType  LightType
+	color      mgl32.Vec3
+	intensity  float32
+	radius     float32
+	falloff    float32
+	// The full angle of the cone of spot lights, in degrees.
+	angle   float32
+	height  float32
+	shadows bool
+}
+
+func NewLight2D(lightType LightType) *Light2D {
+	light := Light2D{lightType: lightType}
+	light.color = mgl32.Vec3{1, 1, 1}
+	light.intensity = 1
+	light.radius = 5
+	light.falloff = 1
+	light.angle = 45
+	light.height = 1
+	return &light
+}
+
+func (light *Light2D) Start(gameObject *GameObject) {
+	light.gameObject = gameObject
+	gameObject.Scene.lights = append(gameObject.Scene.lights, light)
+}
+
+func (light *Light2D) Update(gameObject *GameObject) {
+}
+
+func (light *Light2D) Destroy(gameObject *GameObject) {
+	lights := gameObject.Scene.lights
+	for i, l := range lights {
+		if l == light {
+			gameObject.Scene.lights = append(lights[:i], lights[i+1:]...)
+			return
+		}
+	}
+}
+
+// Radiance is the color multiplied by the intensity.
+func (light *Light2D) Radiance() mgl32.Vec3 {
+	return light.color.Mul(light.intensity)
+}
+
+// Direction of spot lights, in world coordinates.
+func (light *Light2D) Direction() mgl32.Vec2 {
+	angle := float64(light.gameObject.Rotation)
+	return mgl32.Vec2{float32(math.Cos(angle)), float32(math.Sin(angle))}
+}
+
+// attenuation computes the contribution of the light at a point, like the
+// light shader without normal maps and shadows.
+func (light *Light2D) attenuation(point mgl32.Vec2) float32 {
+	if light.lightType == LightGlobal {
+		return 1
+	}
+	delta := point.Sub(light.gameObject.Position)
+	distance := delta.Len()
+	if light.radius <= 0 || distance >= light.radius {
+		return 0
+	}
+	value := float32(math.Pow(float64(1-distance/light.radius), float64(light.falloff)))
+	if light.lightType == LightSpot && distance > 0 {
+		cutoff := light.cutoff()
+		value *= smoothstep(cutoff, cutoff+(1-cutoff)*0.2, delta.Normalize().Dot(light.Direction()))
+	}
+	return value
+}
+
+// cutoff is the cosine of the half angle of the cone, -1 for point lights.
+func (light *Light2D) cutoff() float32 {
+	if light.lightType != LightSpot {
+		return -1
+	}
+	return float32(math.Cos(float64(light.angle) / 2 * math.Pi / 180))
+}
+
+func smoothstep(edge0, edge1, x float32) float32 {
+	if edge1 <= edge0 {
+		if x < edge0 {
+			return 0
+		}
+		return 1
+	}
+	t := (x - edge0) / (edge1 - edge0)
+	if t < 0 {
+		t = 0
+	}
+	if t > 1 {
+		t = 1
+	}
+	return t * t * (3 - 2*t)
+}
+
+// shadowGeometry extrudes the edges of the polygons away from a point, past
+// a distance, then covers the polygons again so that the occluders stay lit.
+// The triangles are returned with their alphas (0 in shadow, 1 lit).
+func shadowGeometry(center mgl32.Vec2, distance float32, polygons [][]mgl32.Vec2) ([]float32, []float32) {
+	var vertices []float32
+	var alphas []float32
+	far := func(point mgl32.Vec2) mgl32.Vec2 {
+		direction := point.Sub(center)
+		if direction.Len() == 0 {
+			return point
+		}
+		return point.Add(direction.Normalize().Mul(distance))
+	}
+	for _, polygon := range polygons {
+		for i, a := range polygon {
+			b := polygon[(i+1)%len(polygon)]
+			farA := far(a)
+			farB := far(b)
+			vertices = append(vertices, a[0], a[1], b[0], b[1], farB[0], farB[1],
+				a[0], a[1], farB[0], farB[1], farA[0], farA[1])
+			alphas = append(alphas, 0, 0, 0, 0, 0, 0)
+		}
+	}
+	for _, polygon := range polygons {
+		for i := 1; i+1 < len(polygon); i++ {
+			vertices = append(vertices, polygon[0][0], polygon[0][1], polygon[i][0], polygon[i][1], polygon[i+1][0], polygon[i+1][1])
+			alphas = append(alphas, 1, 1, 1)
+		}
+	}
+	return vertices, alphas
+}
+
+// polygonsNear returns the polygons of the shadow casters whose bounds
+// intersect a circle.
+func polygonsNear(scene *Scene, center mgl32.Vec2, radius float32) [][]mgl32.Vec2 {
+	var polygons [][]mgl32.Vec2
+	for _, gameObject := range scene.gameObjects {
+		if !gameObject.enabled {
+			continue
+		}
+		for _, component := range gameObject.components {
+			caster, ok := component.(ShadowCaster)
+			if !ok {
+				continue
+			}
+			for _, polygon := range caster.ShadowPolygons(gameObject) {
+				if len(polygon) < 3 {
+					continue
+				}
+				bounds := [4]float32{polygon[0][0], polygon[0][1], polygon[0][0], polygon[0][1]}
+				for _, point := range polygon {
+					bounds[0] = float32(math.Min(float64(bounds[0]), float64(point[0])))
+					bounds[1] = float32(math.Min(float64(bounds[1]), float64(point[1])))
+					bounds[2] = float32(math.Max(float64(bounds[2]), float64(point[0])))
+					bounds[3] = float32(math.Max(float64(bounds[3]), float64(point[1])))
+				}
+				if bounds[0] > center[0]+radius || bounds[2] < center[0]-radius ||
+					bounds[1] > center[1]+radius || bounds[3] < center[1]-radius {
+					continue
+				}
+				polygons = append(polygons, polygon)
+			}
+		}
+	}
+	return polygons
+}
+
+var lightVertexShader = `
+attribute vec2 vertex;
+
+uniform mat4 ortho;
+
+varying vec2 worldout;
+
+void main() {
+    gl_Position = ortho * vec4(vertex, 0.0, 1.0);
+    worldout = vertex;
+}
+`
+
+var lightFragmentShader = `
+uniform vec2 center;
+uniform vec3 radiance;
+uniform float radius;
+uniform float falloff;
+uniform vec2 direction;
+uniform float cutoff;
+uniform float height;
+uniform sampler2D normals;
+uniform float useNormals;
+uniform vec2 resolution;
+
+varying vec2 worldout;
+
+void main() {
+    vec2 delta = worldout - center;
+    float dist = length(delta);
+    float attenuation = pow(clamp(1.0 - dist / radius, 0.0, 1.0), falloff);
+    if (cutoff > -1.0 && dist > 0.0) {
+        float spot = dot(delta / dist, direction);
+        attenuation *= smoothstep(cutoff, cutoff + (1.0 - cutoff) * 0.2, spot);
+    }
+    vec4 normal = texture2D(normals, gl_FragCoord.xy / resolution);
+    vec3 toLight = normalize(vec3(-delta, height));
+    float diffuse = max(dot(normal.xyz * 2.0 - 1.0, toLight), 0.0);
+    attenuation *= mix(1.0, diffuse, normal.a * useNormals);
+    gl_FragColor = vec4(radiance * attenuation, 1.0);
+}
+`
+
+// The light map is opaque, whatever its alpha after the shadow masks.
+var lightCompositeShader = `
+uniform sampler2D tex;
+
+varying vec2 uvout;
+
+void main() {
+    gl_FragColor = vec4(texture2D(tex, uvout).rgb, 1.0);
+}
+`
+
+// Normal maps are rotated and flipped with the sprites, the alpha of the
+// buffer marks the pixels having a normal.
+var normalFragmentShader = `
+uniform sampler2D tex;
+uniform sampler2D normalMap;
+uniform vec2 flip;
+uniform float rotation;
+
+varying vec2 uvout;
+
+void main() {
+    vec4 texel = texture2D(tex, uvout);
+    if (texel.a < 0.5) {
+        discard;
+    }
+    vec3 normal = texture2D(normalMap, uvout).xyz * 2.0 - 1.0;
+    normal.xy *= flip;
+    float c = cos(rotation);
+    float s = sin(rotation);
+    normal.xy = vec2(c * normal.x - s * normal.y, s * normal.x + c * normal.y);
+    gl_FragColor = vec4(normal * 0.5 + 0.5, 1.0);
+}
+`
+
+// The lighting programs are shared by all the cameras.
+var lighting struct {
+	compiled bool
+	failed   bool
+
+	light     uint32
+	locations map[string]int32
+	composite *PostEffect
+	normals   *Material
+
+	mesh       *Mesh
+	screenQuad *Mesh
+	// A flat normal map, for cameras without normal mapped sprites.
+	flat uint32
+}
+
+func compileLighting() bool {
+	if lighting.failed {
+		return false
+	}
+	if lighting.compiled {
+		return true
+	}
+	if !compileParticleProgram() {
+		lighting.failed = true
+		return false
+	}
+	program, err := GLCompileProgram(lightVertexShader, lightFragmentShader)
+	if err != nil {
+		fmt.Println("lights", err)
+		lighting.failed = true
+		return false
+	}
+	lighting.light = program
+	lighting.locations = make(map[string]int32)
+	for _, name := range []string{"ortho", "center", "radiance", "radius", "falloff", "direction", "cutoff", "height", "normals", "useNormals", "resolution"} {
+		lighting.locations[name] = GLUniformLocation(program, name)
+	}
+	lighting.composite = &PostEffect{Name: "lighting", source: &postEffectSource{fragment: lightCompositeShader}, uniforms: make(map[string]interface{})}
+	lighting.normals = NewMaterial("normals", "", normalFragmentShader)
+
+	lighting.mesh = newDynamicMesh()
+	lighting.screenQuad = newScreenQuadMesh()
+	lighting.flat = GLTexturePixels([]uint8{128, 128, 255, 0}, 1, 1)
+	lighting.compiled = true
+	return true
+}
+
+// uploadLightingMesh fills the shared mesh with triangles in world
+// coordinates, the alphas are the ones of the vertex colors.
+func uploadLightingMesh(vertices []float32, alphas []float32) {
+	mesh := lighting.mesh
+	mesh.vertices = vertices
+	mesh.uvs = mesh.uvs[:0]
+	mesh.colors = mesh.colors[:0]
+	for i := 0; i < len(vertices)/2; i++ {
+		mesh.uvs = append(mesh.uvs, 0, 0)
+		alpha := float32(1)
+		if alphas != nil {
+			alpha = alphas[i]
+		}
+		mesh.colors = append(mesh.colors, 0, 0, 0, alpha)
+	}
+	mesh.uploadDynamic()
+}
+
+// A normal mapped component draws its normals for the lighting.
+type normalMapped interface {
+	hasNormalMap() bool
+	drawNormals(gameObject *GameObject, camera *Camera)
+}
+
+// The light map and the normals of a camera, as large as its viewport.
+type lightingTargets struct {
+	lightMap *Texture
+	normals  *Texture
+}
+
+func resizeLightingTarget(target *Texture, width, height uint32) (*Texture, error) {
+	if target != nil && target.Width == width && target.Height == height {
+		return target, nil
+	}
+	if target != nil {
+		target.Destroy()
+	}
+	return newRenderTarget("", width, height)
+}
+
+func (targets *lightingTargets) release() {
+	for _, target := range []*Texture{targets.lightMap, targets.normals} {
+		if target != nil {
+			target.Destroy()
+		}
+	}
+	targets.lightMap = nil
+	targets.normals = nil
+}
+
+// visibleLights returns the lights seen by a camera, with the ambient color.
+func (camera *Camera) visibleLights(scene *Scene) ([]*Light2D, mgl32.Vec3, bool) {
+	var lights []*Light2D
+	var ambient mgl32.Vec3
+	lit := false
+	viewBounds := camera.ViewBounds()
+	for _, light := range scene.lights {
+		gameObject := light.gameObject
+		if !gameObject.enabled || !camera.sees(gameObject) {
+			continue
+		}
+		lit = true
+		if light.lightType == LightGlobal {
+			ambient = ambient.Add(light.Radiance())
+			continue
+		}
+		position := gameObject.Position
+		if position[0]+light.radius < viewBounds[0] || position[0]-light.radius > viewBounds[2] ||
+			position[1]+light.radius < viewBounds[1] || position[1]-light.radius > viewBounds[3] {
+			continue
+		}
+		lights = append(lights, light)
+	}
+	return lights, ambient, lit
+}
+
+// renderLighting multiplies the viewport of a camera, just rendered on a
+// surface, by the light map.
+func (camera *Camera) renderLighting(scene *Scene, surface *Texture, x, y, width, height int32) {
+	lights, ambient, lit := camera.visibleLights(scene)
+	if !lit || width <= 0 || height <= 0 || !compileLighting() {
+		return
+	}
+
+	targets := &camera.lighting
+	var err error
+	targets.lightMap, err = resizeLightingTarget(targets.lightMap, uint32(width), uint32(height))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	viewProjection := camera.Projection.Mul4(camera.View)
+
+	normals := camera.renderNormals(scene, uint32(width), uint32(height))
+
+	bindRenderTarget(targets.lightMap)
+	GLViewport(0, 0, width, height)
+	GLClearRect(0, 0, width, height, mgl32.Vec4{ambient[0], ambient[1], ambient[2], 1})
+
+	for _, light := range lights {
+		center := light.gameObject.Position
+		radius := light.radius
+		quad, _ := appendQuad(nil, nil, center[0]-radius, center[1]-radius, center[0]+radius, center[1]+radius, 0, 0, 0, 0)
+
+		// The alpha channel masks the shadows of the light.
+		GLColorMask(false, false, false, true)
+		GLClearRect(0, 0, width, height, mgl32.Vec4{0, 0, 0, 1})
+		if light.shadows {
+			polygons := polygonsNear(scene, center, radius)
+			if len(polygons) > 0 {
+				vertices, alphas := shadowGeometry(center, radius*2, polygons)
+				setBlendMode(blendReplace)
+				GLUseProgram(particleProgram.id)
+				GLUniform(particleProgram.ortho, viewProjection)
+				GLUniform(particleProgram.textured, float32(0))
+				uploadLightingMesh(vertices, alphas)
+				GLDrawMesh(lighting.mesh, 0)
+			}
+		}
+		GLColorMask(true, true, true, false)
+
+		setBlendMode(blendMasked)
+		GLUseProgram(lighting.light)
+		locations := lighting.locations
+		GLUniform(locations["ortho"], viewProjection)
+		GLUniform(locations["center"], center)
+		GLUniform(locations["radiance"], light.Radiance())
+		GLUniform(locations["radius"], radius)
+		GLUniform(locations["falloff"], light.falloff)
+		GLUniform(locations["cutoff"], light.cutoff())
+		GLUniform(locations["height"], light.height)
+		GLUniform(locations["resolution"], mgl32.Vec2{float32(width), float32(height)})
+		if light.lightType == LightSpot {
+			GLUniform(locations["direction"], light.Direction())
+		}
+		useNormals := float32(0)
+		normalsId := lighting.flat
+		if normals != nil {
+			useNormals = 1
+			normalsId = normals.tid
+		}
+		GLUniform(locations["useNormals"], useNormals)
+		GLBindTextureUnit(1, normalsId)
+		GLUniform(locations["normals"], 1)
+		uploadLightingMesh(quad, nil)
+		IncPerFrameStats("GL.DrawCalls", 1)
+		GLDrawMesh(lighting.mesh, 0)
+		GLColorMask(true, true, true, true)
+	}
+
+	bindRenderTarget(surface)
+	GLViewport(x, y, width, height)
+	setBlendMode(BlendMultiply)
+	if lighting.composite.use(targets.lightMap, 0) {
+		IncPerFrameStats("GL.DrawCalls", 1)
+		GLDrawMesh(lighting.screenQuad, targets.lightMap.tid)
+	}
+}
+
+// renderNormals draws the normal maps seen by the camera, returning nil when
+// there are none.
+func (camera *Camera) renderNormals(scene *Scene, width, height uint32) *Texture {
+	type normalDraw struct {
+		gameObject *GameObject
+		mapped     normalMapped
+	}
+	var draws []normalDraw
+	for _, order := range scene.orderedKeys {
+		for _, gameObject := range scene.orderedGameObjects[order] {
+			if !gameObject.enabled || !camera.sees(gameObject) {
+				continue
+			}
+			for _, key := range gameObject.componentsKeys {
+				mapped, ok := gameObject.components[key].(normalMapped)
+				if ok && mapped.hasNormalMap() {
+					draws = append(draws, normalDraw{gameObject, mapped})
+				}
+			}
+		}
+	}
+	if len(draws) == 0 {
+		return nil
+	}
+
+	targets := &camera.lighting
+	var err error
+	targets.normals, err = resizeLightingTarget(targets.normals, width, height)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	bindRenderTarget(targets.normals)
+	GLViewport(0, 0, int32(width), int32(height))
+	GLClearRect(0, 0, int32(width), int32(height), mgl32.Vec4{0.5, 0.5, 1, 0})
+	for _, draw := range draws {
+		draw.mapped.drawNormals(draw.gameObject, camera)
+	}
+	return targets.normals
+}
+
+func (light *Light2D) SetAttr(attr string, value interface{}) error {
+	var err error
+	switch attr {
+	case "type":
+		name, _ := value.(string)
+		lightType, ok := lightTypeNames[name]
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects point, spot or global", attr, light)
+		}
+		light.lightType = lightType
+	case "color":
+		var color []float32
+		color, err = castFloat32List(value, 3)
+		if err == nil {
+			light.color = mgl32.Vec3{color[0], color[1], color[2]}
+		}
+	case "intensity":
+		light.intensity, err = CastFloat32(value)
+	case "radius":
+		light.radius, err = CastFloat32(value)
+	case "falloff":
+		light.falloff, err = CastFloat32(value)
+	case "angle":
+		light.angle, err = CastFloat32(value)
+	case "height":
+		light.height, err = CastFloat32(value)
+	case "shadows":
+		light.shadows, err = CastBool(value)
+	default:
+		return fmt.Errorf("%v attribute of %T not found", attr, light)
+	}
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, light, err)
+	}
+	return nil
+}
+
+func (light *Light2D) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "type":
+		return light.lightType.String(), nil
+	case "color":
+		return light.color, nil
+	case "intensity":
+		return light.intensity, nil
+	case "radius":
+		return light.radius, nil
+	case "falloff":
+		return light.falloff, nil
+	case "angle":
+		return light.angle, nil
+	case "height":
+		return light.height, nil
+	case "shadows":
+		return light.shadows, nil
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, light)
+}
+
+func (light *Light2D) GetType() string {
+	return "Light2D"
+}
+
+// The optional argument is the type of the light.
+func initLight2D(args []interface{}) Component {
+	light := NewLight2D(LightPoint)
+	if len(args) > 0 {
+		err := light.SetAttr("type", args[0])
+		if err != nil {
+			panic(err)
+		}
+	}
+	return light
+}
+
+func init() {
+	RegisterComponent("Light2D", initLight2D)
+}
@@ -0,0 +1,99 @@
+package gozmo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestLight2DAttrs(t *testing.T) {
+	light := initLight2D([]interface{}{"spot"}).(*Light2D)
+	if light.lightType != LightSpot {
+		t.Error("Expected spot, got", light.lightType)
+	}
+	err := light.SetAttr("color", []interface{}{1, 0.5, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	light.SetAttr("intensity", 2)
+	if light.Radiance() != (mgl32.Vec3{2, 1, 0}) {
+		t.Error("Expected 2 1 0, got", light.Radiance())
+	}
+	if light.SetAttr("type", "area") == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+	lightType, _ := light.GetAttr("type")
+	if lightType != "spot" {
+		t.Error("Expected spot, got", lightType)
+	}
+}
+
+func TestLight2DAttenuation(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Torch")
+	light := NewLight2D(LightPoint)
+	light.radius = 4
+	gameObject.AddComponent("light", light)
+
+	if value := light.attenuation(mgl32.Vec2{2, 0}); value != 0.5 {
+		t.Error("Expected 0.5, got", value)
+	}
+	light.falloff = 2
+	if value := light.attenuation(mgl32.Vec2{0, -2}); value != 0.25 {
+		t.Error("Expected 0.25, got", value)
+	}
+	if value := light.attenuation(mgl32.Vec2{5, 0}); value != 0 {
+		t.Error("Expected 0, got", value)
+	}
+
+	// Spot lights follow the rotation of their GameObject.
+	light.lightType = LightSpot
+	light.falloff = 1
+	gameObject.Rotation = math.Pi / 2
+	if value := light.attenuation(mgl32.Vec2{0, 2}); value != 0.5 {
+		t.Error("Expected 0.5, got", value)
+	}
+	if value := light.attenuation(mgl32.Vec2{2, 0}); value != 0 {
+		t.Error("Expected 0, got", value)
+	}
+}
+
+func TestShadowGeometry(t *testing.T) {
+	square := []mgl32.Vec2{{1, -1}, {2, -1}, {2, 1}, {1, 1}}
+	vertices, alphas := shadowGeometry(mgl32.Vec2{0, 0}, 10, [][]mgl32.Vec2{square})
+	// Two triangles per edge, then the occluder itself.
+	if len(vertices) != (4*6+2*3)*2 || len(alphas) != len(vertices)/2 {
+		t.Fatal("Unexpected sizes", len(vertices), len(alphas))
+	}
+	// The far end of the first edge is pushed away from the light.
+	far := mgl32.Vec2{vertices[4], vertices[5]}
+	expected := mgl32.Vec2{2, -1}.Add(mgl32.Vec2{2, -1}.Normalize().Mul(10))
+	if !far.ApproxEqual(expected) {
+		t.Error("Expected", expected, "got", far)
+	}
+	if alphas[0] != 0 || alphas[len(alphas)-1] != 1 {
+		t.Error("Expected shadows then lit occluders, got", alphas)
+	}
+}
+
+func TestHitBoxShadows(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Wall")
+	gameObject.SetPosition(3, 0)
+	hitbox := NewHitBox(0, 0, 2, 4)
+	gameObject.AddComponent("hitbox", hitbox)
+	defer gameObject.Destroy()
+
+	if len(polygonsNear(scene, mgl32.Vec2{0, 0}, 5)) != 0 {
+		t.Error("Expected no shadows")
+	}
+	hitbox.SetAttr("castShadows", true)
+	polygons := polygonsNear(scene, mgl32.Vec2{0, 0}, 5)
+	if len(polygons) != 1 || polygons[0][0] != (mgl32.Vec2{2, -2}) || polygons[0][2] != (mgl32.Vec2{4, 2}) {
+		t.Error("Unexpected polygons", polygons)
+	}
+	if len(polygonsNear(scene, mgl32.Vec2{-5, 0}, 5)) != 0 {
+		t.Error("Expected the wall out of range")
+	}
+}
@@ -2,20 +2,26 @@
 package lua
 
 import (
+	"bytes"
 	"fmt"
 
 	goz "github.com/20tab/gozmo"
 )
 
 type Lua struct {
-	state *lua.LState
+	state    *lua.LState
+	fileName string
+	unwatch  func()
 }
 
 func NewLua(fileName string) *Lua {
 	l := Lua{}
 	ls := lua.NewState()
 	l.state = ls
-	err := ls.DoFile(fileName)
+
+	ls.SetGlobal("input", ls.SetFuncs(ls.NewTable(), inputFunctions))
+
+	err := doFile(ls, fileName)
 	if err != nil {
 		panic(err)
 	}
@@ -23,9 +29,42 @@ func NewLua(fileName string) *Lua {
 	mt := ls.NewTypeMetatable("gameobject")
 	ls.SetField(mt, "__index", ls.SetFuncs(ls.NewTable(), gameobjectMethods))
 
+	l.fileName = fileName
+	l.unwatch = goz.WatchFile(fileName, l.reload)
+
 	return &l
 }
 
+// reload runs the changed script again in the same state, replacing its
+// functions while keeping the gameobject bindings and the other globals.
+func (l *Lua) reload() {
+	err := doFile(l.state, l.fileName)
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// doFile runs a script read from the file system of the engine.
+func doFile(L *lua.LState, fileName string) error {
+	data, err := goz.DefaultFileSystem().ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	fn, err := L.Load(bytes.NewReader(data), fileName)
+	if err != nil {
+		return err
+	}
+	L.Push(fn)
+	return L.PCall(0, lua.MultRet, nil)
+}
+
+func (l *Lua) Destroy(g *goz.GameObject) {
+	if l.unwatch != nil {
+		l.unwatch()
+		l.unwatch = nil
+	}
+}
+
 func gameobjectCheck(L *lua.LState) *goz.GameObject {
 	ud := L.CheckUserData(1)
 	if v, ok := ud.Value.(*goz.GameObject); ok {
@@ -79,6 +118,8 @@ func gameobjectSetAttr(L *lua.LState) int {
 		err = g.SetAttr(L.CheckString(2), L.CheckString(3), float32(lua.LVAsNumber(v)))
 	case lua.LString:
 		err = g.SetAttr(L.CheckString(2), L.CheckString(3), lua.LVAsString(v))
+	case lua.LBool:
+		err = g.SetAttr(L.CheckString(2), L.CheckString(3), lua.LVAsBool(v))
 	}
 
 	if err != nil {
@@ -93,6 +134,74 @@ var gameobjectMethods = map[string]lua.LGFunction{
 	"setattr": gameobjectSetAttr,
 }
 
+// Input actions, e.g. input.pressed("jump") or input.axis("moveX").
+
+func inputHeld(L *lua.LState) int {
+	L.Push(lua.LBool(goz.GetInputActionHeld(L.CheckString(1))))
+	return 1
+}
+
+func inputPressed(L *lua.LState) int {
+	L.Push(lua.LBool(goz.GetInputActionPressed(L.CheckString(1))))
+	return 1
+}
+
+func inputReleased(L *lua.LState) int {
+	L.Push(lua.LBool(goz.GetInputActionReleased(L.CheckString(1))))
+	return 1
+}
+
+func inputAxis(L *lua.LState) int {
+	L.Push(lua.LNumber(goz.GetInputActionValue(L.CheckString(1))))
+	return 1
+}
+
+// input.bind("jump", "key:Space", "gamepad:South") replaces the bindings,
+// input.bind("moveX", {axis = true, bindings = {"gamepad:LeftX"}}) binds an
+// axis as in the actions config.
+func inputBind(L *lua.LState) int {
+	name := L.CheckString(1)
+	var bindings []string
+	axis := false
+	setAxis := false
+	table, ok := L.Get(2).(*lua.LTable)
+	if ok {
+		axis = lua.LVAsBool(table.RawGetString("axis"))
+		setAxis = true
+		list, _ := table.RawGetString("bindings").(*lua.LTable)
+		if list != nil {
+			for i := 1; i <= list.Len(); i++ {
+				bindings = append(bindings, lua.LVAsString(list.RawGetInt(i)))
+			}
+		}
+	} else {
+		for i := 2; i <= L.GetTop(); i++ {
+			bindings = append(bindings, L.CheckString(i))
+		}
+	}
+	action := goz.GetInputAction(name)
+	if action == nil {
+		action = goz.NewInputAction(name, axis)
+	}
+	err := action.Rebind(bindings...)
+	if err != nil {
+		fmt.Println(err)
+		return 0
+	}
+	if setAxis {
+		action.Axis = axis
+	}
+	return 0
+}
+
+var inputFunctions = map[string]lua.LGFunction{
+	"held":     inputHeld,
+	"pressed":  inputPressed,
+	"released": inputReleased,
+	"axis":     inputAxis,
+	"bind":     inputBind,
+}
+
 func (l *Lua) Start(g *goz.GameObject) {
 	L := l.state
 
@@ -0,0 +1,321 @@
+package gozmo
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// A Material is a shader program with named uniforms and textures, used by
+// Renderers and TileMaps in place of the default shader.
+//
+// Shaders are written in GLSL ES 1.0. The engine sets the "ortho", "bounds",
+// "uvdelta", "addColor", "mulColor" and "time" uniforms and the "tex" sampler,
+// the default vertex shader passes the texture coordinates in the "uvout"
+// varying. Additional textures of the scene are bound to named samplers.
+type Material struct {
+	Name           string
+	vertexSource   string
+	fragmentSource string
+
+	uniforms map[string]interface{}
+	// Texture names by sampler.
+	textures map[string]string
+
+	// The program is compiled at the first use.
+	program   uint32
+	compiled  bool
+	failed    bool
+	locations map[string]int32
+}
+
+var defaultVertexShader = `
+attribute vec2 vertex;
+attribute vec2 uv;
+
+uniform vec4 uvdelta;
+uniform vec2 bounds;
+uniform mat4 ortho;
+
+varying vec2 uvout;
+
+void main() {
+    gl_Position = ortho * vec4(vertex.xy * bounds.xy, 0.0, 1.0);
+
+    vec2 uv2 = uv;
+
+    if (uvdelta == vec4(0.0, 0.0, 0.0, 0.0)) {
+        uvout = uv2;
+        return;
+    }
+
+    if (uv2.x == 0.0) {
+        uv2.x = uvdelta.x;
+    }
+    else {
+        uv2.x = uvdelta.x + uvdelta.z;
+    }
+
+    if (uv2.y == 0.0) {
+        uv2.y = uvdelta.y;
+    }
+    else {
+        uv2.y = uvdelta.y + uvdelta.w;
+    }
+
+    uvout = uv2;
+}
+`
+
+// NewMaterial creates a material from shader sources, the default vertex
+// shader is used when vertexSource is empty.
+func NewMaterial(name string, vertexSource string, fragmentSource string) *Material {
+	if vertexSource == "" {
+		vertexSource = defaultVertexShader
+	}
+	material := Material{Name: name, vertexSource: vertexSource, fragmentSource: fragmentSource}
+	material.uniforms = make(map[string]interface{})
+	material.textures = make(map[string]string)
+	return &material
+}
+
+// NewMaterialFromFilenames loads the shaders of a material, vertexFileName
+// can be empty.
+func (scene *Scene) NewMaterialFromFilenames(name string, vertexFileName string, fragmentFileName string) (*Material, error) {
+	var vertexSource []byte
+	var err error
+	if vertexFileName != "" {
+		vertexSource, err = fileSystem.ReadFile(vertexFileName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	fragmentSource, err := fileSystem.ReadFile(fragmentFileName)
+	if err != nil {
+		return nil, err
+	}
+	material := NewMaterial(name, string(vertexSource), string(fragmentSource))
+	scene.materials[name] = material
+	return material, nil
+}
+
+func (scene *Scene) GetMaterial(name string) *Material {
+	material, ok := scene.materials[name]
+	if !ok {
+		return nil
+	}
+	return material
+}
+
+func (material *Material) SetUniform(name string, value interface{}) error {
+	uniform, err := castUniform(value)
+	if err != nil {
+		return fmt.Errorf("uniform %v of material %v %v", name, material.Name, err)
+	}
+	material.uniforms[name] = uniform
+	return nil
+}
+
+func (material *Material) GetUniform(name string) interface{} {
+	return material.uniforms[name]
+}
+
+// SetTexture binds a texture of the scene to a sampler.
+func (material *Material) SetTexture(sampler string, textureName string) {
+	material.textures[sampler] = textureName
+}
+
+func (material *Material) location(name string) int32 {
+	location, ok := material.locations[name]
+	if !ok {
+		location = GLUniformLocation(material.program, name)
+		material.locations[name] = location
+	}
+	return location
+}
+
+// compile builds the program at the first use, errors are reported once.
+func (material *Material) compile() bool {
+	if material.failed {
+		return false
+	}
+	if material.compiled {
+		return true
+	}
+	program, err := GLCompileProgram(material.vertexSource, material.fragmentSource)
+	if err != nil {
+		fmt.Println("material", material.Name, err)
+		material.failed = true
+		return false
+	}
+	material.program = program
+	material.locations = make(map[string]int32)
+	material.compiled = true
+	return true
+}
+
+// draw renders a mesh with the material, returning false if it cannot be
+// compiled. Uniform values in overrides replace the material ones for this
+// draw only.
+func (material *Material) draw(scene *Scene, mesh *Mesh, textureId uint32, bounds mgl32.Vec2, uvdelta mgl32.Vec4, ortho mgl32.Mat4, overrides map[string]interface{}) bool {
+	if !material.compile() {
+		return false
+	}
+
+	GLUseProgram(material.program)
+	for name, value := range material.uniforms {
+		_, overridden := overrides[name]
+		if !overridden {
+			GLUniform(material.location(name), value)
+		}
+	}
+	for name, value := range overrides {
+		GLUniform(material.location(name), value)
+	}
+	GLUniform(material.location("ortho"), ortho)
+	GLUniform(material.location("bounds"), bounds)
+	GLUniform(material.location("uvdelta"), uvdelta)
+	GLUniform(material.location("addColor"), mesh.addColor)
+	GLUniform(material.location("mulColor"), mesh.mulColor)
+	GLUniform(material.location("premultiply"), mesh.premultiply)
+	GLUniform(material.location("time"), float32(scene.lastTime))
+
+	// The first unit is used by the main texture.
+	var samplers []string
+	for sampler := range material.textures {
+		samplers = append(samplers, sampler)
+	}
+	sort.Strings(samplers)
+	unit := 1
+	for _, sampler := range samplers {
+		texture, ok := scene.textures[material.textures[sampler]]
+		if !ok {
+			continue
+		}
+		GLBindTextureUnit(unit, texture.tid)
+		GLUniform(material.location(sampler), unit)
+		unit++
+	}
+
+	GLDrawMesh(mesh, textureId)
+
+	// Uniforms keep their values in the program, shared by all of the users
+	// of the material: the ones it does not declare are reset to zero.
+	for name, value := range overrides {
+		_, declared := material.uniforms[name]
+		if !declared {
+			GLUniform(material.location(name), reflect.Zero(reflect.TypeOf(value)).Interface())
+		}
+	}
+	return true
+}
+
+// A materialRef assigns a material of the scene (by name) to a component,
+// with its own uniform values, so that animating them (through the
+// "material.uniform" attributes) does not affect other users of the material.
+type materialRef struct {
+	name     string
+	material *Material
+	uniforms map[string]interface{}
+}
+
+func (ref *materialRef) resolve(scene *Scene) *Material {
+	if ref.name == "" {
+		ref.material = nil
+		return nil
+	}
+	if ref.material == nil || ref.material.Name != ref.name {
+		ref.material = scene.GetMaterial(ref.name)
+	}
+	return ref.material
+}
+
+// setAttr manages the "material" and "material.uniform" attributes, returning
+// false for the other ones.
+func (ref *materialRef) setAttr(attr string, value interface{}) (bool, error) {
+	if attr == "material" {
+		name, ok := value.(string)
+		if !ok {
+			return true, fmt.Errorf("expects a string")
+		}
+		ref.name = name
+		ref.material = nil
+		return true, nil
+	}
+	if !strings.HasPrefix(attr, "material.") {
+		return false, nil
+	}
+	uniform, err := castUniform(value)
+	if err != nil {
+		return true, err
+	}
+	if ref.uniforms == nil {
+		ref.uniforms = make(map[string]interface{})
+	}
+	ref.uniforms[attr[len("material."):]] = uniform
+	return true, nil
+}
+
+func (ref *materialRef) getAttr(attr string) (interface{}, bool) {
+	if attr == "material" {
+		return ref.name, true
+	}
+	if !strings.HasPrefix(attr, "material.") {
+		return nil, false
+	}
+	name := attr[len("material."):]
+	value, ok := ref.uniforms[name]
+	if ok {
+		return value, true
+	}
+	if ref.material != nil {
+		value, ok = ref.material.uniforms[name]
+		if ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func loadMaterials(scene *Scene, materials []interface{}) {
+	for _, material := range materials {
+		materialMap := material.(map[string]interface{})
+
+		name, ok := materialMap["name"].(string)
+		if !ok {
+			panic("material requires a name")
+		}
+
+		fragment, ok := materialMap["fragment"].(string)
+		if !ok {
+			panic("material requires a fragment shader")
+		}
+
+		vertex, _ := materialMap["vertex"].(string)
+
+		mat, err := scene.NewMaterialFromFilenames(name, vertex, fragment)
+		if err != nil {
+			panic(err)
+		}
+
+		uniforms, _ := materialMap["uniforms"].(map[string]interface{})
+		for uniform, value := range uniforms {
+			err = mat.SetUniform(uniform, value)
+			if err != nil {
+				panic(err)
+			}
+		}
+
+		textures, _ := materialMap["textures"].(map[string]interface{})
+		for sampler, textureName := range textures {
+			texture, ok := textureName.(string)
+			if !ok {
+				panic("material textures must be names")
+			}
+			mat.SetTexture(sampler, texture)
+		}
+	}
+}
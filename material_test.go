@@ -0,0 +1,28 @@
+package gozmo
+
+import (
+	"testing"
+)
+
+func TestMaterialUniforms(t *testing.T) {
+	scene := NewScene("Test")
+	material := NewMaterial("flash", "", "")
+	scene.materials["flash"] = material
+	material.SetUniform("amount", 0)
+
+	renderer := NewRenderer(nil)
+	renderer.SetAttr("material", "flash")
+	renderer.material.resolve(scene)
+
+	value, _ := renderer.GetAttr("material.amount")
+	if value != float32(0) {
+		t.Error("Expected 0, got", value)
+	}
+
+	// Animating a Renderer does not change the material.
+	renderer.SetAttr("material.amount", float32(0.5))
+	value, _ = renderer.GetAttr("material.amount")
+	if value != float32(0.5) || material.GetUniform("amount") != float32(0) {
+		t.Error("Expected 0.5 and 0, got", value, material.GetUniform("amount"))
+	}
+}
@@ -10,12 +10,57 @@ type Mesh struct {
 
 	vbid  uint32
 	uvbid uint32
+	// Only dynamic meshes have per vertex colors.
+	colors   []float32
+	colorbid uint32
 
 	abid uint32
 
 	addColor mgl32.Vec4
 	mulColor mgl32.Vec4
+	// 1 to multiply the drawn colors by alpha, see BlendMultiply.
+	premultiply float32
 }
 
 // Points to the shader id.
 var shader int32 = -1
+
+// upload sends the vertices and uvs of an existing mesh to the GPU.
+func (mesh *Mesh) upload() {
+	GLBindArray(mesh.abid)
+	GLBufferData(0, mesh.vbid, mesh.vertices)
+	GLBufferData(1, mesh.uvbid, mesh.uvs)
+}
+
+// newDynamicMesh creates a mesh with per vertex colors, filled again on every
+// draw with uploadDynamic.
+func newDynamicMesh() *Mesh {
+	mesh := Mesh{}
+	mesh.abid = GLNewArray()
+	mesh.vbid = GLNewBuffer()
+	mesh.uvbid = GLNewBuffer()
+	mesh.colorbid = GLNewBuffer()
+	return &mesh
+}
+
+// uploadDynamic sends the vertices, uvs and colors of a dynamic mesh to the
+// GPU.
+func (mesh *Mesh) uploadDynamic() {
+	GLBindArray(mesh.abid)
+	GLBufferDynamicData(0, mesh.vbid, mesh.vertices, 2)
+	GLBufferDynamicData(1, mesh.uvbid, mesh.uvs, 2)
+	GLBufferDynamicData(2, mesh.colorbid, mesh.colors, 4)
+}
+
+// destroy deletes the buffers of the mesh.
+func (mesh *Mesh) destroy() {
+	for _, bid := range []uint32{mesh.vbid, mesh.uvbid, mesh.colorbid} {
+		if bid != 0 {
+			GLDeleteBuffer(bid)
+		}
+	}
+	if mesh.abid != 0 {
+		GLDeleteArray(mesh.abid)
+	}
+	mesh.vbid, mesh.uvbid, mesh.colorbid, mesh.abid = 0, 0, 0, 0
+}
@@ -3,12 +3,125 @@
 package gozmo
 
 import (
-	_ "github.com/go-gl/glfw/v3.1/glfw"
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.1/glfw"
 	"github.com/go-gl/mathgl/mgl32"
 )
 
-// The Mouse component converts screen coordinates into game coordinates. It
-// only handles mouse events, not touch ones, and is not bound on Android.
+// A MouseButton follows the glfw numbering, like Key.
+type MouseButton glfw.MouseButton
+
+const (
+	MouseButtonLeft   MouseButton = MouseButton(glfw.MouseButtonLeft)
+	MouseButtonRight  MouseButton = MouseButton(glfw.MouseButtonRight)
+	MouseButtonMiddle MouseButton = MouseButton(glfw.MouseButtonMiddle)
+	MouseButton4      MouseButton = MouseButton(glfw.MouseButton4)
+	MouseButton5      MouseButton = MouseButton(glfw.MouseButton5)
+)
+
+var MouseAttr map[string]MouseButton = map[string]MouseButton{
+	"Left":    MouseButtonLeft,
+	"Right":   MouseButtonRight,
+	"Middle":  MouseButtonMiddle,
+	"Button4": MouseButton4,
+	"Button5": MouseButton5,
+}
+
+// Like the keyboard, the mouse state is fed by the window callbacks and
+// latched at the start of every frame.
+type mouseState struct {
+	// Window coordinates, 0, 0 at the top left corner.
+	x float64
+	y float64
+
+	held   map[MouseButton]bool
+	down   []MouseButton
+	up     []MouseButton
+	wheelX float32
+	wheelY float32
+
+	pendingDown   []MouseButton
+	pendingUp     []MouseButton
+	pendingWheelX float32
+	pendingWheelY float32
+
+	pointer pointer
+}
+
+var mouseInput = mouseState{held: make(map[MouseButton]bool), pointer: pointer{prefix: "mouse", id: -1}}
+
+func mouseButtonEvent(button MouseButton, pressed bool) {
+	if pressed {
+		if !mouseInput.held[button] {
+			mouseInput.pendingDown = append(mouseInput.pendingDown, button)
+		}
+		mouseInput.held[button] = true
+		return
+	}
+	if mouseInput.held[button] {
+		mouseInput.pendingUp = append(mouseInput.pendingUp, button)
+	}
+	mouseInput.held[button] = false
+}
+
+func mouseMoveEvent(x, y float64) {
+	mouseInput.x = x
+	mouseInput.y = y
+}
+
+func mouseWheelEvent(x, y float64) {
+	mouseInput.pendingWheelX += float32(x)
+	mouseInput.pendingWheelY += float32(y)
+}
+
+// updateMouse latches the events received since the last frame, and raises
+// the "mouseEnter", "mouseExit", "mouseDown" and "mouseUp" events on the
+// GameObjects whose HitBox contains the cursor.
+func updateMouse(frame *InputFrame) {
+	for _, button := range frame.MouseButtons {
+		mouseButtonEvent(MouseButton(button.Button), button.Pressed)
+	}
+	mouseMoveEvent(frame.MouseX, frame.MouseY)
+	mouseWheelEvent(frame.WheelX, frame.WheelY)
+
+	mouseInput.down = mouseInput.pendingDown
+	mouseInput.up = mouseInput.pendingUp
+	mouseInput.wheelX = mouseInput.pendingWheelX
+	mouseInput.wheelY = mouseInput.pendingWheelY
+	mouseInput.pendingDown = nil
+	mouseInput.pendingUp = nil
+	mouseInput.pendingWheelX = 0
+	mouseInput.pendingWheelY = 0
+
+	if Engine.Window == nil {
+		return
+	}
+
+	position := Engine.Window.ScreenToWorld(mouseInput.x, mouseInput.y)
+	mouseInput.pointer.move(position)
+	for _, button := range mouseInput.down {
+		mouseInput.pointer.press(int(button), position)
+	}
+	for _, button := range mouseInput.up {
+		mouseInput.pointer.release(int(button), position)
+	}
+}
+
+func containsMouseButton(buttons []MouseButton, button MouseButton) bool {
+	for _, b := range buttons {
+		if b == button {
+			return true
+		}
+	}
+	return false
+}
+
+// The Mouse component converts screen coordinates into game coordinates,
+// using the window size, the projection and the current camera view, and
+// reports the button and wheel state of the current frame. It only handles
+// mouse events, not touch ones, and is not bound on Android.
 type Mouse struct{}
 
 func (mouse *Mouse) Start(gameObject *GameObject)  {}
@@ -22,26 +135,86 @@ func (mouse *Mouse) GetName() string {
 	return "Mouse"
 }
 
-func (mouse *Mouse) X() float32 {
-	x, y := Engine.Window.glfwWindow.GetCursorPos()
-	if x < 0 {
-		x = 0
+// Position returns the cursor in world coordinates, or in screen ones without
+// a window (e.g. replaying input headless).
+func (mouse *Mouse) Position() mgl32.Vec2 {
+	if Engine.Window == nil {
+		return mgl32.Vec2{float32(mouseInput.x), float32(mouseInput.y)}
 	}
-	if x > 1024-1 {
-		x = 1024 - 1
-	}
-	vecScreen := mgl32.Vec4{float32(2*x/1024) - 1, float32(2*y/576) - 1, 0, 1}
-	vecWorld := Engine.Window.Projection.Inv().Mul4x1(vecScreen)
-	return vecWorld[0]
+	return Engine.Window.ScreenToWorld(mouseInput.x, mouseInput.y)
+}
+
+func (mouse *Mouse) X() float32 {
+	return mouse.Position()[0]
 }
 
 func (mouse *Mouse) Y() float32 {
-	return 0
+	return mouse.Position()[1]
+}
+
+// ScreenPosition returns the cursor in window coordinates.
+func (mouse *Mouse) ScreenPosition() (float32, float32) {
+	return float32(mouseInput.x), float32(mouseInput.y)
+}
+
+// GetButton reports whether the button is held.
+func (mouse *Mouse) GetButton(button MouseButton) bool {
+	return mouseInput.held[button]
+}
+
+// GetButtonDown reports whether the button has been pressed in this frame.
+func (mouse *Mouse) GetButtonDown(button MouseButton) bool {
+	return containsMouseButton(mouseInput.down, button)
+}
+
+// GetButtonUp reports whether the button has been released in this frame.
+func (mouse *Mouse) GetButtonUp(button MouseButton) bool {
+	return containsMouseButton(mouseInput.up, button)
 }
 
-// TODO: what if the user specifies an unknown key?
+// Wheel returns the scrolling of this frame.
+func (mouse *Mouse) Wheel() (float32, float32) {
+	return mouseInput.wheelX, mouseInput.wheelY
+}
+
+// Attributes are "x", "y" (world), "screenX", "screenY", "wheelX", "wheelY"
+// and button names ("Left"), optionally followed by ".down" or ".up".
 func (mouse *Mouse) GetAttr(attr string) (interface{}, error) {
-	return 0, nil
+	switch attr {
+	case "x":
+		return mouse.X(), nil
+	case "y":
+		return mouse.Y(), nil
+	case "screenX":
+		return float32(mouseInput.x), nil
+	case "screenY":
+		return float32(mouseInput.y), nil
+	case "wheelX":
+		return mouseInput.wheelX, nil
+	case "wheelY":
+		return mouseInput.wheelY, nil
+	}
+
+	name := attr
+	query := ""
+	dot := strings.LastIndex(attr, ".")
+	if dot > 0 {
+		name = attr[:dot]
+		query = attr[dot+1:]
+	}
+
+	button, ok := MouseAttr[name]
+	if !ok {
+		return nil, fmt.Errorf("%v attribute of %T not found", attr, mouse)
+	}
+
+	switch query {
+	case "down":
+		return mouse.GetButtonDown(button), nil
+	case "up":
+		return mouse.GetButtonUp(button), nil
+	}
+	return mouse.GetButton(button), nil
 }
 
 func NewMouse() *Mouse {
@@ -53,6 +226,27 @@ func initMouse(args []interface{}) Component {
 	return NewMouse()
 }
 
+// readMouseInput is the "mouse" input device, codes are MouseAttr names,
+// "WheelX" and "WheelY".
+func readMouseInput(code string) float32 {
+	switch code {
+	case "WheelX":
+		return mouseInput.wheelX
+	case "WheelY":
+		return mouseInput.wheelY
+	}
+	button, ok := MouseAttr[code]
+	if !ok {
+		return 0
+	}
+	if mouseInput.held[button] {
+		return 1
+	}
+	return 0
+}
+
 func init() {
 	RegisterComponent("Mouse", initMouse)
+	RegisterInputDevice("mouse", readMouseInput)
+	registerInputUpdater(updateMouse)
 }
@@ -5,6 +5,7 @@ package gozmo
 import (
 	"fmt"
 	"image"
+	"strings"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/mathgl/mgl32"
@@ -26,22 +27,41 @@ func GLInit(width int32, height int32) {
 	gl.ClearColor(0, 0, 0, 1)
 }
 
+func GLViewport(x, y, width, height int32) {
+	gl.Viewport(x, y, width, height)
+}
+
 func GLClear() {
 	gl.Clear(gl.COLOR_BUFFER_BIT)
 }
 
+// GLClearRect clears only a rectangle of the framebuffer, with a color.
+func GLClearRect(x, y, width, height int32, color mgl32.Vec4) {
+	gl.Enable(gl.SCISSOR_TEST)
+	gl.Scissor(x, y, width, height)
+	gl.ClearColor(color[0], color[1], color[2], color[3])
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+	gl.ClearColor(0, 0, 0, 1)
+	gl.Disable(gl.SCISSOR_TEST)
+}
+
 func GLTexture(rgba *image.RGBA) uint32 {
+	return GLTexturePixels(rgba.Pix, int32(rgba.Rect.Size().X), int32(rgba.Rect.Size().Y))
+}
+
+// GLTexturePixels uploads RGBA pixels.
+func GLTexturePixels(pixels []uint8, width, height int32) uint32 {
 	var texture uint32
 	gl.GenTextures(1, &texture)
 	gl.ActiveTexture(gl.TEXTURE0)
 	gl.BindTexture(gl.TEXTURE_2D, texture)
 	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA,
-		int32(rgba.Rect.Size().X),
-		int32(rgba.Rect.Size().Y),
+		width,
+		height,
 		0,
 		gl.RGBA,
 		gl.UNSIGNED_BYTE,
-		gl.Ptr(rgba.Pix))
+		gl.Ptr(pixels))
 
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
@@ -53,6 +73,19 @@ func GLTexture(rgba *image.RGBA) uint32 {
 	return texture
 }
 
+// GLUpdateTexture replaces the pixels of a texture, e.g. when its file is
+// reloaded.
+func GLUpdateTexture(texture uint32, pixels []uint8, width, height int32) {
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA,
+		width,
+		height,
+		0,
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		gl.Ptr(pixels))
+}
+
 func GLNewBuffer() uint32 {
 	var bid uint32
 	gl.GenBuffers(1, &bid)
@@ -67,6 +100,26 @@ func GLNewArray() uint32 {
 	return vao
 }
 
+func GLDeleteBuffer(bid uint32) {
+	gl.DeleteBuffers(1, &bid)
+}
+
+func GLDeleteArray(vao uint32) {
+	gl.DeleteVertexArrays(1, &vao)
+}
+
+// GLBufferDynamicData uploads vertex data changing at every frame.
+func GLBufferDynamicData(location uint32, bid uint32, data []float32, components int32) {
+	gl.BindBuffer(gl.ARRAY_BUFFER, bid)
+	gl.BufferData(gl.ARRAY_BUFFER, len(data)*4, gl.Ptr(data), gl.DYNAMIC_DRAW)
+	gl.EnableVertexAttribArray(location)
+	gl.VertexAttribPointer(location, components, gl.FLOAT, false, 0, gl.PtrOffset(0))
+}
+
+func GLBindArray(vao uint32) {
+	gl.BindVertexArray(vao)
+}
+
 func GLBufferData(location uint32, bid uint32, data []float32) {
 	gl.BindBuffer(gl.ARRAY_BUFFER, bid)
 	gl.BufferData(gl.ARRAY_BUFFER, len(data)*4, gl.Ptr(data), gl.STATIC_DRAW)
@@ -79,6 +132,7 @@ var orthoUniform int32 = -1
 var uvDeltaUniform int32 = -1
 var addColorUniform int32 = -1
 var mulColorUniform int32 = -1
+var premultiplyUniform int32 = -1
 
 func GLDraw(mesh *Mesh, shader uint32, width float32, height float32, textureId int32, uvx, uvy, uvw, uvh float32, ortho mgl32.Mat4) {
 	gl.UseProgram(shader)
@@ -88,6 +142,7 @@ func GLDraw(mesh *Mesh, shader uint32, width float32, height float32, textureId
 	gl.Uniform4f(addColorUniform, addColor[0], addColor[1], addColor[2], addColor[3])
 	mulColor := mesh.mulColor
 	gl.Uniform4f(mulColorUniform, mulColor[0], mulColor[1], mulColor[2], mulColor[3])
+	gl.Uniform1f(premultiplyUniform, mesh.premultiply)
 	gl.UniformMatrix4fv(orthoUniform, 1, false, &ortho[0])
 	gl.BindVertexArray(mesh.abid)
 	if textureId > -1 {
@@ -146,12 +201,14 @@ uniform sampler2D tex;
 
 uniform vec4 addColor;
 uniform vec4 mulColor;
+uniform float premultiply;
 
 in vec2 uvout;
 out vec4 color;
 
 void main() {
     color = texture(tex, uvout) * mulColor + addColor;
+    color.rgb *= mix(1.0, color.a, premultiply);
 }` + "\x00"
 
 func GLShader() uint32 {
@@ -185,9 +242,229 @@ func GLShader() uint32 {
 	uvDeltaUniform = gl.GetUniformLocation(programId, gl.Str("uvdelta\x00"))
 	addColorUniform = gl.GetUniformLocation(programId, gl.Str("addColor\x00"))
 	mulColorUniform = gl.GetUniformLocation(programId, gl.Str("mulColor\x00"))
+	premultiplyUniform = gl.GetUniformLocation(programId, gl.Str("premultiply\x00"))
 
 	texUniform := gl.GetUniformLocation(programId, gl.Str("tex\x00"))
 	gl.Uniform1i(texUniform, 0)
 
 	return programId
 }
+
+// GLNewFramebuffer creates a framebuffer rendering into a new RGBA texture.
+func GLNewFramebuffer(width, height int32) (uint32, uint32, error) {
+	var texture uint32
+	gl.GenTextures(1, &texture)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, width, height, 0, gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	var framebuffer uint32
+	gl.GenFramebuffers(1, &framebuffer)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, framebuffer)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, texture, 0)
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		GLDeleteFramebuffer(framebuffer, texture)
+		return 0, 0, fmt.Errorf("framebuffer incomplete (status 0x%x)", status)
+	}
+	return framebuffer, texture, nil
+}
+
+func GLDeleteFramebuffer(framebuffer, texture uint32) {
+	gl.DeleteFramebuffers(1, &framebuffer)
+	gl.DeleteTextures(1, &texture)
+}
+
+func GLDeleteTexture(texture uint32) {
+	gl.DeleteTextures(1, &texture)
+}
+
+// GLBindFramebuffer selects the render target, 0 is the window.
+func GLBindFramebuffer(framebuffer uint32) {
+	gl.BindFramebuffer(gl.FRAMEBUFFER, framebuffer)
+}
+
+// GLTextureFilter sets the filtering of a texture, generating its mipmaps
+// when required.
+func GLTextureFilter(texture uint32, filter TextureFilter, mipmaps bool) {
+	var magFilter int32 = gl.LINEAR
+	var minFilter int32 = gl.LINEAR
+	if filter == TextureNearest {
+		magFilter = gl.NEAREST
+		minFilter = gl.NEAREST
+	}
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	if mipmaps {
+		gl.GenerateMipmap(gl.TEXTURE_2D)
+		minFilter = gl.LINEAR_MIPMAP_LINEAR
+		if filter == TextureNearest {
+			minFilter = gl.NEAREST_MIPMAP_NEAREST
+		}
+	}
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, minFilter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, magFilter)
+}
+
+func GLTextureWrap(texture uint32, wrap TextureWrap) {
+	var mode int32 = gl.CLAMP_TO_EDGE
+	switch wrap {
+	case TextureRepeat:
+		mode = gl.REPEAT
+	case TextureMirror:
+		mode = gl.MIRRORED_REPEAT
+	}
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, mode)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, mode)
+}
+
+func GLBlendMode(mode BlendMode) {
+	switch mode {
+	case BlendAdditive:
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE)
+	case BlendMultiply:
+		gl.BlendFunc(gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA)
+	case BlendPremultiplied:
+		gl.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	case blendReplace:
+		gl.BlendFunc(gl.ONE, gl.ZERO)
+	case blendMasked:
+		gl.BlendFunc(gl.DST_ALPHA, gl.ONE)
+	default:
+		gl.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	}
+}
+
+// GLColorMask selects the channels written by the following draws.
+func GLColorMask(red, green, blue, alpha bool) {
+	gl.ColorMask(red, green, blue, alpha)
+}
+
+// Custom shaders are written in GLSL ES 1.0, these headers make them valid
+// GLSL 3.3 core too.
+var glslVertexHeader = `#version 330 core
+#define attribute in
+#define varying out
+`
+
+var glslFragmentHeader = `#version 330 core
+#define varying in
+#define texture2D texture
+#define gl_FragColor fragColor
+out vec4 fragColor;
+`
+
+func compileShader(kind uint32, source string) (uint32, error) {
+	shaderId := gl.CreateShader(kind)
+	csource, free := gl.Strs(source + "\x00")
+	gl.ShaderSource(shaderId, 1, csource, nil)
+	free()
+	gl.CompileShader(shaderId)
+
+	var status int32
+	gl.GetShaderiv(shaderId, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var length int32
+		gl.GetShaderiv(shaderId, gl.INFO_LOG_LENGTH, &length)
+		log := strings.Repeat("\x00", int(length+1))
+		gl.GetShaderInfoLog(shaderId, length, nil, gl.Str(log))
+		gl.DeleteShader(shaderId)
+		return 0, fmt.Errorf("failed to compile shader: %v", log)
+	}
+	return shaderId, nil
+}
+
+// GLCompileProgram builds a program from GLSL ES 1.0 sources, the "vertex"
+// and "uv" attributes are bound to the locations used by meshes, the "tex"
+// sampler to the first texture unit.
+func GLCompileProgram(vertexSource, fragmentSource string) (uint32, error) {
+	vertexShaderId, err := compileShader(gl.VERTEX_SHADER, glslVertexHeader+vertexSource)
+	if err != nil {
+		return 0, err
+	}
+	fragmentShaderId, err := compileShader(gl.FRAGMENT_SHADER, glslFragmentHeader+fragmentSource)
+	if err != nil {
+		gl.DeleteShader(vertexShaderId)
+		return 0, err
+	}
+
+	programId := gl.CreateProgram()
+	gl.AttachShader(programId, vertexShaderId)
+	gl.AttachShader(programId, fragmentShaderId)
+	gl.BindAttribLocation(programId, 0, gl.Str("vertex\x00"))
+	gl.BindAttribLocation(programId, 1, gl.Str("uv\x00"))
+	gl.BindAttribLocation(programId, 2, gl.Str("color\x00"))
+	gl.LinkProgram(programId)
+
+	gl.DetachShader(programId, vertexShaderId)
+	gl.DetachShader(programId, fragmentShaderId)
+	gl.DeleteShader(vertexShaderId)
+	gl.DeleteShader(fragmentShaderId)
+
+	var status int32
+	gl.GetProgramiv(programId, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var length int32
+		gl.GetProgramiv(programId, gl.INFO_LOG_LENGTH, &length)
+		log := strings.Repeat("\x00", int(length+1))
+		gl.GetProgramInfoLog(programId, length, nil, gl.Str(log))
+		gl.DeleteProgram(programId)
+		return 0, fmt.Errorf("failed to link program: %v", log)
+	}
+
+	gl.UseProgram(programId)
+	gl.Uniform1i(gl.GetUniformLocation(programId, gl.Str("tex\x00")), 0)
+	return programId, nil
+}
+
+func GLUseProgram(program uint32) {
+	gl.UseProgram(program)
+}
+
+func GLUniformLocation(program uint32, name string) int32 {
+	return gl.GetUniformLocation(program, gl.Str(name+"\x00"))
+}
+
+// GLUniform sets a uniform of the current program, values can be float32,
+// int, mgl32.Vec2, mgl32.Vec3, mgl32.Vec4 or mgl32.Mat4.
+func GLUniform(location int32, value interface{}) {
+	switch v := value.(type) {
+	case float32:
+		gl.Uniform1f(location, v)
+	case int:
+		gl.Uniform1i(location, int32(v))
+	case mgl32.Vec2:
+		gl.Uniform2f(location, v[0], v[1])
+	case mgl32.Vec3:
+		gl.Uniform3f(location, v[0], v[1], v[2])
+	case mgl32.Vec4:
+		gl.Uniform4f(location, v[0], v[1], v[2], v[3])
+	case mgl32.Mat4:
+		gl.UniformMatrix4fv(location, 1, false, &v[0])
+	}
+}
+
+func GLBindTextureUnit(unit int, texture uint32) {
+	gl.ActiveTexture(gl.TEXTURE0 + uint32(unit))
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+}
+
+// GLDrawLines draws the vertices of a mesh as pairs of segments.
+func GLDrawLines(mesh *Mesh) {
+	gl.BindVertexArray(mesh.abid)
+	gl.DrawArrays(gl.LINES, 0, int32(len(mesh.vertices)/2))
+}
+
+// GLDrawMesh draws a mesh with the current program and the texture bound to
+// the first unit.
+func GLDrawMesh(mesh *Mesh, texture uint32) {
+	gl.BindVertexArray(mesh.abid)
+	gl.ActiveTexture(gl.TEXTURE0)
+	gl.BindTexture(gl.TEXTURE_2D, texture)
+	gl.DrawArrays(gl.TRIANGLES, 0, int32(len(mesh.vertices)/2))
+}
@@ -4,9 +4,11 @@ package gozmo
 
 import (
 	"fmt"
+	"encoding/binary"
 	"image"
 
 	"github.com/go-gl/mathgl/mgl32"
+	"golang.org/x/mobile/exp/f32"
 	"golang.org/x/mobile/gl"
 )
 
@@ -26,20 +28,39 @@ func GLInit(width int32, height int32) {
 	glctx.ClearColor(0, 0, 0, 1)
 }
 
+func GLViewport(x, y, width, height int32) {
+	glctx.Viewport(int(x), int(y), int(width), int(height))
+}
+
 func GLClear() {
 	glctx.Clear(gl.COLOR_BUFFER_BIT)
 }
 
+// GLClearRect clears only a rectangle of the framebuffer, with a color.
+func GLClearRect(x, y, width, height int32, color mgl32.Vec4) {
+	glctx.Enable(gl.SCISSOR_TEST)
+	glctx.Scissor(x, y, width, height)
+	glctx.ClearColor(color[0], color[1], color[2], color[3])
+	glctx.Clear(gl.COLOR_BUFFER_BIT)
+	glctx.ClearColor(0, 0, 0, 1)
+	glctx.Disable(gl.SCISSOR_TEST)
+}
+
 func GLTexture(rgba *image.RGBA) uint32 {
+	return GLTexturePixels(rgba.Pix, int32(rgba.Rect.Size().X), int32(rgba.Rect.Size().Y))
+}
+
+// GLTexturePixels uploads RGBA pixels.
+func GLTexturePixels(pixels []uint8, width, height int32) uint32 {
 	texture := glctx.CreateTexture()
 	glctx.ActiveTexture(gl.TEXTURE0)
 	glctx.BindTexture(gl.TEXTURE_2D, texture)
-	glctx.TexImage2D(gl.TEXTURE_2D, 0,
-		rgba.Rect.Size().X,
-		rgba.Rect.Size().Y,
+	glctx.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA,
+		int(width),
+		int(height),
 		gl.RGBA,
 		gl.UNSIGNED_BYTE,
-		rgba.Pix)
+		pixels)
 
 	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
 	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
@@ -51,6 +72,18 @@ func GLTexture(rgba *image.RGBA) uint32 {
 	return texture.Value
 }
 
+// GLUpdateTexture replaces the pixels of a texture, e.g. when its file is
+// reloaded.
+func GLUpdateTexture(texture uint32, pixels []uint8, width, height int32) {
+	glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{Value: texture})
+	glctx.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA,
+		int(width),
+		int(height),
+		gl.RGBA,
+		gl.UNSIGNED_BYTE,
+		pixels)
+}
+
 func GLNewBuffer() uint32 {
 	bid := glctx.CreateBuffer()
 	glctx.BindBuffer(gl.ARRAY_BUFFER, bid)
@@ -63,6 +96,25 @@ func GLNewArray() uint32 {
 	return 0
 }
 
+func GLDeleteBuffer(bid uint32) {
+	glctx.DeleteBuffer(gl.Buffer{Value: bid})
+}
+
+// There are no vertex arrays in ES 2.
+func GLDeleteArray(vao uint32) {
+}
+
+// GLBufferDynamicData uploads vertex data changing at every frame.
+func GLBufferDynamicData(location uint32, bid uint32, data []float32, components int32) {
+	glctx.BindBuffer(gl.ARRAY_BUFFER, gl.Buffer{Value: bid})
+	glctx.BufferData(gl.ARRAY_BUFFER, f32.Bytes(binary.LittleEndian, data...), gl.DYNAMIC_DRAW)
+	glctx.EnableVertexAttribArray(gl.Attrib{Value: uint(location)})
+	glctx.VertexAttribPointer(gl.Attrib{Value: uint(location)}, int(components), gl.FLOAT, false, 0, 0)
+}
+
+func GLBindArray(vao uint32) {
+}
+
 func GLBufferData(location uint32, bid uint32, data []float32) {
 	glctx.BindBuffer(gl.ARRAY_BUFFER, gl.Buffer{Value: bid})
 	glctx.BufferData(gl.ARRAY_BUFFER, data, gl.STATIC_DRAW)
@@ -75,6 +127,7 @@ var orthoUniform int32 = -1
 var uvDeltaUniform int32 = -1
 var addColorUniform int32 = -1
 var mulColorUniform int32 = -1
+var premultiplyUniform int32 = -1
 
 func GLDraw(renderer *Renderer, shader uint32, width float32, height float32, uvx, uvy, uvw, uvh float32, ortho mgl32.Mat4) {
 	mesh := renderer.mesh
@@ -86,6 +139,7 @@ func GLDraw(renderer *Renderer, shader uint32, width float32, height float32, uv
 	gl.Uniform4f(addColorUniform, addColor[0], addColor[1], addColor[2], addColor[3])
 	mulColor := renderer.mulColor
 	gl.Uniform4f(mulColorUniform, mulColor[0], mulColor[1], mulColor[2], mulColor[3])
+	gl.Uniform1f(premultiplyUniform, mesh.premultiply)
 	gl.UniformMatrix4fv(orthoUniform, 1, false, &ortho[0])
 	gl.BindVertexArray(mesh.abid)
 	gl.ActiveTexture(gl.TEXTURE0)
@@ -134,12 +188,14 @@ uniform sampler2D tex;
 
 uniform vec4 addColor;
 uniform vec4 mulColor;
+uniform float premultiply;
 
 in vec2 uvout;
 out vec4 color;
 
 void main() {
     color = texture(tex, uvout) * mulColor + addColor;
+    color.rgb *= mix(1.0, color.a, premultiply);
 }` + "\x00"
 
 func GLShader() uint32 {
@@ -173,9 +229,227 @@ func GLShader() uint32 {
 	uvDeltaUniform = gl.GetUniformLocation(programId, gl.Str("uvdelta\x00"))
 	addColorUniform = gl.GetUniformLocation(programId, gl.Str("addColor\x00"))
 	mulColorUniform = gl.GetUniformLocation(programId, gl.Str("mulColor\x00"))
+	premultiplyUniform = gl.GetUniformLocation(programId, gl.Str("premultiply\x00"))
 
 	texUniform := gl.GetUniformLocation(programId, gl.Str("tex\x00"))
 	gl.Uniform1i(texUniform, 0)
 
 	return programId
 }
+
+// GLNewFramebuffer creates a framebuffer rendering into a new RGBA texture.
+func GLNewFramebuffer(width, height int32) (uint32, uint32, error) {
+	texture := glctx.CreateTexture()
+	glctx.BindTexture(gl.TEXTURE_2D, texture)
+	glctx.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int(width), int(height), gl.RGBA, gl.UNSIGNED_BYTE, nil)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+
+	framebuffer := glctx.CreateFramebuffer()
+	glctx.BindFramebuffer(gl.FRAMEBUFFER, framebuffer)
+	glctx.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, texture, 0)
+	status := glctx.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	glctx.BindFramebuffer(gl.FRAMEBUFFER, gl.Framebuffer{Value: 0})
+
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		GLDeleteFramebuffer(framebuffer.Value, texture.Value)
+		return 0, 0, fmt.Errorf("framebuffer incomplete (status 0x%x)", status)
+	}
+	return framebuffer.Value, texture.Value, nil
+}
+
+func GLDeleteFramebuffer(framebuffer, texture uint32) {
+	glctx.DeleteFramebuffer(gl.Framebuffer{Value: framebuffer})
+	glctx.DeleteTexture(gl.Texture{Value: texture})
+}
+
+func GLDeleteTexture(texture uint32) {
+	glctx.DeleteTexture(gl.Texture{Value: texture})
+}
+
+// GLBindFramebuffer selects the render target, 0 is the window.
+func GLBindFramebuffer(framebuffer uint32) {
+	glctx.BindFramebuffer(gl.FRAMEBUFFER, gl.Framebuffer{Value: framebuffer})
+}
+
+// GLTextureFilter sets the filtering of a texture, generating its mipmaps
+// when required.
+func GLTextureFilter(texture uint32, filter TextureFilter, mipmaps bool) {
+	magFilter := gl.LINEAR
+	minFilter := gl.LINEAR
+	if filter == TextureNearest {
+		magFilter = gl.NEAREST
+		minFilter = gl.NEAREST
+	}
+	glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{Value: texture})
+	if mipmaps {
+		glctx.GenerateMipmap(gl.TEXTURE_2D)
+		minFilter = gl.LINEAR_MIPMAP_LINEAR
+		if filter == TextureNearest {
+			minFilter = gl.NEAREST_MIPMAP_NEAREST
+		}
+	}
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, minFilter)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, magFilter)
+}
+
+// OpenGL ES 2 supports repeating only power of two textures.
+func GLTextureWrap(texture uint32, wrap TextureWrap) {
+	mode := gl.CLAMP_TO_EDGE
+	switch wrap {
+	case TextureRepeat:
+		mode = gl.REPEAT
+	case TextureMirror:
+		mode = gl.MIRRORED_REPEAT
+	}
+	glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{Value: texture})
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, mode)
+	glctx.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, mode)
+}
+
+func GLBlendMode(mode BlendMode) {
+	switch mode {
+	case BlendAdditive:
+		glctx.BlendFunc(gl.SRC_ALPHA, gl.ONE)
+	case BlendMultiply:
+		glctx.BlendFunc(gl.DST_COLOR, gl.ONE_MINUS_SRC_ALPHA)
+	case BlendPremultiplied:
+		glctx.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	case blendReplace:
+		glctx.BlendFunc(gl.ONE, gl.ZERO)
+	case blendMasked:
+		glctx.BlendFunc(gl.DST_ALPHA, gl.ONE)
+	default:
+		glctx.BlendFunc(gl.SRC_ALPHA, gl.ONE_MINUS_SRC_ALPHA)
+	}
+}
+
+// GLColorMask selects the channels written by the following draws.
+func GLColorMask(red, green, blue, alpha bool) {
+	glctx.ColorMask(red, green, blue, alpha)
+}
+
+// Custom shaders are written in GLSL ES 1.0.
+var glslVertexHeader = `#version 100
+`
+
+var glslFragmentHeader = `#version 100
+precision mediump float;
+`
+
+func compileShader(kind gl.Enum, source string) (gl.Shader, error) {
+	shaderId := glctx.CreateShader(kind)
+	glctx.ShaderSource(shaderId, source)
+	glctx.CompileShader(shaderId)
+	if glctx.GetShaderi(shaderId, gl.COMPILE_STATUS) == gl.FALSE {
+		log := glctx.GetShaderInfoLog(shaderId)
+		glctx.DeleteShader(shaderId)
+		return shaderId, fmt.Errorf("failed to compile shader: %v", log)
+	}
+	return shaderId, nil
+}
+
+// GLCompileProgram builds a program from GLSL ES 1.0 sources, the "vertex"
+// and "uv" attributes are bound to the locations used by meshes, the "tex"
+// sampler to the first texture unit.
+func GLCompileProgram(vertexSource, fragmentSource string) (uint32, error) {
+	vertexShaderId, err := compileShader(gl.VERTEX_SHADER, glslVertexHeader+vertexSource)
+	if err != nil {
+		return 0, err
+	}
+	fragmentShaderId, err := compileShader(gl.FRAGMENT_SHADER, glslFragmentHeader+fragmentSource)
+	if err != nil {
+		glctx.DeleteShader(vertexShaderId)
+		return 0, err
+	}
+
+	programId := glctx.CreateProgram()
+	glctx.AttachShader(programId, vertexShaderId)
+	glctx.AttachShader(programId, fragmentShaderId)
+	glctx.BindAttribLocation(programId, gl.Attrib{Value: 0}, "vertex")
+	glctx.BindAttribLocation(programId, gl.Attrib{Value: 1}, "uv")
+	glctx.BindAttribLocation(programId, gl.Attrib{Value: 2}, "color")
+	glctx.LinkProgram(programId)
+
+	glctx.DetachShader(programId, vertexShaderId)
+	glctx.DetachShader(programId, fragmentShaderId)
+	glctx.DeleteShader(vertexShaderId)
+	glctx.DeleteShader(fragmentShaderId)
+
+	if glctx.GetProgrami(programId, gl.LINK_STATUS) == gl.FALSE {
+		log := glctx.GetProgramInfoLog(programId)
+		glctx.DeleteProgram(programId)
+		return 0, fmt.Errorf("failed to link program: %v", log)
+	}
+
+	glctx.UseProgram(programId)
+	glctx.Uniform1i(glctx.GetUniformLocation(programId, "tex"), 0)
+	return programId.Value, nil
+}
+
+func GLUseProgram(program uint32) {
+	glctx.UseProgram(gl.Program{Init: true, Value: program})
+}
+
+func GLUniformLocation(program uint32, name string) int32 {
+	return glctx.GetUniformLocation(gl.Program{Init: true, Value: program}, name).Value
+}
+
+// GLUniform sets a uniform of the current program, values can be float32,
+// int, mgl32.Vec2, mgl32.Vec3, mgl32.Vec4 or mgl32.Mat4.
+func GLUniform(location int32, value interface{}) {
+	uniform := gl.Uniform{Value: location}
+	switch v := value.(type) {
+	case float32:
+		glctx.Uniform1f(uniform, v)
+	case int:
+		glctx.Uniform1i(uniform, v)
+	case mgl32.Vec2:
+		glctx.Uniform2f(uniform, v[0], v[1])
+	case mgl32.Vec3:
+		glctx.Uniform3f(uniform, v[0], v[1], v[2])
+	case mgl32.Vec4:
+		glctx.Uniform4f(uniform, v[0], v[1], v[2], v[3])
+	case mgl32.Mat4:
+		glctx.UniformMatrix4fv(uniform, v[:])
+	}
+}
+
+func GLBindTextureUnit(unit int, texture uint32) {
+	glctx.ActiveTexture(gl.TEXTURE0 + gl.Enum(unit))
+	glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{Value: texture})
+}
+
+// bindMesh sets the attributes of a mesh, there are no VAOs.
+func bindMesh(mesh *Mesh) {
+	glctx.BindBuffer(gl.ARRAY_BUFFER, gl.Buffer{Value: mesh.vbid})
+	glctx.EnableVertexAttribArray(gl.Attrib{Value: 0})
+	glctx.VertexAttribPointer(gl.Attrib{Value: 0}, 2, gl.FLOAT, false, 0, 0)
+	glctx.BindBuffer(gl.ARRAY_BUFFER, gl.Buffer{Value: mesh.uvbid})
+	glctx.EnableVertexAttribArray(gl.Attrib{Value: 1})
+	glctx.VertexAttribPointer(gl.Attrib{Value: 1}, 2, gl.FLOAT, false, 0, 0)
+	if mesh.colorbid != 0 {
+		glctx.BindBuffer(gl.ARRAY_BUFFER, gl.Buffer{Value: mesh.colorbid})
+		glctx.EnableVertexAttribArray(gl.Attrib{Value: 2})
+		glctx.VertexAttribPointer(gl.Attrib{Value: 2}, 4, gl.FLOAT, false, 0, 0)
+	} else {
+		glctx.DisableVertexAttribArray(gl.Attrib{Value: 2})
+	}
+}
+
+// GLDrawLines draws the vertices of a mesh as pairs of segments.
+func GLDrawLines(mesh *Mesh) {
+	bindMesh(mesh)
+	glctx.DrawArrays(gl.LINES, 0, len(mesh.vertices)/2)
+}
+
+// GLDrawMesh draws a mesh with the current program and the texture bound to
+// the first unit. Without VAOs the attributes are set at every draw.
+func GLDrawMesh(mesh *Mesh, texture uint32) {
+	bindMesh(mesh)
+	glctx.ActiveTexture(gl.TEXTURE0)
+	glctx.BindTexture(gl.TEXTURE_2D, gl.Texture{Value: texture})
+	glctx.DrawArrays(gl.TRIANGLES, 0, len(mesh.vertices)/2)
+}
@@ -0,0 +1,647 @@
+package gozmo
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// A ParticleEmitter spawns and simulates many small sprites, drawing all of
+// them with a single dynamic mesh.
+//
+// Particles are emitted continuously ("rate" per second) and in "bursts"
+// during a cycle of "duration" seconds. Their color and size change over
+// their lifetime following linear curves, and with a texture they can play
+// its cells (Rows x Cols) as an animation. In the "world" space particles
+// stay where they were emitted, in the "local" space they follow the
+// GameObject.
+type ParticleEmitter struct {
+	texture     *Texture
+	textureName string
+	mesh        *Mesh
+
+	particles []particle
+	random    *rand.Rand
+
+	emitting     bool
+	time         float32
+	accumulator  float32
+	rate         float32
+	bursts       []ParticleBurst
+	duration     float32
+	loop         bool
+	maxParticles int
+	localSpace   bool
+	// Particles requested through the "emit" attribute.
+	pending int
+
+	// Ranges of the random initial values.
+	lifetime        [2]float32
+	speed           [2]float32
+	angle           [2]float32
+	size            [2]float32
+	rotation        [2]float32
+	angularVelocity [2]float32
+	radius          float32
+
+	gravity mgl32.Vec2
+
+	colorOverLifetime []particleKey
+	sizeOverLifetime  []particleKey
+
+	// Cells of the texture played over the lifetime.
+	sheetFrames [2]uint32
+	sheetCycles float32
+
+	blend    BlendMode
+	hasBlend bool
+}
+
+// A ParticleBurst emits Count particles at Time seconds of each cycle.
+type ParticleBurst struct {
+	Time  float32
+	Count int
+}
+
+type particle struct {
+	position        mgl32.Vec2
+	velocity        mgl32.Vec2
+	age             float32
+	lifetime        float32
+	size            float32
+	rotation        float32
+	angularVelocity float32
+}
+
+// A key of a lifetime curve, time goes from 0 (birth) to 1 (death).
+type particleKey struct {
+	time  float32
+	value mgl32.Vec4
+}
+
+var particleVertexShader = `
+attribute vec2 vertex;
+attribute vec2 uv;
+attribute vec4 color;
+
+uniform mat4 ortho;
+
+varying vec2 uvout;
+varying vec4 colorout;
+
+void main() {
+    gl_Position = ortho * vec4(vertex.xy, 0.0, 1.0);
+    uvout = uv;
+    colorout = color;
+}
+`
+
+var particleFragmentShader = `
+uniform sampler2D tex;
+uniform float textured;
+uniform float premultiply;
+
+varying vec2 uvout;
+varying vec4 colorout;
+
+void main() {
+    vec4 texel = mix(vec4(1.0, 1.0, 1.0, 1.0), texture2D(tex, uvout), textured);
+    gl_FragColor = texel * colorout;
+    gl_FragColor.rgb *= mix(1.0, gl_FragColor.a, premultiply);
+}
+`
+
+// The particles program is shared by all the emitters.
+var particleProgram struct {
+	id          uint32
+	compiled    bool
+	failed      bool
+	ortho       int32
+	textured    int32
+	premultiply int32
+}
+
+func compileParticleProgram() bool {
+	if particleProgram.failed {
+		return false
+	}
+	if particleProgram.compiled {
+		return true
+	}
+	program, err := GLCompileProgram(particleVertexShader, particleFragmentShader)
+	if err != nil {
+		fmt.Println("particles", err)
+		particleProgram.failed = true
+		return false
+	}
+	particleProgram.id = program
+	particleProgram.ortho = GLUniformLocation(program, "ortho")
+	particleProgram.textured = GLUniformLocation(program, "textured")
+	particleProgram.premultiply = GLUniformLocation(program, "premultiply")
+	particleProgram.compiled = true
+	return true
+}
+
+func NewParticleEmitter(texture *Texture) *ParticleEmitter {
+	emitter := ParticleEmitter{texture: texture, emitting: true, loop: true}
+	if texture != nil {
+		emitter.textureName = texture.Name
+	}
+	emitter.rate = 10
+	emitter.duration = 1
+	emitter.maxParticles = 1000
+	emitter.lifetime = [2]float32{1, 1}
+	emitter.speed = [2]float32{1, 1}
+	emitter.angle = [2]float32{0, 360}
+	emitter.size = [2]float32{0.1, 0.1}
+	emitter.sheetCycles = 1
+	emitter.random = rand.New(rand.NewSource(1))
+	return &emitter
+}
+
+func (emitter *ParticleEmitter) Start(gameObject *GameObject) {
+}
+
+// Destroy frees the mesh of the particles.
+func (emitter *ParticleEmitter) Destroy(gameObject *GameObject) {
+	if emitter.mesh != nil {
+		emitter.mesh.destroy()
+		emitter.mesh = nil
+	}
+}
+
+func (emitter *ParticleEmitter) Update(gameObject *GameObject) {
+	if emitter.textureName != "" {
+		emitter.texture, _ = gameObject.Scene.textures[emitter.textureName]
+	}
+
+	deltaTime := gameObject.DeltaTime
+
+	if emitter.pending > 0 {
+		emitter.Emit(gameObject, emitter.pending)
+		emitter.pending = 0
+	}
+
+	if emitter.emitting {
+		emitter.emitCycle(gameObject, deltaTime)
+	}
+
+	// Simulate and remove the dead particles.
+	alive := emitter.particles[:0]
+	for _, p := range emitter.particles {
+		p.age += deltaTime
+		if p.age >= p.lifetime {
+			continue
+		}
+		p.velocity = p.velocity.Add(emitter.gravity.Mul(deltaTime))
+		p.position = p.position.Add(p.velocity.Mul(deltaTime))
+		p.rotation += p.angularVelocity * deltaTime
+		alive = append(alive, p)
+	}
+	emitter.particles = alive
+}
+
+// emitCycle advances the emission cycle, spawning the particles of the rate
+// and of the bursts in the elapsed time.
+func (emitter *ParticleEmitter) emitCycle(gameObject *GameObject, deltaTime float32) {
+	previous := emitter.time
+	emitter.time += deltaTime
+
+	emitter.accumulator += emitter.rate * deltaTime
+	count := int(emitter.accumulator)
+	emitter.accumulator -= float32(count)
+
+	for _, burst := range emitter.bursts {
+		if burst.Time >= previous && burst.Time < emitter.time {
+			count += burst.Count
+		}
+	}
+
+	emitter.Emit(gameObject, count)
+
+	if emitter.duration > 0 && emitter.time >= emitter.duration {
+		if emitter.loop {
+			emitter.time -= emitter.duration
+			// Bursts at the start of the next cycle.
+			for _, burst := range emitter.bursts {
+				if burst.Time < emitter.time {
+					emitter.Emit(gameObject, burst.Count)
+				}
+			}
+		} else {
+			emitter.emitting = false
+		}
+	}
+}
+
+func (emitter *ParticleEmitter) randomRange(values [2]float32) float32 {
+	return values[0] + (values[1]-values[0])*emitter.random.Float32()
+}
+
+// Emit spawns particles immediately, up to the maximum.
+func (emitter *ParticleEmitter) Emit(gameObject *GameObject, count int) {
+	for i := 0; i < count && len(emitter.particles) < emitter.maxParticles; i++ {
+		p := particle{}
+		p.lifetime = emitter.randomRange(emitter.lifetime)
+		p.size = emitter.randomRange(emitter.size)
+		p.rotation = emitter.randomRange(emitter.rotation) * math.Pi / 180
+		p.angularVelocity = emitter.randomRange(emitter.angularVelocity) * math.Pi / 180
+
+		angle := float64(emitter.randomRange(emitter.angle) * math.Pi / 180)
+		direction := mgl32.Vec2{float32(math.Cos(angle)), float32(math.Sin(angle))}
+
+		if emitter.radius > 0 {
+			distance := emitter.radius * float32(math.Sqrt(float64(emitter.random.Float32())))
+			p.position = direction.Mul(distance)
+		}
+
+		p.velocity = direction.Mul(emitter.randomRange(emitter.speed))
+
+		// World particles start from the current transform of the emitter.
+		if !emitter.localSpace {
+			rotation := mgl32.Rotate2D(gameObject.Rotation)
+			p.position = rotation.Mul2x1(mgl32.Vec2{p.position[0] * gameObject.Scale[0], p.position[1] * gameObject.Scale[1]}).Add(gameObject.Position)
+			p.velocity = rotation.Mul2x1(p.velocity)
+		}
+
+		emitter.particles = append(emitter.particles, p)
+	}
+}
+
+func (emitter *ParticleEmitter) Play() {
+	emitter.emitting = true
+	emitter.time = 0
+	emitter.accumulator = 0
+}
+
+// Stop ends the emission, the living particles complete their lifetime.
+func (emitter *ParticleEmitter) Stop() {
+	emitter.emitting = false
+}
+
+func (emitter *ParticleEmitter) Clear() {
+	emitter.particles = emitter.particles[:0]
+}
+
+func (emitter *ParticleEmitter) ParticleCount() int {
+	return len(emitter.particles)
+}
+
+func (emitter *ParticleEmitter) AddBurst(time float32, count int) {
+	emitter.bursts = append(emitter.bursts, ParticleBurst{Time: time, Count: count})
+}
+
+// evaluateCurve interpolates the keys linearly, without keys it returns
+// the fallback.
+func evaluateCurve(keys []particleKey, time float32, fallback mgl32.Vec4) mgl32.Vec4 {
+	if len(keys) == 0 {
+		return fallback
+	}
+	if time <= keys[0].time {
+		return keys[0].value
+	}
+	for i := 1; i < len(keys); i++ {
+		if time <= keys[i].time {
+			previous := keys[i-1]
+			t := (time - previous.time) / (keys[i].time - previous.time)
+			return previous.value.Add(keys[i].value.Sub(previous.value).Mul(t))
+		}
+	}
+	return keys[len(keys)-1].value
+}
+
+// sheetRange clamps the sheetFrames to the cells of the texture, a last frame
+// of 0 meaning the last cell.
+func (emitter *ParticleEmitter) sheetRange(cells uint32) (uint32, uint32) {
+	if cells == 0 {
+		return 0, 0
+	}
+	first, last := emitter.sheetFrames[0], emitter.sheetFrames[1]
+	if first >= cells {
+		first = cells - 1
+	}
+	if last == 0 || last >= cells {
+		last = cells - 1
+	}
+	if last < first {
+		last = first
+	}
+	return first, last
+}
+
+// buildMesh fills the vertices, uvs and colors of the living particles.
+func (emitter *ParticleEmitter) buildMesh() {
+	mesh := emitter.mesh
+	mesh.vertices = mesh.vertices[:0]
+	mesh.uvs = mesh.uvs[:0]
+	mesh.colors = mesh.colors[:0]
+
+	texture := emitter.texture
+	uv := [4]float32{0, 0, 1, 1}
+
+	for _, p := range emitter.particles {
+		life := p.age / p.lifetime
+
+		color := evaluateCurve(emitter.colorOverLifetime, life, mgl32.Vec4{1, 1, 1, 1})
+		size := p.size * evaluateCurve(emitter.sizeOverLifetime, life, mgl32.Vec4{1, 1, 1, 1})[0] / 2
+
+		if texture != nil {
+			first, last := emitter.sheetRange(texture.Rows * texture.Cols)
+			frames := last - first + 1
+			index := first + uint32(life*emitter.sheetCycles*float32(frames))%frames
+			uv[2] = 1 / float32(texture.Cols)
+			uv[3] = 1 / float32(texture.Rows)
+			uv[0] = uv[2] * float32(index%texture.Cols)
+			uv[1] = uv[3] * float32(index/texture.Cols)
+		}
+
+		rotation := mgl32.Rotate2D(p.rotation)
+		corner := func(x, y float32) mgl32.Vec2 {
+			return rotation.Mul2x1(mgl32.Vec2{x * size, y * size}).Add(p.position)
+		}
+		bottomLeft := corner(-1, -1)
+		topLeft := corner(-1, 1)
+		bottomRight := corner(1, -1)
+		topRight := corner(1, 1)
+
+		mesh.vertices = append(mesh.vertices, bottomLeft[0], bottomLeft[1],
+			topLeft[0], topLeft[1],
+			bottomRight[0], bottomRight[1],
+			bottomRight[0], bottomRight[1],
+			topRight[0], topRight[1],
+			topLeft[0], topLeft[1])
+
+		u0, v0, u1, v1 := uv[0], uv[1], uv[0]+uv[2], uv[1]+uv[3]
+		mesh.uvs = append(mesh.uvs, u0, v1,
+			u0, v0,
+			u1, v1,
+			u1, v1,
+			u1, v0,
+			u0, v0)
+
+		for i := 0; i < 6; i++ {
+			mesh.colors = append(mesh.colors, color[0], color[1], color[2], color[3])
+		}
+	}
+}
+
+func (emitter *ParticleEmitter) Draw(gameObject *GameObject, camera *Camera) {
+	if len(emitter.particles) == 0 || !compileParticleProgram() {
+		return
+	}
+
+	if emitter.mesh == nil {
+		emitter.mesh = newDynamicMesh()
+	}
+
+	emitter.buildMesh()
+	mesh := emitter.mesh
+	mesh.uploadDynamic()
+
+	ortho := camera.Projection.Mul4(camera.View)
+	if emitter.localSpace {
+		model := mgl32.Translate3D(gameObject.Position[0], gameObject.Position[1], 0)
+		model = model.Mul4(mgl32.Scale3D(gameObject.Scale[0], gameObject.Scale[1], 1))
+		model = model.Mul4(mgl32.HomogRotate3DZ(gameObject.Rotation))
+		ortho = ortho.Mul4(model)
+	}
+
+	IncPerFrameStats("GL.DrawCalls", 1)
+
+	mode := emitter.BlendMode()
+	setBlendMode(mode)
+
+	GLUseProgram(particleProgram.id)
+	GLUniform(particleProgram.ortho, ortho)
+	var tid uint32
+	var textured float32
+	if emitter.texture != nil {
+		tid = emitter.texture.tid
+		textured = 1
+	}
+	GLUniform(particleProgram.textured, textured)
+	premultiply := premultiplyFor(mode, emitter.texture)
+	GLUniform(particleProgram.premultiply, premultiply)
+	GLDrawMesh(mesh, tid)
+	// The program is shared with the overlays.
+	if premultiply != 0 {
+		GLUniform(particleProgram.premultiply, float32(0))
+	}
+}
+
+// BlendMode defaults to premultiplied for premultiplied textures.
+func (emitter *ParticleEmitter) BlendMode() BlendMode {
+	if emitter.hasBlend {
+		return emitter.blend
+	}
+	if emitter.texture != nil && emitter.texture.Options.Premultiplied {
+		return BlendPremultiplied
+	}
+	return BlendAlpha
+}
+
+func (emitter *ParticleEmitter) SetBlendMode(mode BlendMode) {
+	emitter.blend = mode
+	emitter.hasBlend = true
+}
+
+// A range is a number or a list of two numbers (min and max).
+func castRange(value interface{}) ([2]float32, error) {
+	number, err := CastFloat32(value)
+	if err == nil {
+		return [2]float32{number, number}, nil
+	}
+	numbers, err := castFloat32List(value, 2)
+	if err != nil {
+		return [2]float32{}, fmt.Errorf("expects a number or a list of 2 numbers")
+	}
+	return [2]float32{numbers[0], numbers[1]}, nil
+}
+
+// A curve is a list of keys [time, values...], sorted by time.
+func castCurve(value interface{}, size int) ([]particleKey, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expects a list of keys")
+	}
+	var keys []particleKey
+	for _, item := range items {
+		numbers, err := castFloat32List(item, size+1)
+		if err != nil {
+			return nil, fmt.Errorf("key %v", err)
+		}
+		key := particleKey{time: numbers[0]}
+		copy(key.value[:], numbers[1:])
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].time < keys[j].time })
+	return keys, nil
+}
+
+func castBursts(value interface{}) ([]ParticleBurst, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expects a list of [time, count] bursts")
+	}
+	var bursts []ParticleBurst
+	for _, item := range items {
+		numbers, err := castFloat32List(item, 2)
+		if err != nil {
+			return nil, fmt.Errorf("burst %v", err)
+		}
+		bursts = append(bursts, ParticleBurst{Time: numbers[0], Count: int(numbers[1])})
+	}
+	return bursts, nil
+}
+
+func (emitter *ParticleEmitter) SetAttr(attr string, value interface{}) error {
+	var err error
+	switch attr {
+	case "texture":
+		textureName, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, emitter)
+		}
+		emitter.textureName = textureName
+		emitter.texture = nil
+		return nil
+	case "emitting":
+		flag, err := CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T expects a bool", attr, emitter)
+		}
+		if flag && !emitter.emitting {
+			emitter.Play()
+		}
+		emitter.emitting = flag
+		return nil
+	case "rate":
+		emitter.rate, err = CastFloat32(value)
+	case "bursts":
+		emitter.bursts, err = castBursts(value)
+	case "duration":
+		emitter.duration, err = CastFloat32(value)
+	case "loop":
+		emitter.loop, err = CastBool(value)
+	case "maxParticles":
+		emitter.maxParticles, err = CastInt(value)
+	case "space":
+		switch value {
+		case "world":
+			emitter.localSpace = false
+		case "local":
+			emitter.localSpace = true
+		default:
+			return fmt.Errorf("%v attribute of %T expects world or local", attr, emitter)
+		}
+		return nil
+	case "lifetime":
+		emitter.lifetime, err = castRange(value)
+	case "speed":
+		emitter.speed, err = castRange(value)
+	case "angle":
+		emitter.angle, err = castRange(value)
+	case "size":
+		emitter.size, err = castRange(value)
+	case "rotation":
+		emitter.rotation, err = castRange(value)
+	case "angularVelocity":
+		emitter.angularVelocity, err = castRange(value)
+	case "radius":
+		emitter.radius, err = CastFloat32(value)
+	case "gravity":
+		var gravity []float32
+		gravity, err = castFloat32List(value, 2)
+		if err == nil {
+			emitter.gravity = mgl32.Vec2{gravity[0], gravity[1]}
+		}
+	case "colorOverLifetime":
+		emitter.colorOverLifetime, err = castCurve(value, 4)
+	case "sizeOverLifetime":
+		emitter.sizeOverLifetime, err = castCurve(value, 1)
+	case "sheetFrames":
+		var frames []float32
+		frames, err = castFloat32List(value, 2)
+		if err == nil {
+			emitter.sheetFrames = [2]uint32{uint32(frames[0]), uint32(frames[1])}
+		}
+	case "sheetCycles":
+		emitter.sheetCycles, err = CastFloat32(value)
+	case "blend":
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, emitter)
+		}
+		var mode BlendMode
+		mode, err = ParseBlendMode(name)
+		if err == nil {
+			emitter.SetBlendMode(mode)
+		}
+	case "seed":
+		var seed int
+		seed, err = CastInt(value)
+		if err == nil {
+			emitter.random = rand.New(rand.NewSource(int64(seed)))
+		}
+	case "emit":
+		// Emitting needs the GameObject, particles are spawned at the next
+		// update.
+		var count int
+		count, err = CastInt(value)
+		if err == nil {
+			emitter.pending += count
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, emitter, err)
+	}
+	return nil
+}
+
+func (emitter *ParticleEmitter) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "texture":
+		return emitter.textureName, nil
+	case "emitting":
+		return emitter.emitting, nil
+	case "rate":
+		return emitter.rate, nil
+	case "duration":
+		return emitter.duration, nil
+	case "loop":
+		return emitter.loop, nil
+	case "maxParticles":
+		return emitter.maxParticles, nil
+	case "particles":
+		return len(emitter.particles), nil
+	case "space":
+		if emitter.localSpace {
+			return "local", nil
+		}
+		return "world", nil
+	case "gravity":
+		return emitter.gravity, nil
+	case "radius":
+		return emitter.radius, nil
+	case "sheetCycles":
+		return emitter.sheetCycles, nil
+	case "blend":
+		return emitter.BlendMode().String(), nil
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, emitter)
+}
+
+func (emitter *ParticleEmitter) GetType() string {
+	return "ParticleEmitter"
+}
+
+func initParticleEmitter(args []interface{}) Component {
+	return NewParticleEmitter(nil)
+}
+
+func init() {
+	RegisterComponent("ParticleEmitter", initParticleEmitter)
+}
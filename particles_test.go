@@ -0,0 +1,95 @@
+package gozmo
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestParticleEmitterBursts(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Explosion")
+	emitter := NewParticleEmitter(nil)
+	emitter.SetAttr("rate", 0)
+	emitter.SetAttr("lifetime", 0.5)
+	emitter.SetAttr("bursts", []interface{}{[]interface{}{0, 20}})
+	emitter.SetAttr("loop", false)
+	gameObject.DeltaTime = 0.1
+
+	emitter.Update(gameObject)
+	if emitter.ParticleCount() != 20 {
+		t.Fatal("Expected 20 particles, got", emitter.ParticleCount())
+	}
+
+	for i := 0; i < 10; i++ {
+		emitter.Update(gameObject)
+	}
+	if emitter.ParticleCount() != 0 || emitter.emitting {
+		t.Error("Expected the emission to end, got", emitter.ParticleCount(), emitter.emitting)
+	}
+}
+
+func TestParticleEmitterWorldSpace(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Dust")
+	gameObject.Position = mgl32.Vec2{5, 0}
+	emitter := NewParticleEmitter(nil)
+	emitter.SetAttr("speed", 0)
+	emitter.SetAttr("gravity", []interface{}{0, -10})
+	emitter.Emit(gameObject, 1)
+
+	// Moving the emitter does not move the emitted particles.
+	gameObject.Position = mgl32.Vec2{0, 0}
+	gameObject.DeltaTime = 0.5
+	emitter.SetAttr("emitting", false)
+	emitter.Update(gameObject)
+	position := emitter.particles[0].position
+	if position[0] != 5 || position[1] != -2.5 {
+		t.Error("Expected 5 -2.5, got", position)
+	}
+}
+
+func TestParticleSheetFrames(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Sparks")
+	emitter := NewParticleEmitter(&Texture{Rows: 2, Cols: 2})
+	gameObject.AddComponent("sparks", emitter)
+	// Without uploading it.
+	emitter.mesh = &Mesh{}
+	emitter.Emit(gameObject, 1)
+
+	ranges := [][2]interface{}{{4, 0}, {3, 1}, {1, 9}}
+	expected := [][2]uint32{{3, 3}, {3, 3}, {1, 3}}
+	for i, frames := range ranges {
+		err := emitter.SetAttr("sheetFrames", []interface{}{frames[0], frames[1]})
+		if err != nil {
+			t.Fatal(err)
+		}
+		first, last := emitter.sheetRange(4)
+		if first != expected[i][0] || last != expected[i][1] {
+			t.Error("Expected", expected[i], "got", first, last)
+		}
+		emitter.buildMesh()
+		for _, uv := range emitter.mesh.uvs {
+			if uv < 0 || uv > 1 {
+				t.Fatal("Expected the uvs in the texture, got", emitter.mesh.uvs)
+			}
+		}
+	}
+
+	gameObject.Destroy()
+	if emitter.mesh != nil {
+		t.Error("Expected the mesh to be freed")
+	}
+}
+
+func TestEvaluateCurve(t *testing.T) {
+	keys, err := castCurve([]interface{}{[]interface{}{1, 0}, []interface{}{0, 2}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := evaluateCurve(keys, 0.25, mgl32.Vec4{})
+	if value[0] != 1.5 {
+		t.Error("Expected 1.5, got", value[0])
+	}
+}
@@ -0,0 +1,97 @@
+package gozmo
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Pointers (the mouse cursor, touches) raise events on the GameObjects whose
+// HitBox contains them: "<prefix>Enter" and "<prefix>Exit" when hovering
+// starts and ends, "<prefix>Down" and "<prefix>Up" for presses. The Data of
+// these events is a *PointerEvent.
+
+type PointerEvent struct {
+	// The mouse button (-1 when hovering), or the finger of a touch.
+	Id int
+	// World coordinates.
+	Position mgl32.Vec2
+}
+
+// A pointer tracks the HitBoxes it is hovering, id is used for the events
+// not related to a button.
+type pointer struct {
+	prefix  string
+	id      int
+	hovered []*HitBox
+}
+
+func containsHitBox(hitboxes []*HitBox, hitbox *HitBox) bool {
+	for _, hbox := range hitboxes {
+		if hbox == hitbox {
+			return true
+		}
+	}
+	return false
+}
+
+// Only HitBoxes of enabled GameObjects in the loaded scenes are considered.
+func isPointerTarget(hitbox *HitBox) bool {
+	if hitbox.gameObject == nil || !hitbox.gameObject.enabled {
+		return false
+	}
+	if Engine.Window != nil && !sceneManager.IsLoaded(hitbox.gameObject.Scene) {
+		return false
+	}
+	return true
+}
+
+// move recomputes the hovered HitBoxes, raising Enter and Exit events.
+func (p *pointer) move(position mgl32.Vec2) {
+	var hovered []*HitBox
+	for _, hitbox := range hitBoxes {
+		if isPointerTarget(hitbox) && hitbox.Contains(position[0], position[1]) {
+			hovered = append(hovered, hitbox)
+		}
+	}
+
+	for _, hitbox := range p.hovered {
+		if !containsHitBox(hovered, hitbox) {
+			p.raise(hitbox, "Exit", p.id, position)
+		}
+	}
+	for _, hitbox := range hovered {
+		if !containsHitBox(p.hovered, hitbox) {
+			p.raise(hitbox, "Enter", p.id, position)
+		}
+	}
+
+	p.hovered = hovered
+}
+
+// leave raises Exit events on all of the hovered HitBoxes.
+func (p *pointer) leave(position mgl32.Vec2) {
+	for _, hitbox := range p.hovered {
+		p.raise(hitbox, "Exit", p.id, position)
+	}
+	p.hovered = nil
+}
+
+func (p *pointer) press(id int, position mgl32.Vec2) {
+	for _, hitbox := range p.hovered {
+		p.raise(hitbox, "Down", id, position)
+	}
+}
+
+func (p *pointer) release(id int, position mgl32.Vec2) {
+	for _, hitbox := range p.hovered {
+		p.raise(hitbox, "Up", id, position)
+	}
+}
+
+func (p *pointer) raise(hitbox *HitBox, what string, id int, position mgl32.Vec2) {
+	// The HitBox may have been destroyed in the meantime.
+	if hitbox.gameObject == nil {
+		return
+	}
+	event := PointerEvent{Id: id, Position: position}
+	hitbox.gameObject.EnqueueEventWithData(nil, p.prefix+what, &event)
+}
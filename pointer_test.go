@@ -0,0 +1,39 @@
+package gozmo
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestPointerEvents(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	hitbox := NewHitBox(0, 0, 2, 2)
+	gameObject.AddComponent("hitbox", hitbox)
+	defer gameObject.Destroy()
+
+	p := pointer{prefix: "mouse", id: -1}
+
+	p.move(mgl32.Vec2{5, 5})
+	if len(gameObject.events) != 0 {
+		t.Error("Expected 0, got", len(gameObject.events))
+	}
+
+	p.move(mgl32.Vec2{0.5, -0.5})
+	p.press(0, mgl32.Vec2{0.5, -0.5})
+	p.move(mgl32.Vec2{5, 5})
+
+	expected := []string{"mouseEnter", "mouseDown", "mouseExit"}
+	if len(gameObject.events) != len(expected) {
+		t.Fatal("Expected", len(expected), "got", len(gameObject.events))
+	}
+	for i, msg := range expected {
+		if gameObject.events[i].Msg != msg {
+			t.Error("Expected", msg, "got", gameObject.events[i].Msg)
+		}
+	}
+	if gameObject.events[1].Data.(*PointerEvent).Id != 0 {
+		t.Error("Expected 0, got", gameObject.events[1].Data.(*PointerEvent).Id)
+	}
+}
@@ -0,0 +1,529 @@
+package gozmo
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The PostProcess component processes the final image of its scene on the
+// window, with a stack of full-screen effects applied in order. Effects are
+// fragment shaders (GLSL ES 1.0) reading the image from the "tex" sampler at
+// the "uvout" coordinates, they also get the "resolution" of the image in
+// pixels and the "time" in seconds.
+//
+// The image can have a fixed (low) resolution, upscaled to the window keeping
+// its aspect ratio. With "pixelPerfect" the scale is an integer and pixels are
+// not filtered.
+
+type postEffectSource struct {
+	fragment string
+	defaults map[string]interface{}
+}
+
+var postEffects map[string]*postEffectSource
+
+// RegisterPostEffect makes an effect available by name, defaults are the
+// initial values of its uniforms.
+func RegisterPostEffect(name string, fragmentSource string, defaults map[string]interface{}) {
+	// Create the map if required.
+	if postEffects == nil {
+		postEffects = make(map[string]*postEffectSource)
+	}
+	postEffects[name] = &postEffectSource{fragment: fragmentSource, defaults: defaults}
+}
+
+var postVertexShader = `
+attribute vec2 vertex;
+attribute vec2 uv;
+
+varying vec2 uvout;
+
+void main() {
+    gl_Position = vec4(vertex, 0.0, 1.0);
+    uvout = uv;
+}
+`
+
+type PostEffect struct {
+	Name     string
+	source   *postEffectSource
+	uniforms map[string]interface{}
+
+	// The program is compiled at the first use.
+	program   uint32
+	compiled  bool
+	failed    bool
+	locations map[string]int32
+}
+
+func NewPostEffect(name string) (*PostEffect, error) {
+	source, ok := postEffects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown post effect %v", name)
+	}
+	effect := PostEffect{Name: name, source: source, uniforms: make(map[string]interface{})}
+	for uniform, value := range source.defaults {
+		err := effect.SetUniform(uniform, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &effect, nil
+}
+
+// castUniform converts numbers to float32 and lists of 2 to 4 numbers to
+// vectors, matrices are accepted as they are.
+func castUniform(value interface{}) (interface{}, error) {
+	switch value.(type) {
+	case mgl32.Vec2, mgl32.Vec3, mgl32.Vec4, mgl32.Mat4:
+		return value, nil
+	case []interface{}:
+		items := value.([]interface{})
+		if len(items) < 2 || len(items) > 4 {
+			return nil, fmt.Errorf("expects a list of 2 to 4 numbers")
+		}
+		numbers, err := castFloat32List(value, len(items))
+		if err != nil {
+			return nil, err
+		}
+		switch len(numbers) {
+		case 2:
+			return mgl32.Vec2{numbers[0], numbers[1]}, nil
+		case 3:
+			return mgl32.Vec3{numbers[0], numbers[1], numbers[2]}, nil
+		}
+		return mgl32.Vec4{numbers[0], numbers[1], numbers[2], numbers[3]}, nil
+	}
+	return CastFloat32(value)
+}
+
+func (effect *PostEffect) SetUniform(name string, value interface{}) error {
+	uniform, err := castUniform(value)
+	if err != nil {
+		return fmt.Errorf("uniform %v of %v %v", name, effect.Name, err)
+	}
+	effect.uniforms[name] = uniform
+	return nil
+}
+
+func (effect *PostEffect) GetUniform(name string) interface{} {
+	return effect.uniforms[name]
+}
+
+func (effect *PostEffect) location(name string) int32 {
+	location, ok := effect.locations[name]
+	if !ok {
+		location = GLUniformLocation(effect.program, name)
+		effect.locations[name] = location
+	}
+	return location
+}
+
+// use selects the effect program, returning false if it cannot be compiled.
+func (effect *PostEffect) use(input *Texture, time float32) bool {
+	if effect.failed {
+		return false
+	}
+	if !effect.compiled {
+		program, err := GLCompileProgram(postVertexShader, effect.source.fragment)
+		if err != nil {
+			fmt.Println(effect.Name, err)
+			effect.failed = true
+			return false
+		}
+		effect.program = program
+		effect.locations = make(map[string]int32)
+		effect.compiled = true
+	}
+
+	GLUseProgram(effect.program)
+	for name, value := range effect.uniforms {
+		GLUniform(effect.location(name), value)
+	}
+	GLUniform(effect.location("resolution"), mgl32.Vec2{float32(input.Width), float32(input.Height)})
+	GLUniform(effect.location("time"), time)
+	return true
+}
+
+type PostProcess struct {
+	gameObject *GameObject
+	effects    []*PostEffect
+
+	// A fixed resolution of the image, 0 follows the window.
+	width        uint32
+	height       uint32
+	pixelPerfect bool
+	time         float32
+
+	image   *Texture
+	buffers [2]*Texture
+	mesh    *Mesh
+	copy    *PostEffect
+}
+
+func (postProcess *PostProcess) Start(gameObject *GameObject) {
+	postProcess.gameObject = gameObject
+	gameObject.Scene.postProcess = postProcess
+}
+
+func (postProcess *PostProcess) Update(gameObject *GameObject) {
+	postProcess.time += gameObject.DeltaTime
+}
+
+func (postProcess *PostProcess) Destroy(gameObject *GameObject) {
+	if gameObject.Scene.postProcess == postProcess {
+		gameObject.Scene.postProcess = nil
+	}
+	postProcess.releaseTargets()
+}
+
+func (postProcess *PostProcess) releaseTargets() {
+	for _, target := range []*Texture{postProcess.image, postProcess.buffers[0], postProcess.buffers[1]} {
+		if target != nil {
+			target.Destroy()
+		}
+	}
+	postProcess.image = nil
+	postProcess.buffers = [2]*Texture{}
+}
+
+// AddEffect appends an effect to the stack.
+func (postProcess *PostProcess) AddEffect(name string) (*PostEffect, error) {
+	effect, err := NewPostEffect(name)
+	if err != nil {
+		return nil, err
+	}
+	postProcess.effects = append(postProcess.effects, effect)
+	return effect, nil
+}
+
+// GetEffect returns the first effect with the name, or nil.
+func (postProcess *PostProcess) GetEffect(name string) *PostEffect {
+	for _, effect := range postProcess.effects {
+		if effect.Name == name {
+			return effect
+		}
+	}
+	return nil
+}
+
+func (postProcess *PostProcess) SetResolution(width, height uint32) {
+	postProcess.width = width
+	postProcess.height = height
+}
+
+func (postProcess *PostProcess) aspectRatio() (float32, bool) {
+	if postProcess.width == 0 || postProcess.height == 0 {
+		return 0, false
+	}
+	return float32(postProcess.width) / float32(postProcess.height), true
+}
+
+func (postProcess *PostProcess) imageSize() (uint32, uint32) {
+	if postProcess.width > 0 && postProcess.height > 0 {
+		return postProcess.width, postProcess.height
+	}
+	return uint32(Engine.Window.framebufferWidth), uint32(Engine.Window.framebufferHeight)
+}
+
+// windowRect is the area of the window showing the image.
+func (postProcess *PostProcess) windowRect() (int32, int32, int32, int32) {
+	windowWidth := Engine.Window.framebufferWidth
+	windowHeight := Engine.Window.framebufferHeight
+	if postProcess.width == 0 || postProcess.height == 0 {
+		return 0, 0, windowWidth, windowHeight
+	}
+
+	scale := math.Min(float64(windowWidth)/float64(postProcess.width), float64(windowHeight)/float64(postProcess.height))
+	if postProcess.pixelPerfect && scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	width := int32(float64(postProcess.width) * scale)
+	height := int32(float64(postProcess.height) * scale)
+	return (windowWidth - width) / 2, (windowHeight - height) / 2, width, height
+}
+
+// windowToImage converts normalized window coordinates into normalized image
+// ones.
+func (postProcess *PostProcess) windowToImage(nx, ny float32) (float32, float32) {
+	x, y, width, height := postProcess.windowRect()
+	px := nx * float32(Engine.Window.framebufferWidth)
+	py := ny * float32(Engine.Window.framebufferHeight)
+	return (px - float32(x)) / float32(width), (py - float32(y)) / float32(height)
+}
+
+// resize (re)creates a render target when its size changes.
+func (postProcess *PostProcess) resize(target *Texture, width, height uint32) (*Texture, error) {
+	if target != nil && target.Width == width && target.Height == height {
+		return target, nil
+	}
+	if target != nil {
+		target.Destroy()
+	}
+	target, err := newRenderTarget("", width, height)
+	if err != nil {
+		return nil, err
+	}
+	if postProcess.pixelPerfect {
+		target.SetFilter(TextureNearest)
+	}
+	return target, nil
+}
+
+// begin returns the cleared image the cameras draw into, nil if it cannot be
+// created.
+func (postProcess *PostProcess) begin() *Texture {
+	width, height := postProcess.imageSize()
+	image, err := postProcess.resize(postProcess.image, width, height)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+	postProcess.image = image
+
+	bindRenderTarget(image)
+	GLClearRect(0, 0, int32(width), int32(height), mgl32.Vec4{0, 0, 0, 1})
+	return image
+}
+
+// end applies the effects to the image, the last one draws on the window.
+func (postProcess *PostProcess) end(image *Texture) {
+	if image == nil {
+		return
+	}
+	if postProcess.mesh == nil {
+		postProcess.mesh = newScreenQuadMesh()
+	}
+
+	var effects []*PostEffect
+	for _, effect := range postProcess.effects {
+		if !effect.failed {
+			effects = append(effects, effect)
+		}
+	}
+	if len(effects) == 0 {
+		if postProcess.copy == nil {
+			postProcess.copy, _ = NewPostEffect("copy")
+		}
+		effects = append(effects, postProcess.copy)
+	}
+
+	setBlendMode(BlendAlpha)
+	input := image
+	for i, effect := range effects {
+		var output *Texture
+		if i < len(effects)-1 {
+			buffer, err := postProcess.resize(postProcess.buffers[i%2], image.Width, image.Height)
+			if err != nil {
+				fmt.Println(err)
+				return
+			}
+			postProcess.buffers[i%2] = buffer
+			output = buffer
+		}
+
+		bindRenderTarget(output)
+		if output == nil {
+			GLViewport(postProcess.windowRect())
+		} else {
+			GLViewport(0, 0, int32(output.Width), int32(output.Height))
+		}
+
+		if effect.use(input, postProcess.time) {
+			GLDrawMesh(postProcess.mesh, input.tid)
+			input = output
+		}
+	}
+}
+
+func (postProcess *PostProcess) setEffects(value interface{}) error {
+	items, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("expects a list of effect names")
+	}
+	var effects []*PostEffect
+	for _, item := range items {
+		name, ok := item.(string)
+		if !ok {
+			return fmt.Errorf("expects a list of effect names")
+		}
+		effect, err := NewPostEffect(name)
+		if err != nil {
+			return err
+		}
+		effects = append(effects, effect)
+	}
+	postProcess.effects = effects
+	return nil
+}
+
+// Attributes are "effects" (a list of names), "width", "height",
+// "pixelPerfect" and the uniforms of the effects, as "effect.uniform".
+func (postProcess *PostProcess) SetAttr(attr string, value interface{}) error {
+	switch attr {
+	case "effects":
+		err := postProcess.setEffects(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, postProcess, err)
+		}
+		return nil
+	case "width", "height":
+		size, err := CastInt(value)
+		if err != nil || size < 0 {
+			return fmt.Errorf("%v attribute of %T expects a positive number", attr, postProcess)
+		}
+		if attr == "width" {
+			postProcess.width = uint32(size)
+		} else {
+			postProcess.height = uint32(size)
+		}
+		return nil
+	case "pixelPerfect":
+		flag, err := CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, postProcess, err)
+		}
+		postProcess.pixelPerfect = flag
+		// Render targets are filtered at creation.
+		postProcess.releaseTargets()
+		return nil
+	}
+
+	dot := strings.Index(attr, ".")
+	if dot > 0 {
+		effect := postProcess.GetEffect(attr[:dot])
+		if effect != nil {
+			return effect.SetUniform(attr[dot+1:], value)
+		}
+	}
+	return fmt.Errorf("%v attribute of %T not found", attr, postProcess)
+}
+
+func (postProcess *PostProcess) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "effects":
+		var names []string
+		for _, effect := range postProcess.effects {
+			names = append(names, effect.Name)
+		}
+		return names, nil
+	case "width":
+		return postProcess.width, nil
+	case "height":
+		return postProcess.height, nil
+	case "pixelPerfect":
+		return postProcess.pixelPerfect, nil
+	}
+
+	dot := strings.Index(attr, ".")
+	if dot > 0 {
+		effect := postProcess.GetEffect(attr[:dot])
+		if effect != nil {
+			value, ok := effect.uniforms[attr[dot+1:]]
+			if ok {
+				return value, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, postProcess)
+}
+
+func (postProcess *PostProcess) GetType() string {
+	return "PostProcess"
+}
+
+func NewPostProcess() *PostProcess {
+	postProcess := PostProcess{}
+	return &postProcess
+}
+
+// Arguments are the names of the effects.
+func initPostProcess(args []interface{}) Component {
+	postProcess := NewPostProcess()
+	err := postProcess.setEffects(args)
+	if err != nil {
+		panic(err)
+	}
+	return postProcess
+}
+
+var copyEffect = `
+uniform sampler2D tex;
+varying vec2 uvout;
+
+void main() {
+    gl_FragColor = texture2D(tex, uvout);
+}
+`
+
+var colorGradingEffect = `
+uniform sampler2D tex;
+uniform float brightness;
+uniform float contrast;
+uniform float saturation;
+uniform vec4 tint;
+varying vec2 uvout;
+
+void main() {
+    vec4 color = texture2D(tex, uvout);
+    vec3 rgb = color.rgb + brightness;
+    rgb = (rgb - 0.5) * contrast + 0.5;
+    float luma = dot(rgb, vec3(0.299, 0.587, 0.114));
+    rgb = mix(vec3(luma), rgb, saturation);
+    gl_FragColor = vec4(rgb, color.a) * tint;
+}
+`
+
+var vignetteEffect = `
+uniform sampler2D tex;
+uniform float intensity;
+uniform float radius;
+uniform float softness;
+varying vec2 uvout;
+
+void main() {
+    vec4 color = texture2D(tex, uvout);
+    float vignette = smoothstep(radius, radius - softness, length(uvout - 0.5));
+    color.rgb *= mix(1.0, vignette, intensity);
+    gl_FragColor = color;
+}
+`
+
+var crtEffect = `
+uniform sampler2D tex;
+uniform vec2 resolution;
+uniform float scanlines;
+uniform float curvature;
+varying vec2 uvout;
+
+void main() {
+    vec2 uv = uvout * 2.0 - 1.0;
+    uv *= 1.0 + curvature * dot(uv.yx, uv.yx);
+    uv = uv * 0.5 + 0.5;
+    if (uv.x < 0.0 || uv.x > 1.0 || uv.y < 0.0 || uv.y > 1.0) {
+        gl_FragColor = vec4(0.0, 0.0, 0.0, 1.0);
+        return;
+    }
+    vec4 color = texture2D(tex, uv);
+    float line = sin(uv.y * resolution.y * 3.14159) * 0.5 + 0.5;
+    color.rgb *= 1.0 - scanlines * line;
+    gl_FragColor = color;
+}
+`
+
+func init() {
+	RegisterComponent("PostProcess", initPostProcess)
+	RegisterPostEffect("copy", copyEffect, nil)
+	RegisterPostEffect("colorGrading", colorGradingEffect, map[string]interface{}{
+		"brightness": 0, "contrast": 1, "saturation": 1, "tint": mgl32.Vec4{1, 1, 1, 1},
+	})
+	RegisterPostEffect("vignette", vignetteEffect, map[string]interface{}{
+		"intensity": 0.5, "radius": 0.75, "softness": 0.45,
+	})
+	RegisterPostEffect("crt", crtEffect, map[string]interface{}{
+		"scanlines": 0.25, "curvature": 0.1,
+	})
+}
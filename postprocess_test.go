@@ -0,0 +1,45 @@
+package gozmo
+
+import (
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+func TestPostProcessAttrs(t *testing.T) {
+	postProcess := NewPostProcess()
+	err := postProcess.SetAttr("effects", []interface{}{"vignette", "colorGrading"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = postProcess.SetAttr("colorGrading.tint", []interface{}{1.0, 0.5, 0.5, 1.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tint, _ := postProcess.GetAttr("colorGrading.tint")
+	if tint != (mgl32.Vec4{1, 0.5, 0.5, 1}) {
+		t.Error("Expected a tint, got", tint)
+	}
+	if postProcess.SetAttr("effects", []interface{}{"unknown"}) == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestPostProcessPixelPerfect(t *testing.T) {
+	Engine.Window = &Window{framebufferWidth: 1000, framebufferHeight: 500}
+	defer func() { Engine.Window = nil }()
+
+	postProcess := NewPostProcess()
+	postProcess.SetResolution(320, 180)
+	postProcess.pixelPerfect = true
+
+	// 2x, centered.
+	x, y, width, height := postProcess.windowRect()
+	if x != 180 || y != 70 || width != 640 || height != 360 {
+		t.Error("Expected 180 70 640 360, got", x, y, width, height)
+	}
+	nx, ny := postProcess.windowToImage(0.5, 0.5)
+	if nx != 0.5 || ny != 0.5 {
+		t.Error("Expected 0.5 0.5, got", nx, ny)
+	}
+}
@@ -7,7 +7,8 @@ import (
 )
 
 // A Rendered is an accelerated sprite drawer component. It supports color
-// addition and multiplication
+// addition and multiplication, flipping, pivots and the tiled and sliced
+// (nine-slice) draw modes.
 type Renderer struct {
 	mesh          *Mesh
 	texture       *Texture
@@ -15,6 +16,35 @@ type Renderer struct {
 	pixelsPerUnit uint32
 	index         uint32
 	forceHeight   float32
+	material      materialRef
+	// The blend mode depends on the texture unless set.
+	blend    BlendMode
+	hasBlend bool
+
+	flipX bool
+	flipY bool
+	// The pivot in the -1..1 space of the quad (0, 0 is the center), the
+	// GameObject one is used unless set.
+	pivot    mgl32.Vec2
+	hasPivot bool
+	// An explicit size in world units overrides pixelsPerUnit and forceHeight.
+	size     mgl32.Vec2
+	drawMode DrawMode
+	// Nine-slice borders in pixels (left, bottom, right, top).
+	border [4]float32
+	// The geometry currently in the mesh.
+	meshKey spriteMeshKey
+	// A texture of the scene with the normals of the sprite, for the lights.
+	normalMapName string
+	normalMap     *Texture
+}
+
+type spriteMeshKey struct {
+	mode          DrawMode
+	width, height float32
+	uv            [4]float32
+	tile          mgl32.Vec2
+	border        [4]float32
 }
 
 // The mesh is created and uploaded into the GPU only when needed.
@@ -81,12 +111,75 @@ func (renderer *Renderer) Update(gameObject *GameObject) {
 		renderer.createMesh()
 	}
 
+	renderer.material.resolve(gameObject.Scene)
+
+	renderer.normalMap = nil
+	if renderer.normalMapName != "" {
+		renderer.normalMap, _ = gameObject.Scene.textures[renderer.normalMapName]
+	}
+}
+
+func (renderer *Renderer) Draw(gameObject *GameObject, camera *Camera) {
+	ortho, bounds, uvdelta, ok := renderer.transform(gameObject, camera)
+	if !ok {
+		return
+	}
+
+	IncPerFrameStats("GL.DrawCalls", 1)
+
+	mode := renderer.BlendMode()
+	setBlendMode(mode)
+	renderer.mesh.premultiply = premultiplyFor(mode, renderer.texture)
+
+	material := renderer.material.material
+	if material != nil && material.draw(gameObject.Scene, renderer.mesh, renderer.texture.tid, bounds, uvdelta, ortho, renderer.material.uniforms) {
+		return
+	}
+	GLDraw(renderer.mesh, uint32(shader), bounds[0], bounds[1], int32(renderer.texture.tid), uvdelta[0], uvdelta[1], uvdelta[2], uvdelta[3], ortho)
+}
+
+func (renderer *Renderer) hasNormalMap() bool {
+	return renderer.normalMap != nil && renderer.texture != nil && renderer.mesh != nil
+}
+
+// drawNormals draws the normal map in place of the texture, for the lighting.
+func (renderer *Renderer) drawNormals(gameObject *GameObject, camera *Camera) {
+	ortho, bounds, uvdelta, ok := renderer.transform(gameObject, camera)
+	if !ok {
+		return
+	}
+
+	flip := mgl32.Vec2{1, 1}
+	if renderer.flipX {
+		flip[0] = -1
+	}
+	if renderer.flipY {
+		flip[1] = -1
+	}
+	uniforms := map[string]interface{}{"flip": flip, "rotation": gameObject.Rotation}
+	material := lighting.normals
+	material.SetTexture("normalMap", renderer.normalMapName)
+
+	IncPerFrameStats("GL.DrawCalls", 1)
+	setBlendMode(blendReplace)
+	material.draw(gameObject.Scene, renderer.mesh, renderer.texture.tid, bounds, uvdelta, ortho, uniforms)
+}
+
+// transform computes the matrix, the bounds and the uvs of the mesh, updating
+// it, returning false when there is nothing to draw.
+func (renderer *Renderer) transform(gameObject *GameObject, camera *Camera) (mgl32.Mat4, mgl32.Vec2, mgl32.Vec4, bool) {
 	texture := renderer.texture
+	if texture == nil || renderer.mesh == nil {
+		return mgl32.Mat4{}, mgl32.Vec2{}, mgl32.Vec4{}, false
+	}
 
 	// Recompute the mesh size based on the texture.
 	var width float32
 	var height float32
-	if renderer.forceHeight > 0 {
+	if renderer.size[0] > 0 && renderer.size[1] > 0 {
+		width = renderer.size[0] / 2
+		height = renderer.size[1] / 2
+	} else if renderer.forceHeight > 0 {
 		height = renderer.forceHeight / 2
 		width = renderer.forceHeight * ((float32(texture.Width) / float32(texture.Cols)) / (float32(texture.Height) / float32(texture.Rows))) / 2
 	} else {
@@ -94,21 +187,37 @@ func (renderer *Renderer) Update(gameObject *GameObject) {
 		height = float32(texture.Height) / float32(texture.Rows) / float32(renderer.pixelsPerUnit) / 2
 	}
 
+	pivot := gameObject.Pivot
+	if renderer.hasPivot {
+		pivot = renderer.pivot
+	}
+	flip := mgl32.Vec2{1, 1}
+	if renderer.flipX {
+		flip[0] = -1
+	}
+	if renderer.flipY {
+		flip[1] = -1
+	}
+	// The quad center, relative to the pivot.
+	centerX := -pivot[0] * width * flip[0]
+	centerY := -pivot[1] * height * flip[1]
+
 	// Out-of-view culling, avoids drawing quads that are out of the view quad
 	// extract view sizes.
-	viewWidth := Engine.Window.OrthographicSize * Engine.Window.AspectRatio * 2
-	viewHeight := Engine.Window.OrthographicSize * 2
-	viewX := -Engine.Window.View[12] - (viewWidth / 2)
-	viewY := -Engine.Window.View[13] + (viewHeight / 2)
+	viewBounds := camera.ViewBounds()
+	viewWidth := viewBounds[2] - viewBounds[0]
+	viewHeight := viewBounds[3] - viewBounds[1]
+	viewX := viewBounds[0]
+	viewY := viewBounds[3]
 
 	// Check if the object bounds are out of the view.
-	objX := gameObject.Position[0] - width
-	objY := gameObject.Position[1] + height
+	objX := gameObject.Position[0] + centerX - width
+	objY := gameObject.Position[1] + centerY + height
 	if (objX+(width*2)) < viewX ||
 		objX > (viewX+viewWidth) ||
 		(objY-(height*2)) > viewY ||
 		objY < (viewY-viewHeight) {
-		return
+		return mgl32.Mat4{}, mgl32.Vec2{}, mgl32.Vec4{}, false
 	}
 
 	// Recompute uvs based on index.
@@ -121,26 +230,134 @@ func (renderer *Renderer) Update(gameObject *GameObject) {
 	uvx := uvw * float32(idxX)
 	uvy := uvh * float32(idxY)
 
+	// Framebuffers start from the bottom.
+	if texture.IsRenderTexture() {
+		uvy = 1 - uvy
+		uvh = -uvh
+	}
+
 	model := mgl32.Translate3D(gameObject.Position[0], gameObject.Position[1], 0)
 
 	model = model.Mul4(mgl32.Scale3D(gameObject.Scale[0], gameObject.Scale[1], 1))
 
 	model = model.Mul4(mgl32.HomogRotate3DZ(gameObject.Rotation))
 
-	view := Engine.Window.View.Mul4(model)
+	// Flipping mirrors around the pivot, leaving the GameObject scale alone.
+	model = model.Mul4(mgl32.Scale3D(flip[0], flip[1], 1))
 
-	ortho := Engine.Window.Projection.Mul4(view)
+	model = model.Mul4(mgl32.Translate3D(-pivot[0]*width, -pivot[1]*height, 0))
 
-	IncPerFrameStats("GL.DrawCalls", 1)
+	view := camera.View.Mul4(model)
+
+	ortho := camera.Projection.Mul4(view)
 
-	GLDraw(renderer.mesh, uint32(shader), width, height, int32(renderer.texture.tid), uvx, uvy, uvw, uvh, ortho)
+	// Tiled and sliced meshes are in world units with absolute uvs.
+	bounds := mgl32.Vec2{width, height}
+	uvdelta := mgl32.Vec4{uvx, uvy, uvw, uvh}
+	if renderer.updateMesh(width*2, height*2, [4]float32{uvx, uvy, uvw, uvh}) {
+		bounds = mgl32.Vec2{1, 1}
+		uvdelta = mgl32.Vec4{}
+	}
+	return ortho, bounds, uvdelta, true
+}
+
+// updateMesh rebuilds the geometry when the draw mode or the size change,
+// returning false for the simple quad.
+func (renderer *Renderer) updateMesh(width, height float32, uv [4]float32) bool {
+	key := spriteMeshKey{mode: renderer.drawMode}
+	if renderer.drawMode != DrawSimple {
+		texture := renderer.texture
+		pixelsPerUnit := float32(renderer.pixelsPerUnit)
+		key.width = width
+		key.height = height
+		key.uv = uv
+		key.tile = mgl32.Vec2{float32(texture.Width) / float32(texture.Cols) / pixelsPerUnit, float32(texture.Height) / float32(texture.Rows) / pixelsPerUnit}
+		key.border = renderer.border
+	}
+	if key == renderer.meshKey {
+		return key.mode != DrawSimple
+	}
+	renderer.meshKey = key
+
+	mesh := renderer.mesh
+	switch key.mode {
+	case DrawTiled:
+		mesh.vertices, mesh.uvs = tiledGeometry(width, height, key.tile[0], key.tile[1], uv)
+	case DrawSliced:
+		pixelsPerUnit := float32(renderer.pixelsPerUnit)
+		var border [4]float32
+		var uvBorder [4]float32
+		for i, pixels := range key.border {
+			border[i] = pixels / pixelsPerUnit
+			cellPixels := key.tile[i%2] * pixelsPerUnit
+			if cellPixels > 0 {
+				uvBorder[i] = pixels / cellPixels
+			}
+		}
+		mesh.vertices, mesh.uvs = slicedGeometry(width, height, border, uvBorder, uv)
+	default:
+		mesh.vertices, mesh.uvs = appendQuad(nil, nil, -1, -1, 1, 1, 0, 0, 1, 1)
+	}
+	mesh.upload()
+	return key.mode != DrawSimple
 }
 
 func (renderer *Renderer) SetPixelsPerUnit(pixels uint32) {
 	renderer.pixelsPerUnit = pixels
 }
 
+func (renderer *Renderer) SetFlip(flipX, flipY bool) {
+	renderer.flipX = flipX
+	renderer.flipY = flipY
+}
+
+func (renderer *Renderer) SetPivot(x, y float32) {
+	renderer.pivot = mgl32.Vec2{x, y}
+	renderer.hasPivot = true
+}
+
+// SetSize sets the size in world units, zero restores the texture size.
+func (renderer *Renderer) SetSize(width, height float32) {
+	renderer.size = mgl32.Vec2{width, height}
+}
+
+func (renderer *Renderer) SetDrawMode(mode DrawMode) {
+	renderer.drawMode = mode
+}
+
+// SetBorder sets the nine-slice insets in pixels.
+func (renderer *Renderer) SetBorder(left, bottom, right, top float32) {
+	renderer.border = [4]float32{left, bottom, right, top}
+}
+
+func (renderer *Renderer) SetBlendMode(mode BlendMode) {
+	renderer.blend = mode
+	renderer.hasBlend = true
+}
+
+// BlendMode defaults to premultiplied for premultiplied textures.
+func (renderer *Renderer) BlendMode() BlendMode {
+	if renderer.hasBlend {
+		return renderer.blend
+	}
+	if renderer.texture != nil && renderer.texture.Options.Premultiplied {
+		return BlendPremultiplied
+	}
+	return BlendAlpha
+}
+
+// The "material" attribute selects a material of the scene by name, its
+// uniforms can be set (and animated) for this Renderer only as
+// "material.uniform".
 func (renderer *Renderer) SetAttr(attr string, value interface{}) error {
+	handled, err := renderer.material.setAttr(attr, value)
+	if handled {
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, renderer, err)
+		}
+		return nil
+	}
+
 	switch attr {
 	case "index":
 		index, err := CastUInt32(value)
@@ -156,6 +373,17 @@ func (renderer *Renderer) SetAttr(attr string, value interface{}) error {
 			return nil
 		}
 		return fmt.Errorf("%v attribute of %T expects a string", attr, renderer)
+	case "blend":
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, renderer)
+		}
+		mode, err := ParseBlendMode(name)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, renderer, err)
+		}
+		renderer.SetBlendMode(mode)
+		return nil
 	case "addR":
 		color, ok := value.(float32)
 		if ok {
@@ -219,16 +447,76 @@ func (renderer *Renderer) SetAttr(attr string, value interface{}) error {
 			return nil
 		}
 		return fmt.Errorf("%v attribute of %T expects a float32", attr, renderer)
+	case "flipX":
+		flag, err := CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T expects a bool", attr, renderer)
+		}
+		renderer.flipX = flag
+		return nil
+	case "flipY":
+		flag, err := CastBool(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T expects a bool", attr, renderer)
+		}
+		renderer.flipY = flag
+		return nil
+	case "pivot":
+		pivot, err := castFloat32List(value, 2)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, renderer, err)
+		}
+		renderer.SetPivot(pivot[0], pivot[1])
+		return nil
+	case "size":
+		size, err := castFloat32List(value, 2)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, renderer, err)
+		}
+		renderer.SetSize(size[0], size[1])
+		return nil
+	case "drawMode":
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, renderer)
+		}
+		mode, ok := drawModeNames[name]
+		if !ok {
+			return fmt.Errorf("%v attribute of %T unknown draw mode %v", attr, renderer, name)
+		}
+		renderer.drawMode = mode
+		return nil
+	case "border":
+		border, err := castFloat32List(value, 4)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, renderer, err)
+		}
+		renderer.SetBorder(border[0], border[1], border[2], border[3])
+		return nil
+	case "normalMap":
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, renderer)
+		}
+		renderer.normalMapName = name
+		return nil
 	}
 	return nil
 }
 
 func (renderer *Renderer) GetAttr(attr string) (interface{}, error) {
+	value, ok := renderer.material.getAttr(attr)
+	if ok {
+		return value, nil
+	}
+
 	switch attr {
 	case "index":
 		return renderer.index, nil
 	case "texture":
 		return renderer.textureName, nil
+	case "blend":
+		return renderer.BlendMode().String(), nil
 	case "addR":
 		return renderer.mesh.addColor[0], nil
 	case "addG":
@@ -245,6 +533,22 @@ func (renderer *Renderer) GetAttr(attr string) (interface{}, error) {
 		return renderer.mesh.mulColor[2], nil
 	case "mulA":
 		return renderer.mesh.mulColor[3], nil
+	case "forceHeight":
+		return renderer.forceHeight, nil
+	case "flipX":
+		return renderer.flipX, nil
+	case "flipY":
+		return renderer.flipY, nil
+	case "pivot":
+		return renderer.pivot, nil
+	case "size":
+		return renderer.size, nil
+	case "drawMode":
+		return renderer.drawMode.String(), nil
+	case "border":
+		return renderer.border, nil
+	case "normalMap":
+		return renderer.normalMapName, nil
 	}
 	return nil, fmt.Errorf("%v attribute of %T not found", attr, renderer)
 }
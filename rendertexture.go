@@ -0,0 +1,84 @@
+package gozmo
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// A render texture is a Texture backed by a framebuffer: a Camera with a
+// "targetTexture" draws into it, and a Renderer can then display it like any
+// other texture (mirrors, minimaps, portals).
+
+func newRenderTarget(name string, width, height uint32) (*Texture, error) {
+	framebuffer, tid, err := GLNewFramebuffer(int32(width), int32(height))
+	if err != nil {
+		return nil, err
+	}
+	tex := Texture{tid: tid, Name: name, Width: width, Height: height, Rows: 1, Cols: 1}
+	tex.framebuffer = framebuffer
+	countTexture(width, height, 1)
+	return &tex, nil
+}
+
+func (scene *Scene) NewRenderTexture(name string, width, height uint32) (*Texture, error) {
+	tex, err := newRenderTarget(name, width, height)
+	if err != nil {
+		return nil, err
+	}
+	scene.textures[name] = tex
+	return tex, nil
+}
+
+func (texture *Texture) IsRenderTexture() bool {
+	return texture.framebuffer != 0
+}
+
+// updateMipmaps generates the mipmaps of a render texture again, after a
+// camera draws into it.
+func (texture *Texture) updateMipmaps() {
+	if texture.Options.Mipmaps {
+		GLTextureFilter(texture.tid, texture.Options.Filter, true)
+	}
+}
+
+// bindRenderTarget selects a texture (or the window when nil) as the target of
+// the following draws, returning its size in pixels.
+func bindRenderTarget(texture *Texture) (int32, int32) {
+	if texture == nil {
+		GLBindFramebuffer(0)
+		return Engine.Window.framebufferWidth, Engine.Window.framebufferHeight
+	}
+	GLBindFramebuffer(texture.framebuffer)
+	return int32(texture.Width), int32(texture.Height)
+}
+
+// A quad covering the whole target, with the uvs of framebuffer textures
+// (starting from the bottom).
+func newScreenQuadMesh() *Mesh {
+	mesh := Mesh{}
+
+	mesh.abid = GLNewArray()
+	mesh.vbid = GLNewBuffer()
+	mesh.uvbid = GLNewBuffer()
+
+	mesh.vertices = []float32{-1, -1,
+		-1, 1,
+		1, -1,
+		1, -1,
+		1, 1,
+		-1, 1}
+
+	mesh.uvs = []float32{0, 0,
+		0, 1,
+		1, 0,
+		1, 0,
+		1, 1,
+		0, 1}
+
+	mesh.mulColor = mgl32.Vec4{1, 1, 1, 1}
+
+	GLBufferData(0, mesh.vbid, mesh.vertices)
+
+	GLBufferData(1, mesh.uvbid, mesh.uvs)
+
+	return &mesh
+}
@@ -0,0 +1,213 @@
+package gozmo
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+)
+
+// All of the raw input of a frame is collected in an InputFrame: the platform
+// window appends device events to it between frames, and at the start of a
+// frame it is handed to the input updaters. This is the point where a session
+// can be recorded to a file, or replayed from it instead of the real devices.
+
+type InputKey struct {
+	Key     Key
+	Pressed bool
+}
+
+type InputMouseButton struct {
+	Button  int
+	Pressed bool
+}
+
+type InputTouch struct {
+	Id    int
+	X     float32
+	Y     float32
+	Phase TouchPhase
+}
+
+type InputGamepad struct {
+	Present bool
+	Name    string
+	Axes    []float32
+	Buttons []byte
+}
+
+type InputFrame struct {
+	// The engine time of the frame, as passed to Scene.Update.
+	Time float64
+
+	Keys  []InputKey
+	Chars []rune
+
+	// Window coordinates of the cursor.
+	MouseX       float64
+	MouseY       float64
+	MouseButtons []InputMouseButton
+	WheelX       float64
+	WheelY       float64
+
+	Touches  []InputTouch
+	Gamepads []InputGamepad
+}
+
+var pendingInput InputFrame
+
+// Only the cursor position is carried over to the next frame.
+func nextInputFrame(frame *InputFrame) InputFrame {
+	return InputFrame{MouseX: frame.MouseX, MouseY: frame.MouseY}
+}
+
+type inputRecorder struct {
+	file    *os.File
+	writer  *gzip.Writer
+	encoder *gob.Encoder
+}
+
+var recorder *inputRecorder
+
+var replayFrames []*InputFrame
+var replayIndex int
+var replaying bool
+
+// StartInputRecording stores every following frame (time and input) into a
+// gzipped stream of gob encoded InputFrames.
+func StartInputRecording(fileName string) error {
+	if recorder != nil {
+		return fmt.Errorf("input recording already started")
+	}
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(file)
+	recorder = &inputRecorder{file: file, writer: writer, encoder: gob.NewEncoder(writer)}
+	return nil
+}
+
+func StopInputRecording() error {
+	if recorder == nil {
+		return nil
+	}
+	err := recorder.writer.Close()
+	if err == nil {
+		err = recorder.file.Close()
+	} else {
+		recorder.file.Close()
+	}
+	recorder = nil
+	return err
+}
+
+func IsRecordingInput() bool {
+	return recorder != nil
+}
+
+func LoadInputRecording(fileName string) ([]*InputFrame, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	decoder := gob.NewDecoder(reader)
+
+	var frames []*InputFrame
+	for {
+		frame := InputFrame{}
+		err = decoder.Decode(&frame)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return frames, err
+		}
+		frames = append(frames, &frame)
+	}
+	return frames, nil
+}
+
+// StartInputReplay makes Window.Run feed the recorded frames back instead of
+// the real devices. When the recording ends, the real devices are used again.
+func StartInputReplay(fileName string) error {
+	frames, err := LoadInputRecording(fileName)
+	if err != nil {
+		return err
+	}
+	replayFrames = frames
+	replayIndex = 0
+	replaying = true
+	return nil
+}
+
+func StopInputReplay() {
+	replayFrames = nil
+	replaying = false
+}
+
+// IsReplayingInput becomes false once all of the frames are replayed.
+func IsReplayingInput() bool {
+	return replaying
+}
+
+// updateInput is called by the platform window at the start of every frame,
+// with the current engine time. It returns the time to pass to Scene.Update,
+// which differs only while replaying.
+func updateInput(now float64) float64 {
+	frame := pendingInput
+	frame.Time = now
+	pendingInput = nextInputFrame(&frame)
+
+	if replaying {
+		if replayIndex < len(replayFrames) {
+			frame = *replayFrames[replayIndex]
+			replayIndex++
+		} else {
+			StopInputReplay()
+		}
+	}
+
+	if recorder != nil {
+		err := recorder.encoder.Encode(&frame)
+		if err != nil {
+			fmt.Println(err)
+			StopInputRecording()
+		}
+	}
+
+	processInputFrame(&frame)
+	return frame.Time
+}
+
+// processInputFrame feeds a frame to the input updaters, and samples the input
+// actions.
+func processInputFrame(frame *InputFrame) {
+	// The UI consumes its input before the devices see it.
+	updateUI(frame)
+	for _, updater := range inputUpdaters {
+		updater(frame)
+	}
+	UpdateInputActions()
+}
+
+// ReplayInput runs a recorded session on a scene without a window, as fast as
+// possible, which is useful for tests.
+func ReplayInput(scene *Scene, fileName string) error {
+	frames, err := LoadInputRecording(fileName)
+	if err != nil {
+		return err
+	}
+	for _, frame := range frames {
+		processInputFrame(frame)
+		scene.Update(frame.Time)
+	}
+	return nil
+}
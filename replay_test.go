@@ -0,0 +1,46 @@
+package gozmo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInputReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gozmo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "session.input")
+
+	err = StartInputRecording(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingInput.Keys = append(pendingInput.Keys, InputKey{Key: KeyA, Pressed: true})
+	updateInput(1)
+	pendingInput.Keys = append(pendingInput.Keys, InputKey{Key: KeyA, Pressed: false})
+	updateInput(2)
+	err = StopInputRecording()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	gameObject.AddComponent("kbd", NewKeyboard())
+	component := &TestComponentForEvent{}
+	gameObject.AddComponent("test", component)
+
+	err = ReplayInput(scene, fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keyDown and keyUp.
+	if component.counter != 2 {
+		t.Error("Expected 2, got", component.counter)
+	}
+	gameObject.Destroy()
+}
@@ -0,0 +1,194 @@
+package gozmo
+
+import (
+	"path/filepath"
+)
+
+// A ResourceCache shares the textures and sounds loaded from files among the
+// scenes, keyed by path (and by options for textures). Scenes hold references
+// to the cached resources, released when they are destroyed: the last
+// release frees the memory, unless the file is pinned.
+//
+// Each scene has its own Texture (with its own name, rows and columns), but
+// the options of a shared texture are the same for every scene using it.
+// The cache is only used from the main thread.
+//
+// The stats "Textures.Count" and "Textures.Bytes" follow all of the textures
+// on the GPU, "AudioClips.Count" and "AudioClips.Bytes" the cached sounds.
+type ResourceCache struct {
+	textures map[textureKey]*textureResource
+	clips    map[string]*clipResource
+	pinned   map[string]bool
+}
+
+type textureKey struct {
+	fileName string
+	options  TextureOptions
+}
+
+type textureResource struct {
+	key    textureKey
+	tid    uint32
+	width  uint32
+	height uint32
+	refs   int
+}
+
+type clipResource struct {
+	fileName string
+	clip     *AudioClip
+	refs     int
+}
+
+var resourceCache = ResourceCache{
+	textures: make(map[textureKey]*textureResource),
+	clips:    make(map[string]*clipResource),
+	pinned:   make(map[string]bool),
+}
+
+func DefaultResourceCache() *ResourceCache {
+	return &resourceCache
+}
+
+// acquireTexture returns a texture of the file, decoding it only when it is
+// not cached.
+func (cache *ResourceCache) acquireTexture(name string, fileName string, options TextureOptions, decode func() (decodedImage, error)) (*Texture, error) {
+	key := textureKey{fileName: filepath.Clean(fileName), options: options}
+	resource, ok := cache.textures[key]
+	if !ok {
+		decoded, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		uploaded := newTextureFromPixels(name, decoded, options)
+		resource = &textureResource{key: key, tid: uploaded.tid, width: uploaded.Width, height: uploaded.Height}
+		cache.textures[key] = resource
+	}
+	resource.refs++
+
+	texture := Texture{tid: resource.tid, Name: name, Width: resource.width, Height: resource.height, Rows: 1, Cols: 1}
+	texture.Options = options
+	texture.resource = resource
+	return &texture, nil
+}
+
+func (cache *ResourceCache) releaseTexture(resource *textureResource) {
+	resource.refs--
+	cache.freeTexture(resource)
+}
+
+func (cache *ResourceCache) freeTexture(resource *textureResource) {
+	if resource.refs > 0 || cache.pinned[resource.key.fileName] || cache.textures[resource.key] != resource {
+		return
+	}
+	delete(cache.textures, resource.key)
+	deleteTexture(resource.tid, resource.width, resource.height)
+}
+
+// acquireAudioClip returns a clip of the file, sharing the samples of the
+// cached one.
+func (cache *ResourceCache) acquireAudioClip(name string, fileName string, decode func() (*AudioClip, error)) (*AudioClip, error) {
+	fileName = filepath.Clean(fileName)
+	resource, ok := cache.clips[fileName]
+	if !ok {
+		decoded, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		resource = &clipResource{fileName: fileName, clip: decoded}
+		cache.clips[fileName] = resource
+		IncGlobalStats("AudioClips.Count", 1)
+		IncGlobalStats("AudioClips.Bytes", float64(len(decoded.samples)*4))
+	}
+	resource.refs++
+
+	clip := *resource.clip
+	clip.Name = name
+	clip.resource = resource
+	return &clip, nil
+}
+
+func (cache *ResourceCache) releaseAudioClip(resource *clipResource) {
+	resource.refs--
+	cache.freeAudioClip(resource)
+}
+
+func (cache *ResourceCache) freeAudioClip(resource *clipResource) {
+	if resource.refs > 0 || cache.pinned[resource.fileName] || cache.clips[resource.fileName] != resource {
+		return
+	}
+	delete(cache.clips, resource.fileName)
+	DecGlobalStats("AudioClips.Count", 1)
+	DecGlobalStats("AudioClips.Bytes", float64(len(resource.clip.samples)*4))
+}
+
+// Pin keeps the resources of a file cached even when no scene uses them,
+// like the sprites of the player shared by all of the levels.
+func (cache *ResourceCache) Pin(fileName string) {
+	cache.pinned[filepath.Clean(fileName)] = true
+}
+
+// Unpin frees the unused resources of a file.
+func (cache *ResourceCache) Unpin(fileName string) {
+	fileName = filepath.Clean(fileName)
+	delete(cache.pinned, fileName)
+	for key, resource := range cache.textures {
+		if key.fileName == fileName {
+			cache.freeTexture(resource)
+		}
+	}
+	resource, ok := cache.clips[fileName]
+	if ok {
+		cache.freeAudioClip(resource)
+	}
+}
+
+// PreloadTexture loads and pins a texture, so that scenes using it find it
+// in the cache.
+func (cache *ResourceCache) PreloadTexture(fileName string, options TextureOptions) error {
+	texture, err := cache.acquireTexture("", fileName, options, func() (decodedImage, error) {
+		return decodeImageFile(fileName, options.Premultiplied)
+	})
+	if err != nil {
+		return err
+	}
+	cache.Pin(fileName)
+	texture.Destroy()
+	return nil
+}
+
+// PreloadAudioClip loads and pins a sound.
+func (cache *ResourceCache) PreloadAudioClip(fileName string) error {
+	clip, err := cache.acquireAudioClip("", fileName, func() (*AudioClip, error) {
+		return decodeAudioFile(fileName, fileName)
+	})
+	if err != nil {
+		return err
+	}
+	cache.Pin(fileName)
+	clip.Destroy()
+	return nil
+}
+
+// References returns the number of scene references to the resources of a
+// file, -1 when it is not cached.
+func (cache *ResourceCache) References(fileName string) int {
+	fileName = filepath.Clean(fileName)
+	refs := 0
+	cached := false
+	for key, resource := range cache.textures {
+		if key.fileName == fileName {
+			refs += resource.refs
+			cached = true
+		}
+	}
+	resource, ok := cache.clips[fileName]
+	if ok {
+		refs += resource.refs
+		cached = true
+	}
+	if !cached {
+		return -1
+	}
+	return refs
+}
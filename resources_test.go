@@ -0,0 +1,111 @@
+package gozmo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSharedAudioClips(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resources")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "step.wav")
+	err = ioutil.WriteFile(fileName, encodeWAV(wavFormatPCM, 1, 8000, 8, []byte{128, 255, 0, 128}), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := GetGlobalStats("AudioClips.Count")
+	bytes := GetGlobalStats("AudioClips.Bytes")
+	level1 := NewScene("Level1")
+	level2 := NewScene("Level2")
+	step, err := level1.NewAudioClipFromFilename("step", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	walk, err := level2.NewAudioClipFromFilename("walk", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &step.samples[0] != &walk.samples[0] || walk.Name != "walk" {
+		t.Error("Expected the clips to share the samples")
+	}
+	if refs := resourceCache.References(fileName); refs != 2 {
+		t.Error("Expected 2 references, got", refs)
+	}
+	if GetGlobalStats("AudioClips.Count") != count+1 || GetGlobalStats("AudioClips.Bytes") != bytes+16 {
+		t.Error("Expected one more clip of 16 bytes, got", GetGlobalStats("AudioClips.Count"), GetGlobalStats("AudioClips.Bytes"))
+	}
+
+	level1.Destroy()
+	if refs := resourceCache.References(fileName); refs != 1 {
+		t.Error("Expected 1 reference, got", refs)
+	}
+	level2.Destroy()
+	if refs := resourceCache.References(fileName); refs != -1 {
+		t.Error("Expected the clip to be freed, got", refs)
+	}
+	if GetGlobalStats("AudioClips.Count") != count || GetGlobalStats("AudioClips.Bytes") != bytes {
+		t.Error("Expected the stats to be restored")
+	}
+
+	// Pinned clips stay cached without scenes.
+	err = resourceCache.PreloadAudioClip(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refs := resourceCache.References(fileName); refs != 0 {
+		t.Error("Expected 0 references, got", refs)
+	}
+	resourceCache.Unpin(fileName)
+	if refs := resourceCache.References(fileName); refs != -1 {
+		t.Error("Expected the clip to be freed, got", refs)
+	}
+}
+
+func TestSharedTextures(t *testing.T) {
+	// The texture is already uploaded, so the file is not read.
+	fileName := filepath.Join("sprites", "hero.png")
+	options := TextureOptions{Filter: TextureNearest}
+	key := textureKey{fileName: fileName, options: options}
+	resourceCache.textures[key] = &textureResource{key: key, tid: 7, width: 32, height: 16}
+	resourceCache.Pin(fileName)
+	defer func() {
+		delete(resourceCache.textures, key)
+		delete(resourceCache.pinned, fileName)
+	}()
+
+	level1 := NewScene("Level1")
+	level2 := NewScene("Level2")
+	hero, err := level1.NewTextureFromFilename("hero", "sprites/./hero.png", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := level2.NewTextureFromFilename("player", fileName, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hero.tid != 7 || player.tid != 7 || player.Name != "player" || player.Width != 32 {
+		t.Error("Expected the shared texture, got", hero, player)
+	}
+	hero.SetRowsCols(2, 2)
+	if player.Rows != 1 {
+		t.Error("Expected the rows of each scene, got", player.Rows)
+	}
+	if refs := resourceCache.References(fileName); refs != 2 {
+		t.Error("Expected 2 references, got", refs)
+	}
+
+	level1.Destroy()
+	level2.Destroy()
+	if refs := resourceCache.References(fileName); refs != 0 {
+		t.Error("Expected the pinned texture with 0 references, got", refs)
+	}
+	if hero.tid != 0 || hero.resource != nil {
+		t.Error("Expected the destroyed texture to be cleared")
+	}
+}
@@ -3,29 +3,53 @@ package gozmo
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 )
 
 // A Scene is a group of resources (textures, animations, sounds) and
 // instantiated gameObjects, akin to levels in games.
 //
 // When a scene is destroyed, all of the allocated resources and gameObjects
-// are destroyed too.
+// are destroyed too. Resources loaded from files are released instead, and
+// freed once no scene uses them (see ResourceCache).
 type Scene struct {
 	// TODO: is it a good idea to expose Name?
 	Name        string
 	gameObjects map[string]*GameObject
 	textures    map[string]*Texture
 	animations  map[string]*Animation
+	materials   map[string]*Material
+	fonts       map[string]*Font
+	audioClips  map[string]*AudioClip
+	musicTracks map[string]*MusicTrack
+	// The background music, crossfaded when the scene is set on the window.
+	music          string
+	musicCrossfade float32
 	// The last timestamp of the engine.
 	lastTime           float64
 	orderedGameObjects map[int][]*GameObject
 	orderedKeys        []int
+	cameras            []*Camera
+	lights             []*Light2D
+	postProcess        *PostProcess
+	audioListener      *AudioListener
+	// The parsed JSON of scenes loaded from files, for hot reloading.
+	source  map[string]interface{}
+	unwatch func()
 }
 
+// Update runs a frame of the scene alone. Windows update the scenes through
+// their SceneManager instead.
 func (scene *Scene) Update(now float64) {
+	scene.update(now)
+	scene.Draw()
+	endDebugFrame(now)
+	UpdatePerFrameStats()
+}
+
+func (scene *Scene) update(now float64) {
 	deltaTime := float32(now - scene.lastTime)
 	scene.lastTime = now
+	setDebugScene(scene)
 
 	for _, order := range scene.orderedKeys {
 		for _, gameObject := range scene.orderedGameObjects[order] {
@@ -40,14 +64,64 @@ func (scene *Scene) Update(now float64) {
 			// call Update() on components
 			gameObject.Update()
 
+			// resume coroutines whose wait is over
+			gameObject.UpdateCoroutines()
+
 		}
 	}
 
 	for _, updater := range Engine.registeredUpdaters {
 		updater(scene, deltaTime)
 	}
+}
 
-	UpdatePerFrameStats()
+// Draw renders the scene with all of its enabled cameras, or with the window
+// view when there are none. Nothing is drawn without a window.
+//
+// Cameras drawing into render textures come first, so that the textures are
+// ready for the other ones. When the scene has a PostProcess component, the
+// window cameras draw into its image, which is then processed on the window.
+func (scene *Scene) Draw() {
+	if Engine.Window == nil {
+		return
+	}
+
+	collectDebugOutlines(scene)
+
+	cameras := sortCameras(scene.cameras)
+	for _, camera := range cameras {
+		target := camera.renderTarget()
+		if camera.gameObject.enabled && target != nil {
+			camera.render(scene, target)
+			target.updateMipmaps()
+		}
+	}
+
+	var surface *Texture
+	if scene.postProcess != nil {
+		surface = scene.postProcess.begin()
+	}
+
+	drawn := false
+	for _, camera := range cameras {
+		if !camera.gameObject.enabled || camera.targetTexture != "" {
+			continue
+		}
+		camera.render(scene, surface)
+		drawDebug(scene, camera, surface)
+		drawn = true
+	}
+	if !drawn {
+		camera := Engine.Window.defaultCamera()
+		camera.render(scene, surface)
+		drawDebug(scene, camera, surface)
+	}
+
+	if scene.postProcess != nil {
+		scene.postProcess.end(surface)
+	}
+
+	drawCanvases(scene)
 }
 
 func NewScene(name string) *Scene {
@@ -55,6 +129,10 @@ func NewScene(name string) *Scene {
 	scene.gameObjects = make(map[string]*GameObject)
 	scene.textures = make(map[string]*Texture)
 	scene.animations = make(map[string]*Animation)
+	scene.materials = make(map[string]*Material)
+	scene.fonts = make(map[string]*Font)
+	scene.audioClips = make(map[string]*AudioClip)
+	scene.musicTracks = make(map[string]*MusicTrack)
 
 	scene.orderedGameObjects = make(map[int][]*GameObject)
 
@@ -66,7 +144,7 @@ func NewScene(name string) *Scene {
 	return &scene
 }
 
-func loadTextures(scene *Scene, textures []interface{}) {
+func loadTextures(scene *Scene, textures []interface{}, assets *sceneAssets) {
 	for _, texture := range textures {
 		texMap := texture.(map[string]interface{})
 
@@ -77,30 +155,54 @@ func loadTextures(scene *Scene, textures []interface{}) {
 
 		filename, hasFilename := texMap["filename"]
 
-		rows, hasRows := texMap["rows"]
-		cols, hasCols := texMap["cols"]
+		// Render textures have a size instead of a file.
+		width, hasWidth := texMap["width"]
+		height, hasHeight := texMap["height"]
 
 		var tex *Texture
-		var err error
 
-		if hasFilename {
-			tex, err = scene.NewTextureFromFilename(name.(string), filename.(string))
+		options, err := ParseTextureOptions(texMap)
+		if err != nil {
+			panic(err)
+		}
+
+		if hasFilename && assets != nil {
+			assets.add()
+			assets.loader.LoadTexture(scene, name.(string), filename.(string), options, func(tex *Texture, err error) {
+				if err == nil {
+					setTextureGrid(tex, texMap)
+				}
+				assets.finish(err)
+			})
+		} else if hasFilename {
+			tex, err = scene.NewTextureFromFilename(name.(string), filename.(string), options)
 			if err != nil {
 				panic(err)
 			}
+		} else if hasWidth && hasHeight {
+			tex, err = scene.NewRenderTexture(name.(string), uint32(width.(float64)), uint32(height.(float64)))
+			if err != nil {
+				panic(err)
+			}
+			tex.SetOptions(options)
 		}
 
-		if tex == nil {
-			continue
+		if tex != nil {
+			setTextureGrid(tex, texMap)
 		}
+	}
+}
 
-		if hasRows {
-			tex.SetRows(uint32(rows.(float64)))
-		}
+func setTextureGrid(tex *Texture, texMap map[string]interface{}) {
+	rows, hasRows := texMap["rows"]
+	cols, hasCols := texMap["cols"]
 
-		if hasCols {
-			tex.SetCols(uint32(cols.(float64)))
-		}
+	if hasRows {
+		tex.SetRows(uint32(rows.(float64)))
+	}
+
+	if hasCols {
+		tex.SetCols(uint32(cols.(float64)))
 	}
 }
 
@@ -177,6 +279,11 @@ func loadObjects(scene *Scene, objects []interface{}) {
 		if hasAttrs {
 			setAttrs(gameObject, attrs.([]interface{}))
 		}
+
+		persistent, ok := objMap["dontDestroyOnLoad"]
+		if ok && persistent.(bool) {
+			gameObject.DontDestroyOnLoad()
+		}
 	}
 }
 
@@ -232,7 +339,7 @@ func loadAnimations(scene *Scene, animations []interface{}) {
 }
 
 func NewSceneFromFilename(fileName string) *Scene {
-	data, err := ioutil.ReadFile(fileName)
+	data, err := fileSystem.ReadFile(fileName)
 	if err != nil {
 		panic(err)
 	}
@@ -244,12 +351,21 @@ func NewSceneFromFilename(fileName string) *Scene {
 		panic(err)
 	}
 
+	scene := newSceneFromJSON(parsed, nil)
+	watchScene(scene, fileName)
+	return scene
+}
+
+// newSceneFromJSON builds a parsed scene. With assets, textures and sounds
+// are loaded in the background.
+func newSceneFromJSON(parsed map[string]interface{}, assets *sceneAssets) *Scene {
 	name, ok := parsed["name"]
 	if !ok {
 		panic("a scene requires a name")
 	}
 
 	scene := NewScene(name.(string))
+	scene.source = parsed
 
 	for key, value := range parsed {
 		switch key {
@@ -257,13 +373,24 @@ func NewSceneFromFilename(fileName string) *Scene {
 			scene.Name = value.(string)
 		case "textures":
 			textures := value.([]interface{})
-			loadTextures(scene, textures)
+			loadTextures(scene, textures, assets)
 		case "objects":
 			objects := value.([]interface{})
 			loadObjects(scene, objects)
 		case "animations":
 			animations := value.([]interface{})
 			loadAnimations(scene, animations)
+		case "materials":
+			materials := value.([]interface{})
+			loadMaterials(scene, materials)
+		case "fonts":
+			fonts := value.([]interface{})
+			loadFonts(scene, fonts)
+		case "audio":
+			clips := value.([]interface{})
+			loadAudioClips(scene, clips, assets)
+		case "music":
+			loadMusic(scene, value.(map[string]interface{}))
 		}
 	}
 
@@ -271,6 +398,11 @@ func NewSceneFromFilename(fileName string) *Scene {
 }
 
 func (scene *Scene) Destroy() {
+	if scene.unwatch != nil {
+		scene.unwatch()
+		scene.unwatch = nil
+	}
+
 	// Destroy all objects.
 	for _, gameObject := range scene.gameObjects {
 		gameObject.Destroy()
@@ -281,6 +413,25 @@ func (scene *Scene) Destroy() {
 		texture.Destroy()
 	}
 
-	// Remove the scene from the map, so that the GC can reclaim it.
-	delete(Engine.scenes, scene.Name)
+	for _, clip := range scene.audioClips {
+		clip.Destroy()
+	}
+
+	scene.gameObjects = make(map[string]*GameObject)
+	scene.orderedGameObjects = make(map[int][]*GameObject)
+	scene.orderedKeys = nil
+	scene.textures = make(map[string]*Texture)
+	scene.animations = make(map[string]*Animation)
+	scene.materials = make(map[string]*Material)
+	scene.fonts = make(map[string]*Font)
+	scene.audioClips = make(map[string]*AudioClip)
+	scene.musicTracks = make(map[string]*MusicTrack)
+
+	sceneManager.remove(scene)
+
+	// Remove the scene from the map, so that the GC can reclaim it. A
+	// reloaded scene with the same name is already registered.
+	if Engine.scenes[scene.Name] == scene {
+		delete(Engine.scenes, scene.Name)
+	}
 }
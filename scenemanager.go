@@ -0,0 +1,538 @@
+package gozmo
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// TransitionType is the effect covering the window while a scene replaces
+// another one.
+type TransitionType int
+
+const (
+	TransitionNone TransitionType = iota
+	// The window fades to the color and back.
+	TransitionFade
+	// The color sweeps the window from left to right, twice.
+	TransitionWipe
+)
+
+// A Transition lasts Duration seconds: the first half covers the window,
+// still drawing the old scene, the second half uncovers the new one.
+type Transition struct {
+	Type     TransitionType
+	Duration float32
+	Color    mgl32.Vec4
+}
+
+// FadeTransition fades to black and back.
+func FadeTransition(duration float32) Transition {
+	return Transition{Type: TransitionFade, Duration: duration, Color: mgl32.Vec4{0, 0, 0, 1}}
+}
+
+// WipeTransition wipes with black.
+func WipeTransition(duration float32) Transition {
+	return Transition{Type: TransitionWipe, Duration: duration, Color: mgl32.Vec4{0, 0, 0, 1}}
+}
+
+// LoadMode tells if a loaded scene replaces the loaded ones or goes on top
+// of them.
+type LoadMode int
+
+const (
+	LoadSingle LoadMode = iota
+	LoadAdditive
+)
+
+type sceneTransition struct {
+	Transition
+	elapsed float32
+	// The incoming scene, nil while it is loading.
+	scene      *Scene
+	loaded     bool
+	justLoaded bool
+}
+
+// A SceneManager updates and draws the loaded scenes: the main one, then the
+// additively loaded ones on top of it (like a HUD over a level).
+//
+// Loading a scene in single mode destroys the loaded ones, with their
+// resources, at the middle of the transition (or once the new scene is
+// loaded, the window staying covered until then). Their GameObjects marked with
+// DontDestroyOnLoad move to the new scene instead. Scenes are only added and
+// removed between frames, so components can load scenes in their Update.
+type SceneManager struct {
+	scenes     []*Scene
+	transition *sceneTransition
+	unloads    []*Scene
+	lastTime   float64
+	// The mesh of the transition overlay.
+	mesh *Mesh
+}
+
+var sceneManager SceneManager
+
+// DefaultSceneManager is the manager of the scenes shown on the window.
+func DefaultSceneManager() *SceneManager {
+	return &sceneManager
+}
+
+// ActiveScene is the main scene, nil when none is loaded.
+func (manager *SceneManager) ActiveScene() *Scene {
+	if len(manager.scenes) == 0 {
+		return nil
+	}
+	return manager.scenes[0]
+}
+
+// Scenes returns the loaded scenes, in drawing order.
+func (manager *SceneManager) Scenes() []*Scene {
+	return append([]*Scene(nil), manager.scenes...)
+}
+
+func (manager *SceneManager) IsLoaded(scene *Scene) bool {
+	return manager.index(scene) >= 0
+}
+
+func (manager *SceneManager) index(scene *Scene) int {
+	for i, loaded := range manager.scenes {
+		if loaded == scene {
+			return i
+		}
+	}
+	return -1
+}
+
+// InTransition tells if a scene is being replaced.
+func (manager *SceneManager) InTransition() bool {
+	return manager.transition != nil
+}
+
+// SetScene sets the main scene at once, keeping the previous one alive (as
+// Window.SetScene always did) and canceling any transition. A nil scene
+// unloads all of the scenes without destroying them.
+func (manager *SceneManager) SetScene(scene *Scene) {
+	manager.transition = nil
+	if scene == nil {
+		manager.scenes = nil
+		return
+	}
+	manager.remove(scene)
+	if len(manager.scenes) == 0 {
+		manager.scenes = []*Scene{scene}
+	} else {
+		manager.scenes[0] = scene
+	}
+	manager.activate(scene)
+	scene.startMusic()
+}
+
+// LoadScene shows a scene. In single mode it replaces the loaded scenes
+// with the transition, additive scenes appear at once on top of the others.
+func (manager *SceneManager) LoadScene(scene *Scene, mode LoadMode, transition Transition) {
+	if mode == LoadAdditive {
+		manager.remove(scene)
+		manager.scenes = append(manager.scenes, scene)
+		manager.activate(scene)
+		return
+	}
+	manager.startTransition(transition).scene = scene
+}
+
+// LoadSceneFromFilename loads the scene from a JSON file with the default
+// AssetLoader, while the loaded scenes keep running. done, which can be nil,
+// is called on the main thread with the loaded scene, or with the error of a
+// missing or malformed file: the transition is then canceled, revealing the
+// loaded scenes again.
+func (manager *SceneManager) LoadSceneFromFilename(fileName string, mode LoadMode, transition Transition, done func(*Scene, error)) {
+	var pending *sceneTransition
+	if mode == LoadSingle {
+		pending = manager.startTransition(transition)
+	}
+	assetLoader.LoadScene(fileName, func(scene *Scene, err error) {
+		if err != nil {
+			if manager.transition == pending && pending != nil {
+				pending.cancel()
+			}
+			if done == nil {
+				fmt.Println("scene", fileName, err)
+				return
+			}
+			done(nil, err)
+			return
+		}
+		if mode == LoadAdditive {
+			manager.LoadScene(scene, mode, transition)
+		} else if manager.transition == pending {
+			pending.scene = scene
+		} else {
+			// Another scene was loaded meanwhile.
+			scene.Destroy()
+			return
+		}
+		if done != nil {
+			done(scene, nil)
+		}
+	})
+}
+
+func (manager *SceneManager) startTransition(transition Transition) *sceneTransition {
+	if transition.Duration < 0 {
+		transition.Duration = 0
+	}
+	manager.transition = &sceneTransition{Transition: transition}
+	return manager.transition
+}
+
+// UnloadScene destroys a loaded scene, before the next frame. The
+// GameObjects marked with DontDestroyOnLoad move to the main scene, unless
+// the main scene is the unloaded one.
+func (manager *SceneManager) UnloadScene(scene *Scene) {
+	manager.unloads = append(manager.unloads, scene)
+}
+
+func (manager *SceneManager) remove(scene *Scene) {
+	index := manager.index(scene)
+	if index >= 0 {
+		manager.scenes = append(manager.scenes[:index], manager.scenes[index+1:]...)
+	}
+}
+
+// activate avoids a huge delta time in the first update of a scene.
+func (manager *SceneManager) activate(scene *Scene) {
+	if manager.lastTime > 0 {
+		scene.lastTime = manager.lastTime
+	}
+}
+
+// replace destroys the loaded scenes, moving their persistent GameObjects
+// to the new main scene.
+func (manager *SceneManager) replace(scene *Scene) {
+	for _, old := range manager.Scenes() {
+		if old == scene {
+			continue
+		}
+		old.movePersistent(scene)
+		old.Destroy()
+	}
+	manager.scenes = []*Scene{scene}
+	manager.activate(scene)
+	scene.startMusic()
+}
+
+func (manager *SceneManager) unload(scene *Scene) {
+	if !manager.IsLoaded(scene) {
+		return
+	}
+	main := manager.ActiveScene()
+	if main != scene {
+		scene.movePersistent(main)
+	}
+	manager.remove(scene)
+	scene.Destroy()
+}
+
+// update runs a frame of the loaded scenes, drawing the transition over them.
+func (manager *SceneManager) update(now float64) {
+	deltaTime := float32(now - manager.lastTime)
+	manager.lastTime = now
+
+	unloads := manager.unloads
+	manager.unloads = nil
+	for _, scene := range unloads {
+		manager.unload(scene)
+	}
+
+	transition := manager.transition
+	if transition != nil {
+		// The time spent loading must not skip the second half.
+		if transition.justLoaded {
+			deltaTime = 0
+			transition.justLoaded = false
+		}
+		transition.elapsed += deltaTime
+		if !transition.loaded && transition.elapsed >= transition.Duration/2 {
+			// The window stays covered until the scene is loaded.
+			transition.elapsed = transition.Duration / 2
+			if transition.scene != nil {
+				transition.loaded = true
+				transition.justLoaded = true
+				manager.replace(transition.scene)
+			}
+		}
+	}
+
+	for _, scene := range manager.Scenes() {
+		scene.update(now)
+		scene.Draw()
+	}
+	endDebugFrame(now)
+
+	if transition != nil && manager.transition == transition {
+		manager.drawTransition(transition.coverage())
+		if transition.loaded && transition.elapsed >= transition.Duration {
+			manager.transition = nil
+		}
+	}
+
+	UpdatePerFrameStats()
+}
+
+// coverage goes from 0 to 1 in the first half and back to 0 in the second.
+// cancel reveals the loaded scenes again, from the current coverage.
+func (transition *sceneTransition) cancel() {
+	if transition.loaded {
+		return
+	}
+	transition.loaded = true
+	transition.elapsed = transition.Duration - transition.elapsed
+}
+
+func (transition *sceneTransition) coverage() float32 {
+	half := transition.Duration / 2
+	if half <= 0 {
+		return 0
+	}
+	if !transition.loaded {
+		return clamp01(transition.elapsed / half)
+	}
+	return clamp01(1 - (transition.elapsed-half)/half)
+}
+
+func clamp01(value float32) float32 {
+	if value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
+
+// overlay returns the rectangle (left, right in 0..1) and the color covering
+// the window.
+func (transition *sceneTransition) overlay(coverage float32) (float32, float32, mgl32.Vec4) {
+	color := transition.Color
+	switch transition.Type {
+	case TransitionFade:
+		color[3] *= coverage
+		return 0, 1, color
+	case TransitionWipe:
+		// The wipe enters from the left and leaves from the right.
+		if !transition.loaded {
+			return 0, coverage, color
+		}
+		return 1 - coverage, 1, color
+	}
+	return 0, 0, color
+}
+
+func (manager *SceneManager) drawTransition(coverage float32) {
+	if coverage <= 0 || Engine.Window == nil || !compileParticleProgram() {
+		return
+	}
+	left, right, color := manager.transition.overlay(coverage)
+	if right <= left || color[3] <= 0 {
+		return
+	}
+	if manager.mesh == nil {
+		manager.mesh = newDynamicMesh()
+	}
+
+	mesh := manager.mesh
+	mesh.vertices, mesh.uvs = appendQuad(mesh.vertices[:0], mesh.uvs[:0], left, 0, right, 1, 0, 0, 0, 0)
+	mesh.colors = mesh.colors[:0]
+	for i := 0; i < 6; i++ {
+		mesh.colors = append(mesh.colors, color[0], color[1], color[2], color[3])
+	}
+	mesh.uploadDynamic()
+
+	GLViewport(0, 0, Engine.Window.framebufferWidth, Engine.Window.framebufferHeight)
+	setBlendMode(BlendAlpha)
+	GLUseProgram(particleProgram.id)
+	GLUniform(particleProgram.ortho, mgl32.Ortho2D(0, 1, 0, 1))
+	GLUniform(particleProgram.textured, float32(0))
+	IncPerFrameStats("GL.DrawCalls", 1)
+	GLDrawMesh(mesh, 0)
+}
+
+// sortByScene orders canvases by the drawing order of their scenes first.
+func (manager *SceneManager) sortByScene(canvases []*Canvas) {
+	sort.SliceStable(canvases, func(i, j int) bool {
+		si := manager.index(canvases[i].gameObject.Scene)
+		sj := manager.index(canvases[j].gameObject.Scene)
+		if si != sj {
+			return si < sj
+		}
+		return canvases[i].order < canvases[j].order
+	})
+}
+
+// DontDestroyOnLoad keeps the GameObject alive when its scene is unloaded,
+// moving it to the scene replacing it.
+func (gameObject *GameObject) DontDestroyOnLoad() {
+	gameObject.persistent = true
+}
+
+// resourceAttrs are the component attributes naming resources of the scene.
+var resourceAttrs = []string{"texture", "normalMap", "material", "font", "clip", "animation"}
+
+// movePersistent moves the GameObjects marked with DontDestroyOnLoad to
+// another scene. A GameObject of the other scene with the same name is
+// destroyed, so that scenes can all include a persistent object.
+func (scene *Scene) movePersistent(to *Scene) {
+	if to == nil {
+		return
+	}
+	for _, order := range scene.orderedKeys {
+		for _, gameObject := range append([]*GameObject(nil), scene.orderedGameObjects[order]...) {
+			if gameObject.persistent {
+				scene.moveGameObject(gameObject, to)
+			}
+		}
+	}
+}
+
+// moveGameObject moves a GameObject to another scene, with the resources
+// named by the attributes of its components, like the texture of a Renderer
+// and the frames of an animation. Resources only named later (like an
+// animation played by a script) must also be in the other scene.
+func (scene *Scene) moveGameObject(gameObject *GameObject, to *Scene) {
+	existing, ok := to.gameObjects[gameObject.Name]
+	if ok {
+		existing.Destroy()
+		to.removeGameObject(existing)
+	}
+	scene.removeGameObject(gameObject)
+
+	gameObject.Scene = to
+	to.gameObjects[gameObject.Name] = gameObject
+	gameObject.index = -1
+	gameObject.SetOrder(gameObject.order)
+
+	for _, key := range gameObject.componentsKeys {
+		component := gameObject.components[key]
+		switch component := component.(type) {
+		case *Camera:
+			scene.cameras = removeCamera(scene.cameras, component)
+			to.cameras = append(to.cameras, component)
+		case *Light2D:
+			scene.lights = removeLight(scene.lights, component)
+			to.lights = append(to.lights, component)
+		case *PostProcess:
+			if scene.postProcess == component {
+				scene.postProcess = nil
+				to.postProcess = component
+			}
+		case *AudioListener:
+			if scene.audioListener == component {
+				scene.audioListener = nil
+				to.audioListener = component
+			}
+		}
+		componentAttr, ok := component.(ComponentAttr)
+		if !ok {
+			continue
+		}
+		for _, attr := range resourceAttrs {
+			value, err := componentAttr.GetAttr(attr)
+			name, ok := value.(string)
+			if err == nil && ok {
+				scene.moveResource(attr, name, to)
+			}
+		}
+	}
+}
+
+// removeGameObject unlinks a GameObject, keeping the indices of the others
+// in its order list.
+func (scene *Scene) removeGameObject(gameObject *GameObject) {
+	if scene.gameObjects[gameObject.Name] == gameObject {
+		delete(scene.gameObjects, gameObject.Name)
+	}
+	list := scene.orderedGameObjects[gameObject.order]
+	for i, other := range list {
+		if other != gameObject {
+			continue
+		}
+		list = append(list[:i], list[i+1:]...)
+		for j := i; j < len(list); j++ {
+			list[j].index = j
+		}
+		scene.orderedGameObjects[gameObject.order] = list
+		break
+	}
+	gameObject.index = -1
+}
+
+func removeCamera(cameras []*Camera, camera *Camera) []*Camera {
+	for i, c := range cameras {
+		if c == camera {
+			return append(cameras[:i], cameras[i+1:]...)
+		}
+	}
+	return cameras
+}
+
+func removeLight(lights []*Light2D, light *Light2D) []*Light2D {
+	for i, l := range lights {
+		if l == light {
+			return append(lights[:i], lights[i+1:]...)
+		}
+	}
+	return lights
+}
+
+// moveResource moves a resource named by an attribute, unless the other
+// scene has its own with the same name.
+func (scene *Scene) moveResource(attr string, name string, to *Scene) {
+	switch attr {
+	case "texture", "normalMap":
+		texture, ok := scene.textures[name]
+		if ok && to.textures[name] == nil {
+			to.textures[name] = texture
+			delete(scene.textures, name)
+		}
+	case "material":
+		material, ok := scene.materials[name]
+		if ok && to.materials[name] == nil {
+			to.materials[name] = material
+			delete(scene.materials, name)
+			for _, textureName := range material.textures {
+				scene.moveResource("texture", textureName, to)
+			}
+		}
+	case "font":
+		font, ok := scene.fonts[name]
+		if ok && to.fonts[name] == nil {
+			to.fonts[name] = font
+			delete(scene.fonts, name)
+			// The atlas is a texture of the scene.
+			if font.texture != nil {
+				scene.moveResource("texture", font.texture.Name, to)
+			}
+		}
+	case "clip":
+		clip, ok := scene.audioClips[name]
+		if ok && to.audioClips[name] == nil {
+			to.audioClips[name] = clip
+			delete(scene.audioClips, name)
+		}
+	case "animation":
+		animation, ok := scene.animations[name]
+		if ok && to.animations[name] == nil {
+			to.animations[name] = animation
+			delete(scene.animations, name)
+			for _, frame := range animation.Frames {
+				for _, action := range frame.actions {
+					value, ok := action.Value.(string)
+					if ok {
+						scene.moveResource(action.Attr, value, to)
+					}
+				}
+			}
+		}
+	}
+}
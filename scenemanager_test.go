@@ -0,0 +1,209 @@
+package gozmo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSceneTransition(t *testing.T) {
+	manager := SceneManager{}
+	level1 := NewScene("Level1")
+	level1.NewGameObject("Door")
+	level2 := NewScene("Level2")
+
+	manager.SetScene(level1)
+	manager.update(1)
+	manager.LoadScene(level2, LoadSingle, FadeTransition(1))
+
+	// The old scene is still shown while the window fades.
+	manager.update(1.25)
+	if manager.ActiveScene() != level1 {
+		t.Fatal("Expected Level1, got", manager.ActiveScene().Name)
+	}
+	if coverage := manager.transition.coverage(); coverage != 0.5 {
+		t.Error("Expected 0.5, got", coverage)
+	}
+
+	manager.update(1.5)
+	if manager.ActiveScene() != level2 || len(manager.Scenes()) != 1 {
+		t.Fatal("Expected only Level2, got", manager.Scenes())
+	}
+	if level1.FindGameObject("Door") != nil || Engine.scenes["Level1"] != nil {
+		t.Error("Expected Level1 to be destroyed")
+	}
+	if coverage := manager.transition.coverage(); coverage != 1 {
+		t.Error("Expected 1, got", coverage)
+	}
+
+	// The frame after the load does not count.
+	manager.update(1.6)
+	manager.update(1.85)
+	if coverage := manager.transition.coverage(); coverage != 0.5 {
+		t.Error("Expected 0.5, got", coverage)
+	}
+	manager.update(2.1)
+	if manager.InTransition() {
+		t.Error("Expected the transition to end")
+	}
+}
+
+func TestTransitionOverlay(t *testing.T) {
+	wipe := sceneTransition{Transition: WipeTransition(1)}
+	left, right, _ := wipe.overlay(0.25)
+	if left != 0 || right != 0.25 {
+		t.Error("Expected 0 0.25, got", left, right)
+	}
+	wipe.loaded = true
+	left, right, _ = wipe.overlay(0.25)
+	if left != 0.75 || right != 1 {
+		t.Error("Expected 0.75 1, got", left, right)
+	}
+
+	fade := sceneTransition{Transition: FadeTransition(1)}
+	left, right, color := fade.overlay(0.5)
+	if left != 0 || right != 1 || color[3] != 0.5 {
+		t.Error("Expected 0 1 0.5, got", left, right, color[3])
+	}
+}
+
+func TestDontDestroyOnLoad(t *testing.T) {
+	manager := SceneManager{}
+	level1 := NewScene("Level1")
+	clip, _ := NewAudioClip("jump", 44100, 1, make([]float32, 10))
+	level1.AddAudioClip(clip)
+	level1.AddAudioClip(&AudioClip{Name: "rain", SampleRate: 44100, Channels: 1})
+	level1.AddAnimation("idle", 10, true).AddSimpleFrame("renderer", "texture", "hero_idle", false)
+	level1.textures["hero_idle"] = &Texture{Name: "hero_idle"}
+	level1.textures["grass"] = &Texture{Name: "grass"}
+	level1.textures["hud.font"] = &Texture{Name: "hud.font", tid: 3}
+	level1.fonts["hud"] = &Font{Name: "hud", texture: level1.textures["hud.font"]}
+
+	player := level1.NewGameObject("Player")
+	player.SetOrder(3)
+	player.AddComponent("voice", NewAudioSource(clip))
+	animator := NewAnimator()
+	player.AddComponent("animator", animator)
+	animator.SetAttr("animation", "idle")
+	light := NewLight2D(LightPoint)
+	player.AddComponent("light", light)
+	player.AddComponent("score", NewText("hud", "0"))
+	player.DontDestroyOnLoad()
+	level1.NewGameObject("Tree")
+
+	level2 := NewScene("Level2")
+	duplicate := level2.NewGameObject("Player")
+	level2.NewGameObject("Rock")
+
+	manager.SetScene(level1)
+	manager.LoadScene(level2, LoadSingle, Transition{})
+	manager.update(1)
+
+	if level2.FindGameObject("Player") != player || player.Scene != level2 {
+		t.Fatal("Expected the persistent Player in Level2")
+	}
+	if level2.FindGameObject("Player") == duplicate {
+		t.Error("Expected the Player of Level2 to be replaced")
+	}
+	if len(level2.orderedGameObjects[3]) != 1 || len(level2.orderedGameObjects[0]) != 1 {
+		t.Error("Expected Player and Rock ordered, got", level2.orderedGameObjects)
+	}
+	if len(level2.lights) != 1 || level2.lights[0] != light {
+		t.Error("Expected the light in Level2, got", level2.lights)
+	}
+	if level2.GetAudioClip("jump") == nil || level2.animations["idle"] == nil || level2.textures["hero_idle"] == nil {
+		t.Error("Expected the resources of Player in Level2")
+	}
+	if level2.fonts["hud"] == nil || level2.textures["hud.font"] == nil || level2.textures["hud.font"].tid != 3 {
+		t.Error("Expected the font of Player with its atlas in Level2")
+	}
+	if level2.GetAudioClip("rain") != nil || level2.textures["grass"] != nil {
+		t.Error("Expected only the resources of Player in Level2")
+	}
+	if level1.FindGameObject("Tree") != nil || len(level1.textures) != 0 {
+		t.Error("Expected Level1 to be destroyed")
+	}
+}
+
+func TestAdditiveScenes(t *testing.T) {
+	manager := SceneManager{}
+	level := NewScene("Level")
+	manager.SetScene(level)
+
+	hud := NewScene("HUD")
+	hud.NewGameObject("Score").DontDestroyOnLoad()
+	hud.NewGameObject("Lives")
+	manager.LoadScene(hud, LoadAdditive, FadeTransition(1))
+	scenes := manager.Scenes()
+	if len(scenes) != 2 || scenes[0] != level || scenes[1] != hud {
+		t.Fatal("Expected Level and HUD, got", scenes)
+	}
+
+	// Unloading waits for the next frame.
+	manager.UnloadScene(hud)
+	if !manager.IsLoaded(hud) {
+		t.Error("Expected HUD to be loaded")
+	}
+	manager.update(1)
+	if manager.IsLoaded(hud) || len(manager.Scenes()) != 1 {
+		t.Error("Expected only Level, got", manager.Scenes())
+	}
+	if level.FindGameObject("Score") == nil || level.FindGameObject("Lives") != nil {
+		t.Error("Expected Score to move to Level")
+	}
+}
+
+func TestLoadSceneFromFilename(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scenes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "level.json")
+	data := `{"name": "Loaded", "objects": [{"name": "Manager", "dontDestroyOnLoad": true}, {"name": "Wall"}]}`
+	err = ioutil.WriteFile(fileName, []byte(data), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manager := SceneManager{}
+	manager.SetScene(NewScene("Menu"))
+	var loaded *Scene
+	manager.LoadSceneFromFilename(fileName, LoadSingle, FadeTransition(1), func(scene *Scene, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		loaded = scene
+	})
+	manager.update(1)
+	if manager.ActiveScene().Name != "Menu" {
+		t.Error("Expected Menu until the file is loaded")
+	}
+	DefaultAssetLoader().Wait()
+	manager.update(1.5)
+	scene := manager.ActiveScene()
+	if scene != loaded || scene.Name != "Loaded" || !scene.FindGameObject("Manager").persistent || scene.FindGameObject("Wall").persistent {
+		t.Error("Expected Loaded with a persistent Manager")
+	}
+
+	// Errors are reported, the transition reveals the scene again.
+	var loadErr error
+	manager.LoadSceneFromFilename(filepath.Join(dir, "missing.json"), LoadSingle, FadeTransition(1), func(scene *Scene, err error) {
+		loadErr = err
+	})
+	manager.update(2)
+	manager.update(2.25)
+	DefaultAssetLoader().Wait()
+	if loadErr == nil {
+		t.Fatal("Expected an error for a missing file")
+	}
+	manager.update(2.5)
+	if coverage := manager.transition.coverage(); coverage != 0.5 {
+		t.Error("Expected the transition to fade out from 0.5, got", coverage)
+	}
+	manager.update(3)
+	if manager.InTransition() || manager.ActiveScene() != scene {
+		t.Error("Expected the transition to end on Loaded")
+	}
+}
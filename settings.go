@@ -0,0 +1,117 @@
+package gozmo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Settings are user preferences (like the volumes of the audio buses) kept
+// across runs in a JSON file. Without a file they only last for the run.
+// Changes are written when the window closes, or by SaveSettings.
+
+var settings = struct {
+	fileName  string
+	values    map[string]interface{}
+	dirty     bool
+	listeners []func()
+}{values: make(map[string]interface{})}
+
+// LoadSettings reads the settings from a file, which is also the one
+// written by SaveSettings. A missing file is not an error.
+func LoadSettings(fileName string) error {
+	settings.fileName = fileName
+	data, err := ioutil.ReadFile(fileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var values map[string]interface{}
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return fmt.Errorf("settings %v %v", fileName, err)
+	}
+	for key, value := range values {
+		settings.values[key] = value
+	}
+	settings.dirty = false
+	for _, listener := range settings.listeners {
+		listener()
+	}
+	return nil
+}
+
+// SaveSettings writes the settings to the file given to LoadSettings.
+func SaveSettings() error {
+	if settings.fileName == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(settings.values, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(settings.fileName), 0755)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(settings.fileName, data, 0644)
+	if err != nil {
+		return err
+	}
+	settings.dirty = false
+	return nil
+}
+
+// saveSettings writes the changed settings, reporting errors.
+func saveSettings() {
+	if !settings.dirty {
+		return
+	}
+	err := SaveSettings()
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+func Setting(key string) (interface{}, bool) {
+	value, ok := settings.values[key]
+	return value, ok
+}
+
+func SetSetting(key string, value interface{}) {
+	settings.values[key] = value
+	settings.dirty = true
+}
+
+func settingFloat32(key string, defaultValue float32) float32 {
+	value, ok := settings.values[key]
+	if !ok {
+		return defaultValue
+	}
+	number, err := CastFloat32(value)
+	if err != nil {
+		return defaultValue
+	}
+	return number
+}
+
+func settingBool(key string, defaultValue bool) bool {
+	value, ok := settings.values[key]
+	if !ok {
+		return defaultValue
+	}
+	flag, err := CastBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return flag
+}
+
+// onSettingsLoaded registers a function applying the loaded settings.
+func onSettingsLoaded(listener func()) {
+	settings.listeners = append(settings.listeners, listener)
+}
@@ -0,0 +1,117 @@
+package gozmo
+
+// Geometry of the tiled and sliced Renderer draw modes. Vertices are in world
+// units around the center, uvs are absolute texture coordinates of the cell
+// (u0, v0 is its top-left corner).
+
+type DrawMode int
+
+const (
+	DrawSimple DrawMode = iota
+	DrawTiled
+	DrawSliced
+)
+
+var drawModeNames = map[string]DrawMode{
+	"simple": DrawSimple,
+	"tiled":  DrawTiled,
+	"sliced": DrawSliced,
+}
+
+func (mode DrawMode) String() string {
+	for name, value := range drawModeNames {
+		if value == mode {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Upper limit of the quads of a tiled sprite.
+const maxSpriteTiles = 4096
+
+// appendQuad adds two triangles with the same layout of the Renderer quad.
+func appendQuad(vertices, uvs []float32, left, bottom, right, top, u0, v0, u1, v1 float32) ([]float32, []float32) {
+	vertices = append(vertices, left, bottom,
+		left, top,
+		right, bottom,
+		right, bottom,
+		right, top,
+		left, top)
+
+	uvs = append(uvs, u0, v1,
+		u0, v0,
+		u1, v1,
+		u1, v1,
+		u1, v0,
+		u0, v0)
+
+	return vertices, uvs
+}
+
+// tiledGeometry repeats the cell (of tileWidth x tileHeight world units) from
+// the bottom left corner, clipping the last row and column.
+func tiledGeometry(width, height, tileWidth, tileHeight float32, uv [4]float32) ([]float32, []float32) {
+	if tileWidth <= 0 || tileHeight <= 0 || (width/tileWidth)*(height/tileHeight) > maxSpriteTiles {
+		return appendQuad(nil, nil, -width/2, -height/2, width/2, height/2, uv[0], uv[1], uv[0]+uv[2], uv[1]+uv[3])
+	}
+
+	var vertices []float32
+	var uvs []float32
+	for y := float32(0); y < height; y += tileHeight {
+		tileTop := y + tileHeight
+		if tileTop > height {
+			tileTop = height
+		}
+		v1 := uv[1] + uv[3]
+		v0 := v1 - uv[3]*(tileTop-y)/tileHeight
+		for x := float32(0); x < width; x += tileWidth {
+			tileRight := x + tileWidth
+			if tileRight > width {
+				tileRight = width
+			}
+			u0 := uv[0]
+			u1 := u0 + uv[2]*(tileRight-x)/tileWidth
+			vertices, uvs = appendQuad(vertices, uvs, x-width/2, y-height/2, tileRight-width/2, tileTop-height/2, u0, v0, u1, v1)
+		}
+	}
+	return vertices, uvs
+}
+
+// slicedGeometry builds a nine-slice: the corners keep their size, the edges
+// stretch along one axis and the center along both. Borders (left, bottom,
+// right, top) are in world units, uvBorder in fractions of the cell; they
+// shrink when the sprite is smaller than them.
+func slicedGeometry(width, height float32, border [4]float32, uvBorder [4]float32, uv [4]float32) ([]float32, []float32) {
+	if border[0]+border[2] > width {
+		scale := width / (border[0] + border[2])
+		border[0] *= scale
+		border[2] *= scale
+	}
+	if border[1]+border[3] > height {
+		scale := height / (border[1] + border[3])
+		border[1] *= scale
+		border[3] *= scale
+	}
+
+	xs := [4]float32{-width / 2, -width/2 + border[0], width/2 - border[2], width / 2}
+	ys := [4]float32{-height / 2, -height/2 + border[1], height/2 - border[3], height / 2}
+	us := [4]float32{uv[0], uv[0] + uv[2]*uvBorder[0], uv[0] + uv[2]*(1-uvBorder[2]), uv[0] + uv[2]}
+	// Texture rows start from the top.
+	vs := [4]float32{uv[1] + uv[3], uv[1] + uv[3]*(1-uvBorder[1]), uv[1] + uv[3]*uvBorder[3], uv[1]}
+
+	var vertices []float32
+	var uvs []float32
+	for row := 0; row < 3; row++ {
+		if ys[row+1] <= ys[row] {
+			continue
+		}
+		for col := 0; col < 3; col++ {
+			if xs[col+1] <= xs[col] {
+				continue
+			}
+			vertices, uvs = appendQuad(vertices, uvs, xs[col], ys[row], xs[col+1], ys[row+1], us[col], vs[row+1], us[col+1], vs[row])
+		}
+	}
+	return vertices, uvs
+}
@@ -0,0 +1,40 @@
+package gozmo
+
+import (
+	"testing"
+)
+
+func TestTiledGeometry(t *testing.T) {
+	// 2.5 x 1 tiles: two whole and a clipped one.
+	vertices, uvs := tiledGeometry(2.5, 1, 1, 1, [4]float32{0, 0, 0.5, 1})
+	if len(vertices) != 3*12 || len(uvs) != 3*12 {
+		t.Fatal("Expected 3 quads, got", len(vertices)/12)
+	}
+	// Right and top edge of the last quad.
+	if vertices[3*12-4] != 1.25 || vertices[3*12-3] != 0.5 {
+		t.Error("Expected 1.25 0.5, got", vertices[3*12-4], vertices[3*12-3])
+	}
+	if uvs[3*12-4] != 0.25 {
+		t.Error("Expected the clipped tile to end at 0.25, got", uvs[3*12-4])
+	}
+}
+
+func TestSlicedGeometry(t *testing.T) {
+	vertices, uvs := slicedGeometry(4, 2, [4]float32{0.5, 0.5, 0.5, 0.5}, [4]float32{0.25, 0.25, 0.25, 0.25}, [4]float32{0, 0, 1, 1})
+	if len(vertices) != 9*12 || len(uvs) != 9*12 {
+		t.Fatal("Expected 9 quads, got", len(vertices)/12)
+	}
+	// The bottom left corner keeps its size.
+	if vertices[8] != -1.5 || vertices[9] != -0.5 {
+		t.Error("Expected -1.5 -0.5, got", vertices[8], vertices[9])
+	}
+	if uvs[8] != 0.25 || uvs[9] != 0.75 {
+		t.Error("Expected 0.25 0.75, got", uvs[8], uvs[9])
+	}
+
+	// Borders shrink on small sprites.
+	vertices, _ = slicedGeometry(0.5, 0.5, [4]float32{0.5, 0.5, 0.5, 0.5}, [4]float32{0.25, 0.25, 0.25, 0.25}, [4]float32{0, 0, 1, 1})
+	if len(vertices) != 4*12 {
+		t.Error("Expected 4 corners, got", len(vertices)/12)
+	}
+}
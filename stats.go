@@ -39,3 +39,29 @@ func UpdatePerFrameStats() {
 		Engine.perFrameStats[key] = 0
 	}
 }
+
+// Global stats are never reset, like the memory used by the resources.
+func checkGlobalStats(name string) {
+	if Engine.globalStats == nil {
+		Engine.globalStats = make(map[string]float64)
+	}
+	_, ok := Engine.globalStats[name]
+	if !ok {
+		Engine.globalStats[name] = 0
+	}
+}
+
+func IncGlobalStats(name string, value float64) {
+	checkGlobalStats(name)
+	Engine.globalStats[name] += value
+}
+
+func DecGlobalStats(name string, value float64) {
+	checkGlobalStats(name)
+	Engine.globalStats[name] -= value
+}
+
+func GetGlobalStats(name string) float64 {
+	checkGlobalStats(name)
+	return Engine.globalStats[name]
+}
@@ -0,0 +1,326 @@
+package gozmo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// The Text component draws a string with a Font of the scene.
+//
+// Lines are aligned around the GameObject position ("align" left, center or
+// right) and wrapped between words to "width" world units. The "text"
+// attribute accepts numbers too, formatted with "format" (e.g. "%06d"), so
+// that a score can be animated or updated from Lua.
+type Text struct {
+	mesh     *Mesh
+	font     *Font
+	fontName string
+
+	text   string
+	format string
+
+	align         TextAlign
+	verticalAlign TextAlign
+	// The wrapping width in world units, 0 disables it.
+	width       float32
+	lineSpacing float32
+	// The height of a line in world units overrides pixelsPerUnit.
+	size          float32
+	pixelsPerUnit float32
+	color         mgl32.Vec4
+
+	// The mesh is rebuilt when the text or its layout change.
+	dirty bool
+}
+
+func NewText(fontName string, text string) *Text {
+	textComponent := Text{fontName: fontName, text: text}
+	textComponent.lineSpacing = 1
+	textComponent.pixelsPerUnit = 100
+	textComponent.color = mgl32.Vec4{1, 1, 1, 1}
+	textComponent.dirty = true
+	return &textComponent
+}
+
+func (text *Text) Start(gameObject *GameObject) {
+}
+
+func (text *Text) Update(gameObject *GameObject) {
+	font := gameObject.Scene.GetFont(text.fontName)
+	if font != text.font {
+		text.font = font
+		text.dirty = true
+	}
+}
+
+func (text *Text) SetText(value string) {
+	if value != text.text {
+		text.text = value
+		text.dirty = true
+	}
+}
+
+func (text *Text) GetText() string {
+	return text.text
+}
+
+// SetNumber shows a number with the "format" attribute.
+func (text *Text) SetNumber(number float32) {
+	text.SetText(formatNumber(text.format, number))
+}
+
+// formatNumber rounds the number for integer verbs (%d, %x...).
+func formatNumber(format string, number float32) string {
+	if format == "" {
+		return strconv.FormatFloat(float64(number), 'f', -1, 32)
+	}
+	if strings.ContainsRune("bcdoqxXU", formatVerb(format)) {
+		return fmt.Sprintf(format, int(math.Floor(float64(number)+0.5)))
+	}
+	return fmt.Sprintf(format, number)
+}
+
+// formatVerb returns the verb of the first directive of a format.
+func formatVerb(format string) rune {
+	directive := false
+	for _, r := range format {
+		if !directive {
+			directive = r == '%'
+			continue
+		}
+		if strings.ContainsRune("+-# 0123456789.", r) {
+			continue
+		}
+		if r != '%' {
+			return r
+		}
+		directive = false
+	}
+	return 0
+}
+
+// scale converts font pixels to world units.
+func (text *Text) scale() float32 {
+	if text.size > 0 && text.font.LineHeight > 0 {
+		return text.size / text.font.LineHeight
+	}
+	return 1 / text.pixelsPerUnit
+}
+
+// buildMesh lays out the text in world units, relatively to the GameObject.
+func (text *Text) buildMesh() {
+	font := text.font
+	scale := text.scale()
+	glyphs, _, height := font.layout(text.text, text.width/scale, text.align, text.lineSpacing)
+
+	var top float32
+	switch text.verticalAlign {
+	case TextMiddle:
+		top = height / 2
+	case TextBottom:
+		top = height
+	}
+
+	textureWidth := float32(font.texture.Width)
+	textureHeight := float32(font.texture.Height)
+
+	mesh := text.mesh
+	mesh.vertices = mesh.vertices[:0]
+	mesh.uvs = mesh.uvs[:0]
+	for _, placed := range glyphs {
+		glyph := placed.glyph
+		left := placed.x * scale
+		right := (placed.x + glyph.Width) * scale
+		glyphTop := (top - placed.y) * scale
+		bottom := (top - placed.y - glyph.Height) * scale
+		mesh.vertices, mesh.uvs = appendQuad(mesh.vertices, mesh.uvs, left, bottom, right, glyphTop,
+			glyph.X/textureWidth, glyph.Y/textureHeight,
+			(glyph.X+glyph.Width)/textureWidth, (glyph.Y+glyph.Height)/textureHeight)
+	}
+	if len(mesh.vertices) > 0 {
+		mesh.upload()
+	}
+	text.dirty = false
+}
+
+func (text *Text) Draw(gameObject *GameObject, camera *Camera) {
+	if text.font == nil || text.font.texture == nil {
+		return
+	}
+
+	if text.mesh == nil {
+		if shader == -1 {
+			shader = int32(GLShader())
+		}
+		mesh := Mesh{}
+		mesh.abid = GLNewArray()
+		mesh.vbid = GLNewBuffer()
+		mesh.uvbid = GLNewBuffer()
+		text.mesh = &mesh
+	}
+
+	if text.dirty {
+		text.buildMesh()
+	}
+	if len(text.mesh.vertices) == 0 {
+		return
+	}
+	text.mesh.mulColor = text.color
+
+	model := mgl32.Translate3D(gameObject.Position[0], gameObject.Position[1], 0)
+
+	model = model.Mul4(mgl32.Scale3D(gameObject.Scale[0], gameObject.Scale[1], 1))
+
+	model = model.Mul4(mgl32.HomogRotate3DZ(gameObject.Rotation))
+
+	ortho := camera.Projection.Mul4(camera.View.Mul4(model))
+
+	IncPerFrameStats("GL.DrawCalls", 1)
+
+	texture := text.font.texture
+	if texture.Options.Premultiplied {
+		setBlendMode(BlendPremultiplied)
+	} else {
+		setBlendMode(BlendAlpha)
+	}
+
+	// The vertices are in world units and the uvs absolute.
+	GLDraw(text.mesh, uint32(shader), 1, 1, int32(texture.tid), 0, 0, 0, 0, ortho)
+}
+
+func castTextAlign(value interface{}, names map[string]TextAlign) (TextAlign, error) {
+	name, ok := value.(string)
+	if ok {
+		align, ok := names[name]
+		if ok {
+			return align, nil
+		}
+	}
+	var expected []string
+	for name := range names {
+		expected = append(expected, name)
+	}
+	sort.Strings(expected)
+	return TextLeft, fmt.Errorf("expects one of %v", strings.Join(expected, ", "))
+}
+
+var textAlignNames = map[string]TextAlign{"left": TextLeft, "center": TextCenter, "right": TextRight}
+
+var textVerticalAlignNames = map[string]TextAlign{"top": TextTop, "middle": TextMiddle, "bottom": TextBottom}
+
+func textAlignName(align TextAlign, names map[string]TextAlign) string {
+	for name, value := range names {
+		if value == align {
+			return name
+		}
+	}
+	return ""
+}
+
+func (text *Text) SetAttr(attr string, value interface{}) error {
+	var err error
+	switch attr {
+	case "text":
+		str, ok := value.(string)
+		if ok {
+			text.SetText(str)
+			return nil
+		}
+		number, err := CastFloat32(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T expects a string or a number", attr, text)
+		}
+		text.SetNumber(number)
+		return nil
+	case "font":
+		fontName, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, text)
+		}
+		text.fontName = fontName
+		return nil
+	case "format":
+		format, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, text)
+		}
+		text.format = format
+		return nil
+	case "align":
+		text.align, err = castTextAlign(value, textAlignNames)
+	case "verticalAlign":
+		text.verticalAlign, err = castTextAlign(value, textVerticalAlignNames)
+	case "width":
+		text.width, err = CastFloat32(value)
+	case "lineSpacing":
+		text.lineSpacing, err = CastFloat32(value)
+	case "size":
+		text.size, err = CastFloat32(value)
+	case "pixelsPerUnit":
+		text.pixelsPerUnit, err = CastFloat32(value)
+	case "color":
+		color, err := castFloat32List(value, 4)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T %v", attr, text, err)
+		}
+		text.color = mgl32.Vec4{color[0], color[1], color[2], color[3]}
+		return nil
+	case "alpha":
+		alpha, err := CastFloat32(value)
+		if err != nil {
+			return fmt.Errorf("%v attribute of %T expects a float32", attr, text)
+		}
+		text.color[3] = alpha
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, text, err)
+	}
+	text.dirty = true
+	return nil
+}
+
+func (text *Text) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "text":
+		return text.text, nil
+	case "font":
+		return text.fontName, nil
+	case "format":
+		return text.format, nil
+	case "align":
+		return textAlignName(text.align, textAlignNames), nil
+	case "verticalAlign":
+		return textAlignName(text.verticalAlign, textVerticalAlignNames), nil
+	case "width":
+		return text.width, nil
+	case "lineSpacing":
+		return text.lineSpacing, nil
+	case "size":
+		return text.size, nil
+	case "pixelsPerUnit":
+		return text.pixelsPerUnit, nil
+	case "color":
+		return text.color, nil
+	case "alpha":
+		return text.color[3], nil
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, text)
+}
+
+func (text *Text) GetType() string {
+	return "Text"
+}
+
+func initText(args []interface{}) Component {
+	return NewText("", "")
+}
+
+func init() {
+	RegisterComponent("Text", initText)
+}
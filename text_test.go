@@ -0,0 +1,89 @@
+package gozmo
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+)
+
+var testBMFont = `info face="Test Font" size=10
+common lineHeight=10 base=8 scaleW=64 scaleH=64 pages=1
+page id=0 file="test font.png"
+chars count=3
+char id=32 x=0 y=0 width=0 height=0 xoffset=0 yoffset=0 xadvance=4 page=0 chnl=15
+char id=65 x=0 y=0 width=6 height=8 xoffset=0 yoffset=1 xadvance=6 page=0 chnl=15
+char id=66 x=6 y=0 width=6 height=8 xoffset=1 yoffset=1 xadvance=6 page=0 chnl=15
+kernings count=1
+kerning first=65 second=66 amount=-1
+`
+
+func TestParseBMFont(t *testing.T) {
+	font, page, err := parseBMFont(strings.NewReader(testBMFont))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page != "test font.png" {
+		t.Error("Expected test font.png, got", page)
+	}
+	glyph, ok := font.Glyph('B')
+	if !ok || glyph.X != 6 || glyph.XOffset != 1 {
+		t.Error("Unexpected glyph", glyph)
+	}
+	if font.Kern('A', 'B') != -1 {
+		t.Error("Expected -1, got", font.Kern('A', 'B'))
+	}
+}
+
+func TestTextLayout(t *testing.T) {
+	font, _, _ := parseBMFont(strings.NewReader(testBMFont))
+
+	// "AB AB" is 6 + 6 - 1 + 4 + 6 + 6 - 1 = 26 pixels wide.
+	glyphs, width, height := font.layout("AB AB", 0, TextCenter, 1)
+	if len(glyphs) != 4 || width != 26 || height != 10 {
+		t.Fatal("Unexpected layout", len(glyphs), width, height)
+	}
+	if glyphs[0].x != -13 || glyphs[1].x != -13+5+1 {
+		t.Error("Expected -13 and -7, got", glyphs[0].x, glyphs[1].x)
+	}
+
+	glyphs, _, height = font.layout("AB AB", 20, TextLeft, 1.5)
+	if height != 25 || glyphs[2].x != 0 || glyphs[2].y != 16 {
+		t.Error("Expected a wrapped second line, got", height, glyphs[2])
+	}
+}
+
+func TestRasterizeFont(t *testing.T) {
+	parsed, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := opentype.NewFace(parsed, &opentype.FaceOptions{Size: 16, DPI: 72})
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, atlas := rasterizeFont(face, "Hello")
+	if len(font.glyphs) != 4 {
+		t.Error("Expected 4 glyphs, got", len(font.glyphs))
+	}
+	glyph, _ := font.Glyph('H')
+	_, _, _, alpha := atlas.At(int(glyph.X+glyph.Width/2), int(glyph.Y+glyph.Height/2)).RGBA()
+	if alpha == 0 {
+		t.Error("Expected the H to be drawn in the atlas")
+	}
+}
+
+func TestTextNumbers(t *testing.T) {
+	text := NewText("", "")
+	text.SetAttr("format", "Score: %05d")
+	text.SetAttr("text", float32(41.6))
+	if text.GetText() != "Score: 00042" {
+		t.Error("Expected Score: 00042, got", text.GetText())
+	}
+	text.SetAttr("format", "%.1f%%")
+	text.SetAttr("text", 0.25)
+	if text.GetText() != "0.2%" && text.GetText() != "0.3%" {
+		t.Error("Expected 0.2%, got", text.GetText())
+	}
+}
@@ -1,6 +1,7 @@
 package gozmo
 
 import (
+	"fmt"
 	"image"
 	"image/draw"
 	_ "image/gif"
@@ -9,6 +10,31 @@ import (
 	"os"
 )
 
+type TextureFilter int
+
+const (
+	TextureLinear TextureFilter = iota
+	TextureNearest
+)
+
+type TextureWrap int
+
+const (
+	TextureClamp TextureWrap = iota
+	TextureRepeat
+	TextureMirror
+)
+
+// TextureOptions control how a texture is sampled. Premultiplied textures
+// keep the colors multiplied by alpha, they are drawn with the premultiplied
+// blend mode by default.
+type TextureOptions struct {
+	Filter        TextureFilter
+	Wrap          TextureWrap
+	Mipmaps       bool
+	Premultiplied bool
+}
+
 type Texture struct {
 	tid    uint32
 	Name   string
@@ -16,35 +42,108 @@ type Texture struct {
 	Height uint32
 	Rows   uint32
 	Cols   uint32
+
+	// Render textures are attached to a framebuffer.
+	framebuffer uint32
+	// Textures loaded from files are shared through the ResourceCache.
+	resource *textureResource
+
+	Options TextureOptions
 }
 
-func (scene *Scene) NewTextureFromFilename(name string, fileName string) (*Texture, error) {
-	imgFile, err := os.Open(fileName)
+// Options are optional, the default is linear filtering, clamping and
+// straight alpha.
+// The texture is shared with the other scenes loading the same file with the
+// same options.
+func (scene *Scene) NewTextureFromFilename(name string, fileName string, options ...TextureOptions) (*Texture, error) {
+	var textureOptions TextureOptions
+	if len(options) > 0 {
+		textureOptions = options[0]
+	}
+	tex, err := resourceCache.acquireTexture(name, fileName, textureOptions, func() (decodedImage, error) {
+		return decodeImageFile(fileName, textureOptions.Premultiplied)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return scene.NewTextureFromFile(name, imgFile)
+	scene.textures[name] = tex
+	return tex, nil
 }
 
-func (scene *Scene) NewTextureFromFile(name string, file *os.File) (*Texture, error) {
+func (scene *Scene) NewTextureFromFile(name string, file *os.File, options ...TextureOptions) (*Texture, error) {
 	img, _, err := image.Decode(file)
 	if err != nil {
 		return nil, err
 	}
+	return scene.NewTextureFromImage(name, img, options...), nil
+}
 
-	rgba := image.NewRGBA(img.Bounds())
-	draw.Draw(rgba, rgba.Bounds(), img, image.Point{0, 0}, draw.Src)
+// NewTextureFromImage uploads an image, it is used for generated textures.
+func (scene *Scene) NewTextureFromImage(name string, img image.Image, options ...TextureOptions) *Texture {
+	tex := newTextureFromImage(name, img, options...)
+	scene.textures[name] = tex
+	return tex
+}
+
+// newTextureFromImage does not add the texture to a scene.
+func newTextureFromImage(name string, img image.Image, options ...TextureOptions) *Texture {
+	var textureOptions TextureOptions
+	if len(options) > 0 {
+		textureOptions = options[0]
+	}
+	return newTextureFromPixels(name, imagePixels(img, textureOptions.Premultiplied), textureOptions)
+}
+
+// decodedImage holds the pixels of an image, ready for the upload.
+type decodedImage struct {
+	pixels []uint8
+	width  int
+	height int
+}
+
+// imagePixels converts an image to RGBA rows, without OpenGL so that it can
+// run on any goroutine.
+func imagePixels(img image.Image, premultiplied bool) decodedImage {
+	// image.RGBA is premultiplied, image.NRGBA is not.
+	var pixels []uint8
+	if premultiplied {
+		rgba := image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		pixels = rgba.Pix
+	} else {
+		nrgba := image.NewNRGBA(img.Bounds())
+		draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+		pixels = nrgba.Pix
+	}
+	size := img.Bounds().Size()
+	return decodedImage{pixels: pixels, width: size.X, height: size.Y}
+}
+
+func decodeImageFile(fileName string, premultiplied bool) (decodedImage, error) {
+	file, err := fileSystem.Open(fileName)
+	if err != nil {
+		return decodedImage{}, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return decodedImage{}, fmt.Errorf("texture %v %v", fileName, err)
+	}
+	return imagePixels(img, premultiplied), nil
+}
 
-	tid := GLTexture(rgba)
+func newTextureFromPixels(name string, decoded decodedImage, options TextureOptions) *Texture {
+	tid := GLTexturePixels(decoded.pixels, int32(decoded.width), int32(decoded.height))
+	countTexture(uint32(decoded.width), uint32(decoded.height), 1)
 
-	tex := Texture{tid: tid, Name: name, Width: uint32(rgba.Bounds().Size().X), Height: uint32(rgba.Bounds().Size().Y)}
+	tex := Texture{tid: tid, Name: name, Width: uint32(decoded.width), Height: uint32(decoded.height)}
 
 	tex.Rows = 1
 	tex.Cols = 1
 
-	scene.textures[name] = &tex
+	tex.SetOptions(options)
 
-	return &tex, nil
+	return &tex
 }
 
 func (scene *Scene) NewTexture(name string, width uint32, height uint32) {
@@ -63,6 +162,99 @@ func (texture *Texture) SetRowsCols(rows, cols uint32) {
 	texture.Cols = cols
 }
 
+func (texture *Texture) SetOptions(options TextureOptions) {
+	texture.Options = options
+	GLTextureFilter(texture.tid, options.Filter, options.Mipmaps)
+	GLTextureWrap(texture.tid, options.Wrap)
+}
+
+func (texture *Texture) SetFilter(filter TextureFilter) {
+	texture.Options.Filter = filter
+	GLTextureFilter(texture.tid, filter, texture.Options.Mipmaps)
+}
+
+func (texture *Texture) SetWrap(wrap TextureWrap) {
+	texture.Options.Wrap = wrap
+	GLTextureWrap(texture.tid, wrap)
+}
+
+// ParseTextureOptions reads the "filter" ("linear", "nearest"), "wrap"
+// ("clamp", "repeat", "mirror"), "mipmaps" and "premultiplied" keys of a
+// scene texture entry.
+func ParseTextureOptions(options map[string]interface{}) (TextureOptions, error) {
+	var textureOptions TextureOptions
+
+	filter, ok := options["filter"]
+	if ok {
+		switch filter {
+		case "linear":
+			textureOptions.Filter = TextureLinear
+		case "nearest":
+			textureOptions.Filter = TextureNearest
+		default:
+			return textureOptions, fmt.Errorf("unknown texture filter %v", filter)
+		}
+	}
+
+	wrap, ok := options["wrap"]
+	if ok {
+		switch wrap {
+		case "clamp":
+			textureOptions.Wrap = TextureClamp
+		case "repeat":
+			textureOptions.Wrap = TextureRepeat
+		case "mirror":
+			textureOptions.Wrap = TextureMirror
+		default:
+			return textureOptions, fmt.Errorf("unknown texture wrap %v", wrap)
+		}
+	}
+
+	mipmaps, ok := options["mipmaps"]
+	if ok {
+		flag, err := CastBool(mipmaps)
+		if err != nil {
+			return textureOptions, fmt.Errorf("texture mipmaps %v", err)
+		}
+		textureOptions.Mipmaps = flag
+	}
+
+	premultiplied, ok := options["premultiplied"]
+	if ok {
+		flag, err := CastBool(premultiplied)
+		if err != nil {
+			return textureOptions, fmt.Errorf("texture premultiplied %v", err)
+		}
+		textureOptions.Premultiplied = flag
+	}
+
+	return textureOptions, nil
+}
+
+// Destroy frees the texture from the GPU, or releases it when it is shared.
 func (texture *Texture) Destroy() {
-	// TODO: delete the texture from the GPU.
+	switch {
+	case texture.resource != nil:
+		resourceCache.releaseTexture(texture.resource)
+	case texture.framebuffer != 0:
+		GLDeleteFramebuffer(texture.framebuffer, texture.tid)
+		countTexture(texture.Width, texture.Height, -1)
+	case texture.tid != 0:
+		deleteTexture(texture.tid, texture.Width, texture.Height)
+	}
+	texture.resource = nil
+	texture.tid = 0
+	texture.framebuffer = 0
+}
+
+func deleteTexture(tid uint32, width, height uint32) {
+	GLDeleteTexture(tid)
+	countTexture(width, height, -1)
+}
+
+// countTexture updates the memory stats of the textures, with the size of the
+// base level.
+func countTexture(width, height uint32, sign float64) {
+	IncGlobalStats("Textures.Count", sign)
+	IncGlobalStats("Textures.Bytes", sign*float64(width)*float64(height)*4)
 }
@@ -0,0 +1,54 @@
+package gozmo
+
+import (
+	"testing"
+)
+
+func TestParseTextureOptions(t *testing.T) {
+	options, err := ParseTextureOptions(map[string]interface{}{"filter": "nearest", "wrap": "repeat", "mipmaps": true, "premultiplied": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := TextureOptions{Filter: TextureNearest, Wrap: TextureRepeat, Mipmaps: true, Premultiplied: true}
+	if options != expected {
+		t.Errorf("unexpected options %v", options)
+	}
+
+	_, err = ParseTextureOptions(map[string]interface{}{"wrap": "tile"})
+	if err == nil {
+		t.Error("unknown wrap mode accepted")
+	}
+}
+
+func TestRendererBlendMode(t *testing.T) {
+	renderer := NewRenderer(nil)
+	renderer.texture = &Texture{Options: TextureOptions{Premultiplied: true}}
+	if renderer.BlendMode() != BlendPremultiplied {
+		t.Errorf("premultiplied texture drawn with %v", renderer.BlendMode())
+	}
+
+	err := renderer.SetAttr("blend", "additive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blend, _ := renderer.GetAttr("blend")
+	if blend != "additive" {
+		t.Errorf("unexpected blend mode %v", blend)
+	}
+
+	err = renderer.SetAttr("blend", "screen")
+	if err == nil {
+		t.Error("unknown blend mode accepted")
+	}
+}
+
+func TestPremultiplyFor(t *testing.T) {
+	straight := &Texture{}
+	premultiplied := &Texture{Options: TextureOptions{Premultiplied: true}}
+	if premultiplyFor(BlendMultiply, straight) != 1 || premultiplyFor(BlendMultiply, nil) != 1 {
+		t.Error("Expected straight colors to be premultiplied for multiply")
+	}
+	if premultiplyFor(BlendMultiply, premultiplied) != 0 || premultiplyFor(BlendAlpha, straight) != 0 {
+		t.Error("Expected the colors to be kept")
+	}
+}
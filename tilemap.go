@@ -2,8 +2,7 @@ package gozmo
 
 import (
 	"encoding/csv"
-	_ "fmt"
-	"os"
+	"fmt"
 	"strconv"
 
 	"github.com/go-gl/mathgl/mgl32"
@@ -17,6 +16,8 @@ type TileMap struct {
 	pixelsPerUnit uint32
 
 	data [][]int32
+
+	material materialRef
 }
 
 func NewTileMap(texture *Texture) *TileMap {
@@ -26,7 +27,7 @@ func NewTileMap(texture *Texture) *TileMap {
 }
 
 func NewTileMapFromCSVFilename(fileName string, texture *Texture) *TileMap {
-	csvfile, err := os.Open(fileName)
+	csvfile, err := fileSystem.Open(fileName)
 	if err != nil {
 		panic(err)
 	}
@@ -134,6 +135,10 @@ func (tilemap *TileMap) Start(gameObject *GameObject) {
 }
 
 func (tilemap *TileMap) Update(gameObject *GameObject) {
+	tilemap.material.resolve(gameObject.Scene)
+}
+
+func (tilemap *TileMap) Draw(gameObject *GameObject, camera *Camera) {
 
 	texture := tilemap.texture
 
@@ -167,25 +172,66 @@ func (tilemap *TileMap) Update(gameObject *GameObject) {
 
 	model = model.Mul4(mgl32.HomogRotate3DZ(gameObject.Rotation))
 
-	view := Engine.Window.View.Mul4(model)
+	view := camera.View.Mul4(model)
 
-	ortho := Engine.Window.Projection.Mul4(view)
+	ortho := camera.Projection.Mul4(view)
 
 	IncPerFrameStats("GL.DrawCalls", 1)
 
+	setBlendMode(BlendAlpha)
+
+	material := tilemap.material.material
+	if material != nil && material.draw(gameObject.Scene, tilemap.mesh, texture.tid, mgl32.Vec2{width, height}, mgl32.Vec4{uvx, uvy, uvw, uvh}, ortho, tilemap.material.uniforms) {
+		return
+	}
 	GLDraw(tilemap.mesh, uint32(shader), width, height, int32(texture.tid), uvx, uvy, uvw, uvh, ortho)
 }
 
+// Bounds returns the world area (minX, minY, maxX, maxY) covered by the tiles
+// of the map attached to gameObject, rotation is not considered.
+func (tilemap *TileMap) Bounds(gameObject *GameObject) [4]float32 {
+	cols := 0
+	for _, row := range tilemap.data {
+		if len(row) > cols {
+			cols = len(row)
+		}
+	}
+	rows := len(tilemap.data)
+
+	// Tiles are 2 units wide, the first one is centered on the origin.
+	minX := -1 * gameObject.Scale[0]
+	maxX := float32(2*cols-1) * gameObject.Scale[0]
+	minY := float32(1-2*rows) * gameObject.Scale[1]
+	maxY := 1 * gameObject.Scale[1]
+	return [4]float32{gameObject.Position[0] + minX, gameObject.Position[1] + minY, gameObject.Position[0] + maxX, gameObject.Position[1] + maxY}
+}
+
+// The whole map is a single mesh, its outline is the only chunk.
+func (tilemap *TileMap) DebugOutline(gameObject *GameObject) {
+	if !DebugOutlines(DebugTileMaps) {
+		return
+	}
+	bounds := tilemap.Bounds(gameObject)
+	DebugRect(bounds[0], bounds[1], bounds[2], bounds[3], mgl32.Vec4{1, 1, 0, 1}, 0)
+}
+
 func (tilemap *TileMap) SetPixelsPerUnit(pixels uint32) {
 	tilemap.pixelsPerUnit = pixels
 }
 
+// The "material" and "material.uniform" attributes work like the Renderer
+// ones.
 func (tilemap *TileMap) SetAttr(attr string, value interface{}) error {
+	_, err := tilemap.material.setAttr(attr, value)
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, tilemap, err)
+	}
 	return nil
 }
 
 func (tilemap *TileMap) GetAttr(attr string) (interface{}, error) {
-	return nil, nil
+	value, _ := tilemap.material.getAttr(attr)
+	return value, nil
 }
 
 func (tilemap *TileMap) GetType() string {
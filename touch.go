@@ -0,0 +1,373 @@
+package gozmo
+
+import (
+	"fmt"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Touches are fed by the platform window (x/mobile touch events on Android)
+// and latched at the start of every frame. Each finger also behaves like a
+// mouse cursor, raising "mouseEnter", "mouseDown", "mouseUp" and "mouseExit"
+// events (with the finger as PointerEvent.Id) on the GameObjects whose HitBox
+// contains it, so that the same components work on desktop and mobile.
+
+// The order matches the x/mobile touch types.
+type TouchPhase int
+
+const (
+	TouchBegin TouchPhase = iota
+	TouchMove
+	TouchEnd
+)
+
+// A Finger is an active touch, positions are in world coordinates.
+type Finger struct {
+	Id            int
+	Phase         TouchPhase
+	Position      mgl32.Vec2
+	StartPosition mgl32.Vec2
+	// Engine time, in seconds.
+	StartTime float64
+
+	moved   bool
+	pointer pointer
+}
+
+// Gesture thresholds, distances are in world units and times in seconds.
+var (
+	TouchTapDistance   float32 = 0.3
+	TouchTapTime               = 0.3
+	TouchSwipeDistance float32 = 1
+	TouchSwipeTime             = 0.5
+)
+
+// A GestureEvent is the Data of the "tap", "swipe" and "pinch" events.
+type GestureEvent struct {
+	Position mgl32.Vec2
+	// The movement of a swipe.
+	Delta mgl32.Vec2
+	// The distance ratio between the two fingers of a pinch, relative to the
+	// previous frame.
+	Scale float32
+}
+
+type rawTouch struct {
+	id    int
+	x     float32
+	y     float32
+	phase TouchPhase
+	when  float64
+}
+
+type touchState struct {
+	fingers map[int]*Finger
+	// Fingers in touch order, for deterministic iteration.
+	order []int
+
+	began []*Finger
+	ended []*Finger
+
+	taps    []*GestureEvent
+	swipes  []*GestureEvent
+	pinch   *GestureEvent
+	spacing float32
+
+	pending []rawTouch
+
+	components []*Touch
+}
+
+var touchInput = touchState{fingers: make(map[int]*Finger)}
+
+func touchEvent(id int, x, y float32, phase TouchPhase, when float64) {
+	touchInput.pending = append(touchInput.pending, rawTouch{id: id, x: x, y: y, phase: phase, when: when})
+}
+
+func screenToWorld(x, y float32) mgl32.Vec2 {
+	if Engine.Window == nil {
+		return mgl32.Vec2{x, y}
+	}
+	return Engine.Window.ScreenToWorld(float64(x), float64(y))
+}
+
+func (state *touchState) fingerList() []*Finger {
+	var fingers []*Finger
+	for _, id := range state.order {
+		fingers = append(fingers, state.fingers[id])
+	}
+	return fingers
+}
+
+func (state *touchState) apply(raw rawTouch) {
+	position := screenToWorld(raw.x, raw.y)
+	finger, ok := state.fingers[raw.id]
+
+	switch raw.phase {
+	case TouchBegin:
+		finger = &Finger{Id: raw.id, Position: position, StartPosition: position, StartTime: raw.when}
+		finger.pointer = pointer{prefix: "mouse", id: raw.id}
+		state.fingers[raw.id] = finger
+		state.order = append(state.order, raw.id)
+		state.began = append(state.began, finger)
+		finger.pointer.move(position)
+		finger.pointer.press(raw.id, position)
+	case TouchMove:
+		if !ok {
+			return
+		}
+		finger.Phase = TouchMove
+		finger.Position = position
+		finger.moved = true
+		finger.pointer.move(position)
+	case TouchEnd:
+		if !ok {
+			return
+		}
+		finger.Phase = TouchEnd
+		finger.Position = position
+		finger.pointer.release(raw.id, position)
+		finger.pointer.leave(position)
+		state.ended = append(state.ended, finger)
+		delete(state.fingers, raw.id)
+		for i, id := range state.order {
+			if id == raw.id {
+				state.order = append(state.order[:i], state.order[i+1:]...)
+				break
+			}
+		}
+		state.recognize(finger, raw.when)
+	}
+}
+
+// recognize checks for taps and swipes when a finger is lifted.
+func (state *touchState) recognize(finger *Finger, when float64) {
+	delta := finger.Position.Sub(finger.StartPosition)
+	elapsed := when - finger.StartTime
+	if delta.Len() <= TouchTapDistance && elapsed <= TouchTapTime {
+		state.taps = append(state.taps, &GestureEvent{Position: finger.Position, Scale: 1})
+		return
+	}
+	if delta.Len() >= TouchSwipeDistance && elapsed <= TouchSwipeTime {
+		state.swipes = append(state.swipes, &GestureEvent{Position: finger.StartPosition, Delta: delta, Scale: 1})
+	}
+}
+
+// updateTouches latches the touches received since the last frame, and
+// raises the touch and gesture events on GameObjects with a Touch component.
+func updateTouches(frame *InputFrame) {
+	for _, touch := range frame.Touches {
+		touchEvent(touch.Id, touch.X, touch.Y, touch.Phase, frame.Time)
+	}
+
+	state := &touchInput
+	state.began = nil
+	state.ended = nil
+	state.taps = nil
+	state.swipes = nil
+	state.pinch = nil
+
+	for _, finger := range state.fingers {
+		finger.moved = false
+	}
+
+	for _, raw := range state.pending {
+		state.apply(raw)
+	}
+	state.pending = nil
+
+	for _, finger := range state.fingers {
+		if finger.Phase == TouchBegin && !containsFinger(state.began, finger) {
+			finger.Phase = TouchMove
+		}
+	}
+
+	// Pinches are tracked between the first two fingers.
+	fingers := state.fingerList()
+	if len(fingers) >= 2 {
+		spacing := fingers[0].Position.Sub(fingers[1].Position).Len()
+		if state.spacing > 0 && spacing > 0 && spacing != state.spacing {
+			center := fingers[0].Position.Add(fingers[1].Position).Mul(0.5)
+			state.pinch = &GestureEvent{Position: center, Scale: spacing / state.spacing}
+		}
+		state.spacing = spacing
+	} else {
+		state.spacing = 0
+	}
+
+	for _, touch := range state.components {
+		gameObject := touch.gameObject
+		if !gameObject.enabled {
+			continue
+		}
+		for _, finger := range state.began {
+			gameObject.EnqueueEventWithData(nil, "touchBegin", finger)
+		}
+		for _, finger := range fingers {
+			if finger.moved {
+				gameObject.EnqueueEventWithData(nil, "touchMove", finger)
+			}
+		}
+		for _, finger := range state.ended {
+			gameObject.EnqueueEventWithData(nil, "touchEnd", finger)
+		}
+		for _, tap := range state.taps {
+			gameObject.EnqueueEventWithData(nil, "tap", tap)
+		}
+		for _, swipe := range state.swipes {
+			gameObject.EnqueueEventWithData(nil, "swipe", swipe)
+		}
+		if state.pinch != nil {
+			gameObject.EnqueueEventWithData(nil, "pinch", state.pinch)
+		}
+	}
+}
+
+func containsFinger(fingers []*Finger, finger *Finger) bool {
+	for _, f := range fingers {
+		if f == finger {
+			return true
+		}
+	}
+	return false
+}
+
+// The Touch component reports the active fingers and the gestures of the
+// current frame, and enqueues "touchBegin", "touchMove", "touchEnd" (with a
+// *Finger as Data), "tap", "swipe" and "pinch" (with a *GestureEvent as Data)
+// events on its GameObject.
+type Touch struct {
+	gameObject *GameObject
+}
+
+func (touch *Touch) Start(gameObject *GameObject) {
+	touch.gameObject = gameObject
+	touchInput.components = append(touchInput.components, touch)
+}
+
+func (touch *Touch) Update(gameObject *GameObject) {}
+
+func (touch *Touch) Destroy(gameObject *GameObject) {
+	for i, t := range touchInput.components {
+		if t == touch {
+			touchInput.components = append(touchInput.components[:i], touchInput.components[i+1:]...)
+			return
+		}
+	}
+}
+
+// Fingers returns the active touches, in touch order.
+func (touch *Touch) Fingers() []*Finger {
+	return touchInput.fingerList()
+}
+
+// Began returns the fingers touching the screen since this frame.
+func (touch *Touch) Began() []*Finger {
+	return touchInput.began
+}
+
+// Ended returns the fingers lifted in this frame.
+func (touch *Touch) Ended() []*Finger {
+	return touchInput.ended
+}
+
+func (touch *Touch) Taps() []*GestureEvent {
+	return touchInput.taps
+}
+
+func (touch *Touch) Swipes() []*GestureEvent {
+	return touchInput.swipes
+}
+
+// Pinch returns the pinch of this frame, or nil.
+func (touch *Touch) Pinch() *GestureEvent {
+	return touchInput.pinch
+}
+
+func (touch *Touch) SetAttr(attr string, value interface{}) error {
+	return nil
+}
+
+// Attributes are "count", "x" and "y" (of the first finger), "began",
+// "ended", "tap" (booleans for this frame), "swipeX", "swipeY" and "pinch".
+func (touch *Touch) GetAttr(attr string) (interface{}, error) {
+	fingers := touch.Fingers()
+	switch attr {
+	case "count":
+		return float32(len(fingers)), nil
+	case "x", "y":
+		if len(fingers) == 0 {
+			return float32(0), nil
+		}
+		if attr == "x" {
+			return fingers[0].Position[0], nil
+		}
+		return fingers[0].Position[1], nil
+	case "began":
+		return len(touchInput.began) > 0, nil
+	case "ended":
+		return len(touchInput.ended) > 0, nil
+	case "tap":
+		return len(touchInput.taps) > 0, nil
+	case "swipeX", "swipeY":
+		if len(touchInput.swipes) == 0 {
+			return float32(0), nil
+		}
+		if attr == "swipeX" {
+			return touchInput.swipes[0].Delta[0], nil
+		}
+		return touchInput.swipes[0].Delta[1], nil
+	case "pinch":
+		if touchInput.pinch == nil {
+			return float32(1), nil
+		}
+		return touchInput.pinch.Scale, nil
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, touch)
+}
+
+func (touch *Touch) GetType() string {
+	return "Touch"
+}
+
+func NewTouch() *Touch {
+	touch := Touch{}
+	return &touch
+}
+
+func initTouch(args []interface{}) Component {
+	return NewTouch()
+}
+
+// readTouchInput is the "touch" input device, codes are "Touch" (any finger
+// down), "Tap", "SwipeLeft", "SwipeRight", "SwipeUp" and "SwipeDown".
+func readTouchInput(code string) float32 {
+	switch code {
+	case "Touch":
+		if len(touchInput.fingers) > 0 {
+			return 1
+		}
+	case "Tap":
+		if len(touchInput.taps) > 0 {
+			return 1
+		}
+	case "SwipeLeft", "SwipeRight", "SwipeUp", "SwipeDown":
+		for _, swipe := range touchInput.swipes {
+			x, y := swipe.Delta[0], swipe.Delta[1]
+			horizontal := x*x > y*y
+			if (code == "SwipeLeft" && horizontal && x < 0) ||
+				(code == "SwipeRight" && horizontal && x > 0) ||
+				(code == "SwipeUp" && !horizontal && y > 0) ||
+				(code == "SwipeDown" && !horizontal && y < 0) {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+func init() {
+	RegisterComponent("Touch", initTouch)
+	RegisterInputDevice("touch", readTouchInput)
+	registerInputUpdater(updateTouches)
+}
@@ -0,0 +1,48 @@
+package gozmo
+
+import (
+	"testing"
+)
+
+func TestTouchGestures(t *testing.T) {
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("Object")
+	touch := NewTouch()
+	gameObject.AddComponent("touch", touch)
+	defer gameObject.Destroy()
+
+	touchEvent(1, 0, 0, TouchBegin, 0)
+	updateTouches(&InputFrame{})
+	if len(touch.Fingers()) != 1 || len(touch.Began()) != 1 {
+		t.Error("Expected 1 finger, got", len(touch.Fingers()))
+	}
+
+	touchEvent(1, 0.1, 0, TouchEnd, 0)
+	updateTouches(&InputFrame{})
+	if len(touch.Taps()) != 1 {
+		t.Error("Expected 1 tap, got", len(touch.Taps()))
+	}
+
+	touchEvent(2, 0, 0, TouchBegin, 0)
+	touchEvent(2, 3, 0, TouchMove, 0)
+	touchEvent(2, 5, 0, TouchEnd, 0)
+	updateTouches(&InputFrame{})
+	if len(touch.Swipes()) != 1 || touch.Swipes()[0].Delta[0] != 5 {
+		t.Error("Expected a swipe of 5, got", touch.Swipes())
+	}
+	if readTouchInput("SwipeRight") != 1 {
+		t.Error("Expected 1, got", readTouchInput("SwipeRight"))
+	}
+
+	touchEvent(3, 0, 0, TouchBegin, 0)
+	touchEvent(4, 2, 0, TouchBegin, 0)
+	updateTouches(&InputFrame{})
+	touchEvent(4, 4, 0, TouchMove, 0)
+	updateTouches(&InputFrame{})
+	if touch.Pinch() == nil || touch.Pinch().Scale != 2 {
+		t.Error("Expected a pinch of 2, got", touch.Pinch())
+	}
+	touchEvent(3, 0, 0, TouchEnd, 0)
+	touchEvent(4, 4, 0, TouchEnd, 0)
+	updateTouches(&InputFrame{})
+}
@@ -0,0 +1,1223 @@
+package gozmo
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// A Canvas draws a tree of widgets in screen space, over the cameras and the
+// post processing. Canvas units are window pixels divided by a scale, which
+// fits the "referenceResolution" to the window depending on "scaleMode"
+// (none, fit, width or height). 0, 0 is the top left corner and Y grows
+// downward.
+//
+// Widgets are declared in the "widgets" attribute as a list of objects with
+// a "type", an optional "name" and "children", the other keys being widget
+// attributes. A widget is placed in its parent by anchors: on an axis where
+// the two anchors match, "position" moves the "pivot" of the widget from the
+// anchor and "size" is fixed; on a stretched axis the widget follows the
+// parent, inset by "margins" (left, top, right, bottom).
+//
+// Pressing a widget, or submitting the focused one with Enter or Space,
+// enqueues its "event" on the canvas GameObject (or on the "target" one)
+// with a *UIEvent as Data. Presses over the UI and the keys used to
+// navigate it are removed from the input frame, so that Mouse, Touch and
+// Keyboard components do not see them.
+
+type UIEvent struct {
+	Canvas *Canvas
+	Widget string
+	// The new value of sliders (float32), toggles (bool) and lists (the
+	// selected index), nil for buttons.
+	Value interface{}
+}
+
+type UIScaleMode int
+
+const (
+	UIScaleNone UIScaleMode = iota
+	UIScaleFit
+	UIScaleWidth
+	UIScaleHeight
+)
+
+var uiScaleModeNames = map[string]UIScaleMode{
+	"none":   UIScaleNone,
+	"fit":    UIScaleFit,
+	"width":  UIScaleWidth,
+	"height": UIScaleHeight,
+}
+
+func (mode UIScaleMode) String() string {
+	for name, value := range uiScaleModeNames {
+		if value == mode {
+			return name
+		}
+	}
+	return ""
+}
+
+// Anchor presets, as anchorMin and anchorMax.
+var uiAnchorPresets = map[string][4]float32{
+	"topLeft":       {0, 0, 0, 0},
+	"top":           {0.5, 0, 0.5, 0},
+	"topRight":      {1, 0, 1, 0},
+	"left":          {0, 0.5, 0, 0.5},
+	"center":        {0.5, 0.5, 0.5, 0.5},
+	"right":         {1, 0.5, 1, 0.5},
+	"bottomLeft":    {0, 1, 0, 1},
+	"bottom":        {0.5, 1, 0.5, 1},
+	"bottomRight":   {1, 1, 1, 1},
+	"stretchTop":    {0, 0, 1, 0},
+	"stretchBottom": {0, 1, 1, 1},
+	"stretchLeft":   {0, 0, 0, 1},
+	"stretchRight":  {1, 0, 1, 1},
+	"stretch":       {0, 0, 1, 1},
+}
+
+type uiRect struct {
+	x, y, w, h float32
+}
+
+func (rect uiRect) contains(x, y float32) bool {
+	return x >= rect.x && x <= rect.x+rect.w && y >= rect.y && y <= rect.y+rect.h
+}
+
+func (rect uiRect) center() mgl32.Vec2 {
+	return mgl32.Vec2{rect.x + rect.w/2, rect.y + rect.h/2}
+}
+
+// The behaviour of each widget type.
+type uiControl interface {
+	setAttr(widget *Widget, attr string, value interface{}) (bool, error)
+	getAttr(widget *Widget, attr string) (interface{}, bool)
+	draw(widget *Widget, batch *uiBatch)
+	// Interactive controls receive presses and can be focused.
+	interactive() bool
+	press(widget *Widget, x, y float32)
+	drag(widget *Widget, x, y float32)
+	release(widget *Widget, inside bool)
+	submit(widget *Widget)
+	// key handles an arrow key, returning false to move the focus instead.
+	key(widget *Widget, key Key) bool
+	scroll(widget *Widget, amount float32) bool
+}
+
+// uiStatic is embedded by the controls ignoring input.
+type uiStatic struct{}
+
+func (uiStatic) interactive() bool                          { return false }
+func (uiStatic) press(widget *Widget, x, y float32)         {}
+func (uiStatic) drag(widget *Widget, x, y float32)          {}
+func (uiStatic) release(widget *Widget, inside bool)        {}
+func (uiStatic) submit(widget *Widget)                      {}
+func (uiStatic) key(widget *Widget, key Key) bool           { return false }
+func (uiStatic) scroll(widget *Widget, amount float32) bool { return false }
+
+// Constructors of the widget types can change the defaults of the widget.
+var uiWidgetTypes = map[string]func(widget *Widget) uiControl{}
+
+func registerWidgetType(name string, constructor func(widget *Widget) uiControl) {
+	uiWidgetTypes[name] = constructor
+}
+
+type Widget struct {
+	Name     string
+	typeName string
+	canvas   *Canvas
+	parent   *Widget
+	children []*Widget
+	control  uiControl
+
+	visible      bool
+	interactable bool
+	// Widgets without raycast (like labels) let the pointer through.
+	raycast bool
+
+	anchorMin mgl32.Vec2
+	anchorMax mgl32.Vec2
+	position  mgl32.Vec2
+	size      mgl32.Vec2
+	pivot     mgl32.Vec2
+	margins   [4]float32
+
+	color  mgl32.Vec4
+	event  string
+	target string
+
+	// Computed by the layout, in canvas units.
+	rect    uiRect
+	hovered bool
+	pressed bool
+}
+
+// NewWidget creates a widget of a type (panel, image, label, button, slider,
+// toggle, list), centered in its parent.
+func NewWidget(typeName string, name string) (*Widget, error) {
+	constructor, ok := uiWidgetTypes[typeName]
+	if !ok {
+		return nil, fmt.Errorf("unknown widget type %v", typeName)
+	}
+	widget := Widget{Name: name, typeName: typeName}
+	widget.visible = true
+	widget.interactable = true
+	widget.raycast = true
+	widget.anchorMin = mgl32.Vec2{0.5, 0.5}
+	widget.anchorMax = mgl32.Vec2{0.5, 0.5}
+	widget.pivot = mgl32.Vec2{0.5, 0.5}
+	widget.size = mgl32.Vec2{100, 30}
+	widget.color = mgl32.Vec4{1, 1, 1, 1}
+	widget.control = constructor(&widget)
+	return &widget, nil
+}
+
+func (widget *Widget) Type() string {
+	return widget.typeName
+}
+
+func (widget *Widget) Parent() *Widget {
+	return widget.parent
+}
+
+func (widget *Widget) Children() []*Widget {
+	return widget.children
+}
+
+// Visible reports whether the widget and all of its parents are visible.
+func (widget *Widget) Visible() bool {
+	for w := widget; w != nil; w = w.parent {
+		if !w.visible {
+			return false
+		}
+	}
+	return true
+}
+
+func (widget *Widget) SetVisible(visible bool) {
+	widget.visible = visible
+}
+
+// Rect returns the position and size of the widget, in canvas units.
+func (widget *Widget) Rect() (x, y, width, height float32) {
+	return widget.rect.x, widget.rect.y, widget.rect.w, widget.rect.h
+}
+
+func (widget *Widget) focusable() bool {
+	return widget.control.interactive() && widget.interactable && widget.Visible()
+}
+
+// layoutAxis places a widget on an axis of its parent.
+func layoutAxis(start, length, anchorMin, anchorMax, position, size, pivot, marginMin, marginMax float32) (float32, float32) {
+	if anchorMin == anchorMax {
+		return start + anchorMin*length + position - pivot*size, size
+	}
+	min := start + anchorMin*length + marginMin
+	max := start + anchorMax*length - marginMax
+	if max < min {
+		max = min
+	}
+	return min, max - min
+}
+
+func (widget *Widget) layout(parent uiRect) {
+	widget.rect.x, widget.rect.w = layoutAxis(parent.x, parent.w, widget.anchorMin[0], widget.anchorMax[0],
+		widget.position[0], widget.size[0], widget.pivot[0], widget.margins[0], widget.margins[2])
+	widget.rect.y, widget.rect.h = layoutAxis(parent.y, parent.h, widget.anchorMin[1], widget.anchorMax[1],
+		widget.position[1], widget.size[1], widget.pivot[1], widget.margins[1], widget.margins[3])
+	for _, child := range widget.children {
+		child.layout(widget.rect)
+	}
+}
+
+// hit returns the front widget blocking the pointer at a point.
+func (widget *Widget) hit(x, y float32) *Widget {
+	if !widget.visible {
+		return nil
+	}
+	for i := len(widget.children) - 1; i >= 0; i-- {
+		found := widget.children[i].hit(x, y)
+		if found != nil {
+			return found
+		}
+	}
+	if widget.parent != nil && widget.raycast && widget.rect.contains(x, y) {
+		return widget
+	}
+	return nil
+}
+
+// interactiveWidget returns the widget itself or its first interactive
+// parent, so that pressing the label of a button presses the button.
+func interactiveWidget(widget *Widget) *Widget {
+	for w := widget; w != nil; w = w.parent {
+		if w.control.interactive() {
+			if !w.interactable {
+				return nil
+			}
+			return w
+		}
+	}
+	return nil
+}
+
+func (widget *Widget) draw(batch *uiBatch) {
+	if !widget.visible {
+		return
+	}
+	widget.control.draw(widget, batch)
+	for _, child := range widget.children {
+		child.draw(batch)
+	}
+}
+
+// stateColor tints a color when the widget is hovered, pressed or disabled.
+func (widget *Widget) stateColor(color mgl32.Vec4) mgl32.Vec4 {
+	switch {
+	case !widget.interactable:
+		color[3] *= 0.5
+	case widget.pressed:
+		color = mgl32.Vec4{color[0] * 0.75, color[1] * 0.75, color[2] * 0.75, color[3]}
+	case widget.hovered || uiInput.focused == widget:
+		color = mgl32.Vec4{color[0] * 1.15, color[1] * 1.15, color[2] * 1.15, color[3]}
+	}
+	return color
+}
+
+// emit enqueues the event of the widget on its target.
+func (widget *Widget) emit(value interface{}) {
+	canvas := widget.canvas
+	if widget.event == "" || canvas == nil || canvas.gameObject == nil {
+		return
+	}
+	targetName := widget.target
+	if targetName == "" {
+		targetName = canvas.target
+	}
+	target := canvas.gameObject
+	if targetName != "" {
+		target = canvas.gameObject.Scene.FindGameObject(targetName)
+		if target == nil {
+			return
+		}
+	}
+	target.EnqueueEventWithData(canvas.gameObject, widget.event, &UIEvent{Canvas: canvas, Widget: widget.Name, Value: value})
+}
+
+func castVec2(value interface{}) (mgl32.Vec2, error) {
+	numbers, err := castFloat32List(value, 2)
+	if err != nil {
+		return mgl32.Vec2{}, err
+	}
+	return mgl32.Vec2{numbers[0], numbers[1]}, nil
+}
+
+func castColor(value interface{}) (mgl32.Vec4, error) {
+	numbers, err := castFloat32List(value, 4)
+	if err != nil {
+		return mgl32.Vec4{}, err
+	}
+	return mgl32.Vec4{numbers[0], numbers[1], numbers[2], numbers[3]}, nil
+}
+
+// setAnchor accepts a preset name, which also moves the pivot to the anchor,
+// or a list of anchorMin and anchorMax.
+func (widget *Widget) setAnchor(value interface{}) error {
+	name, ok := value.(string)
+	if ok {
+		preset, ok := uiAnchorPresets[name]
+		if !ok {
+			return fmt.Errorf("unknown anchor preset %v", name)
+		}
+		widget.anchorMin = mgl32.Vec2{preset[0], preset[1]}
+		widget.anchorMax = mgl32.Vec2{preset[2], preset[3]}
+		for i := 0; i < 2; i++ {
+			if preset[i] == preset[i+2] {
+				widget.pivot[i] = preset[i]
+			}
+		}
+		return nil
+	}
+	anchors, err := castFloat32List(value, 4)
+	if err != nil {
+		return fmt.Errorf("expects a preset or a list of 4 numbers")
+	}
+	widget.anchorMin = mgl32.Vec2{anchors[0], anchors[1]}
+	widget.anchorMax = mgl32.Vec2{anchors[2], anchors[3]}
+	return nil
+}
+
+func (widget *Widget) SetAttr(attr string, value interface{}) error {
+	handled, err := widget.control.setAttr(widget, attr, value)
+	if handled {
+		if err != nil {
+			return fmt.Errorf("%v attribute of %v widget %v", attr, widget.typeName, err)
+		}
+		return nil
+	}
+	switch attr {
+	case "anchor":
+		err = widget.setAnchor(value)
+	case "anchorMin":
+		widget.anchorMin, err = castVec2(value)
+	case "anchorMax":
+		widget.anchorMax, err = castVec2(value)
+	case "position":
+		widget.position, err = castVec2(value)
+	case "size":
+		widget.size, err = castVec2(value)
+	case "pivot":
+		widget.pivot, err = castVec2(value)
+	case "margins":
+		var margins []float32
+		margins, err = castFloat32List(value, 4)
+		if err == nil {
+			copy(widget.margins[:], margins)
+		}
+	case "visible":
+		widget.visible, err = CastBool(value)
+	case "interactable":
+		widget.interactable, err = CastBool(value)
+	case "raycast":
+		widget.raycast, err = CastBool(value)
+	case "color":
+		widget.color, err = castColor(value)
+	case "alpha":
+		widget.color[3], err = CastFloat32(value)
+	case "event", "target":
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %v widget expects a string", attr, widget.typeName)
+		}
+		if attr == "event" {
+			widget.event = name
+		} else {
+			widget.target = name
+		}
+	default:
+		return fmt.Errorf("%v attribute of %v widget not found", attr, widget.typeName)
+	}
+	if err != nil {
+		return fmt.Errorf("%v attribute of %v widget %v", attr, widget.typeName, err)
+	}
+	return nil
+}
+
+func (widget *Widget) GetAttr(attr string) (interface{}, error) {
+	value, handled := widget.control.getAttr(widget, attr)
+	if handled {
+		return value, nil
+	}
+	switch attr {
+	case "anchorMin":
+		return widget.anchorMin, nil
+	case "anchorMax":
+		return widget.anchorMax, nil
+	case "position":
+		return widget.position, nil
+	case "size":
+		return widget.size, nil
+	case "pivot":
+		return widget.pivot, nil
+	case "margins":
+		return widget.margins, nil
+	case "visible":
+		return widget.visible, nil
+	case "interactable":
+		return widget.interactable, nil
+	case "raycast":
+		return widget.raycast, nil
+	case "color":
+		return widget.color, nil
+	case "alpha":
+		return widget.color[3], nil
+	case "event":
+		return widget.event, nil
+	case "target":
+		return widget.target, nil
+	}
+	return nil, fmt.Errorf("%v attribute of %v widget not found", attr, widget.typeName)
+}
+
+type Canvas struct {
+	gameObject *GameObject
+	// The root stretches over the whole canvas.
+	root    *Widget
+	widgets map[string]*Widget
+
+	referenceResolution mgl32.Vec2
+	scaleMode           UIScaleMode
+	// Canvases with a higher order are drawn over the others.
+	order      int
+	target     string
+	focusColor mgl32.Vec4
+
+	batch uiBatch
+}
+
+func NewCanvas() *Canvas {
+	canvas := Canvas{widgets: make(map[string]*Widget)}
+	canvas.root = &Widget{typeName: "root", canvas: &canvas, control: &uiPanel{}, visible: true, anchorMax: mgl32.Vec2{1, 1}}
+	canvas.referenceResolution = mgl32.Vec2{800, 600}
+	canvas.focusColor = mgl32.Vec4{1, 0.8, 0.2, 1}
+	return &canvas
+}
+
+func (canvas *Canvas) Start(gameObject *GameObject) {
+	canvas.gameObject = gameObject
+	uiInput.canvases = append(uiInput.canvases, canvas)
+}
+
+func (canvas *Canvas) Update(gameObject *GameObject) {
+}
+
+func (canvas *Canvas) Destroy(gameObject *GameObject) {
+	for i, c := range uiInput.canvases {
+		if c == canvas {
+			uiInput.canvases = append(uiInput.canvases[:i], uiInput.canvases[i+1:]...)
+			break
+		}
+	}
+	uiInput.forget(canvas)
+}
+
+// The canvas accepts "uiFocus", "uiShow" and "uiHide" events, with the name
+// of a widget as Data.
+func (canvas *Canvas) OnEvent(gameObject *GameObject, event *Event) {
+	name, ok := event.Data.(string)
+	if !ok {
+		return
+	}
+	widget := canvas.Widget(name)
+	if widget == nil {
+		return
+	}
+	switch event.Msg {
+	case "uiFocus":
+		canvas.Focus(widget)
+	case "uiShow":
+		widget.visible = true
+	case "uiHide":
+		widget.visible = false
+	}
+}
+
+func (canvas *Canvas) Root() *Widget {
+	return canvas.root
+}
+
+// Widget finds a widget by name.
+func (canvas *Canvas) Widget(name string) *Widget {
+	widget, ok := canvas.widgets[name]
+	if !ok {
+		return nil
+	}
+	return widget
+}
+
+// AddWidget appends a widget to the children of a parent, the root when nil.
+func (canvas *Canvas) AddWidget(parent *Widget, widget *Widget) {
+	if parent == nil {
+		parent = canvas.root
+	}
+	widget.parent = parent
+	parent.children = append(parent.children, widget)
+	canvas.attach(widget)
+}
+
+func (canvas *Canvas) attach(widget *Widget) {
+	widget.canvas = canvas
+	if widget.Name != "" {
+		canvas.widgets[widget.Name] = widget
+	}
+	for _, child := range widget.children {
+		canvas.attach(child)
+	}
+}
+
+// RemoveWidget removes a widget with its children.
+func (canvas *Canvas) RemoveWidget(widget *Widget) {
+	parent := widget.parent
+	if parent == nil || widget.canvas != canvas {
+		return
+	}
+	for i, child := range parent.children {
+		if child == widget {
+			parent.children = append(parent.children[:i], parent.children[i+1:]...)
+			break
+		}
+	}
+	widget.parent = nil
+	canvas.detach(widget)
+}
+
+func (canvas *Canvas) detach(widget *Widget) {
+	if canvas.widgets[widget.Name] == widget {
+		delete(canvas.widgets, widget.Name)
+	}
+	uiInput.forgetWidget(widget)
+	widget.canvas = nil
+	for _, child := range widget.children {
+		canvas.detach(child)
+	}
+}
+
+// Focus moves the keyboard focus to a widget, nil clears it.
+func (canvas *Canvas) Focus(widget *Widget) {
+	if widget != nil && (widget.canvas != canvas || !widget.focusable()) {
+		return
+	}
+	uiInput.focused = widget
+}
+
+// FocusedWidget returns the widget with the keyboard focus, if any.
+func FocusedWidget() *Widget {
+	return uiInput.focused
+}
+
+// windowSize is the size of the window, or of the reference resolution
+// without a window.
+func (canvas *Canvas) windowSize() (float32, float32) {
+	if Engine.Window != nil {
+		return float32(Engine.Window.width), float32(Engine.Window.height)
+	}
+	return canvas.referenceResolution[0], canvas.referenceResolution[1]
+}
+
+// Scale returns the window pixels of a canvas unit.
+func (canvas *Canvas) Scale() float32 {
+	width, height := canvas.windowSize()
+	reference := canvas.referenceResolution
+	if reference[0] <= 0 || reference[1] <= 0 || width <= 0 || height <= 0 {
+		return 1
+	}
+	switch canvas.scaleMode {
+	case UIScaleFit:
+		return float32(math.Min(float64(width/reference[0]), float64(height/reference[1])))
+	case UIScaleWidth:
+		return width / reference[0]
+	case UIScaleHeight:
+		return height / reference[1]
+	}
+	return 1
+}
+
+// Size returns the size of the canvas, in canvas units.
+func (canvas *Canvas) Size() (float32, float32) {
+	width, height := canvas.windowSize()
+	scale := canvas.Scale()
+	return width / scale, height / scale
+}
+
+func (canvas *Canvas) layout() {
+	width, height := canvas.Size()
+	canvas.root.rect = uiRect{0, 0, width, height}
+	for _, child := range canvas.root.children {
+		child.layout(canvas.root.rect)
+	}
+}
+
+// toCanvas converts window coordinates.
+func (canvas *Canvas) toCanvas(x, y float64) (float32, float32) {
+	scale := float64(canvas.Scale())
+	return float32(x / scale), float32(y / scale)
+}
+
+// focusables lists the focusable widgets in tree order.
+func (canvas *Canvas) focusables() []*Widget {
+	var widgets []*Widget
+	var walk func(widget *Widget)
+	walk = func(widget *Widget) {
+		if !widget.visible {
+			return
+		}
+		if widget.focusable() {
+			widgets = append(widgets, widget)
+		}
+		for _, child := range widget.children {
+			walk(child)
+		}
+	}
+	walk(canvas.root)
+	return widgets
+}
+
+// navigate finds the closest focusable widget in a direction, favouring the
+// aligned ones.
+func (canvas *Canvas) navigate(from *Widget, direction mgl32.Vec2) *Widget {
+	origin := from.rect.center()
+	var best *Widget
+	var bestScore float32
+	for _, widget := range canvas.focusables() {
+		if widget == from {
+			continue
+		}
+		delta := widget.rect.center().Sub(origin)
+		along := delta.Dot(direction)
+		if along <= 0 {
+			continue
+		}
+		across := float32(math.Abs(float64(delta[0]*direction[1] - delta[1]*direction[0])))
+		score := along + across*2
+		if best == nil || score < bestScore {
+			best = widget
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// loadWidgets builds widgets from their JSON description.
+func (canvas *Canvas) loadWidgets(parent *Widget, items []interface{}) error {
+	for _, item := range items {
+		widgetMap, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("a widget must be an object")
+		}
+		typeName, ok := widgetMap["type"].(string)
+		if !ok {
+			return fmt.Errorf("a widget requires a type")
+		}
+		name, _ := widgetMap["name"].(string)
+		widget, err := NewWidget(typeName, name)
+		if err != nil {
+			return err
+		}
+
+		// The anchor preset goes first, as it moves the pivot.
+		var keys []string
+		for key := range widgetMap {
+			if key != "type" && key != "name" && key != "children" && key != "anchor" {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		if _, ok := widgetMap["anchor"]; ok {
+			keys = append([]string{"anchor"}, keys...)
+		}
+		for _, key := range keys {
+			err = widget.SetAttr(key, widgetMap[key])
+			if err != nil {
+				return err
+			}
+		}
+
+		canvas.AddWidget(parent, widget)
+
+		children, ok := widgetMap["children"]
+		if ok {
+			childList, ok := children.([]interface{})
+			if !ok {
+				return fmt.Errorf("children of widget %v must be a list", name)
+			}
+			err = canvas.loadWidgets(widget, childList)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Attributes of named widgets are available as "widget.attr".
+func (canvas *Canvas) SetAttr(attr string, value interface{}) error {
+	var err error
+	switch attr {
+	case "referenceResolution":
+		canvas.referenceResolution, err = castVec2(value)
+	case "scaleMode":
+		name, _ := value.(string)
+		mode, ok := uiScaleModeNames[name]
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects none, fit, width or height", attr, canvas)
+		}
+		canvas.scaleMode = mode
+	case "order":
+		canvas.order, err = CastInt(value)
+	case "target":
+		name, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a string", attr, canvas)
+		}
+		canvas.target = name
+	case "focusColor":
+		canvas.focusColor, err = castColor(value)
+	case "widgets":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v attribute of %T expects a list of widgets", attr, canvas)
+		}
+		for len(canvas.root.children) > 0 {
+			canvas.RemoveWidget(canvas.root.children[0])
+		}
+		err = canvas.loadWidgets(canvas.root, items)
+	default:
+		dot := strings.Index(attr, ".")
+		if dot < 0 {
+			return fmt.Errorf("%v attribute of %T not found", attr, canvas)
+		}
+		widget := canvas.Widget(attr[:dot])
+		if widget == nil {
+			return fmt.Errorf("%v attribute of %T: widget %v not found", attr, canvas, attr[:dot])
+		}
+		return widget.SetAttr(attr[dot+1:], value)
+	}
+	if err != nil {
+		return fmt.Errorf("%v attribute of %T %v", attr, canvas, err)
+	}
+	return nil
+}
+
+func (canvas *Canvas) GetAttr(attr string) (interface{}, error) {
+	switch attr {
+	case "referenceResolution":
+		return canvas.referenceResolution, nil
+	case "scaleMode":
+		return canvas.scaleMode.String(), nil
+	case "order":
+		return canvas.order, nil
+	case "target":
+		return canvas.target, nil
+	case "focusColor":
+		return canvas.focusColor, nil
+	case "scale":
+		return canvas.Scale(), nil
+	}
+	dot := strings.Index(attr, ".")
+	if dot >= 0 {
+		widget := canvas.Widget(attr[:dot])
+		if widget != nil {
+			return widget.GetAttr(attr[dot+1:])
+		}
+	}
+	return nil, fmt.Errorf("%v attribute of %T not found", attr, canvas)
+}
+
+func (canvas *Canvas) GetType() string {
+	return "Canvas"
+}
+
+func initCanvas(args []interface{}) Component {
+	return NewCanvas()
+}
+
+// The left mouse button presses widgets.
+const uiPointerButton = 0
+
+type uiState struct {
+	canvases []*Canvas
+
+	focused *Widget
+	hovered *Widget
+	// The widget pressed by the mouse, and the buttons pressed over the UI.
+	pressed      *Widget
+	mouseButtons map[int]bool
+	// Fingers touching the UI, with the widget they pressed (if any).
+	touches map[int]*Widget
+	// Keys used by the UI, whose release is removed too.
+	keys  map[Key]bool
+	shift bool
+}
+
+var uiInput = uiState{mouseButtons: make(map[int]bool), touches: make(map[int]*Widget), keys: make(map[Key]bool)}
+
+// forget drops the references to the widgets of a canvas.
+func (state *uiState) forget(canvas *Canvas) {
+	var walk func(widget *Widget)
+	walk = func(widget *Widget) {
+		state.forgetWidget(widget)
+		for _, child := range widget.children {
+			walk(child)
+		}
+	}
+	walk(canvas.root)
+}
+
+func (state *uiState) forgetWidget(widget *Widget) {
+	if state.focused == widget {
+		state.focused = nil
+	}
+	if state.hovered == widget {
+		state.hovered = nil
+	}
+	if state.pressed == widget {
+		state.pressed = nil
+	}
+	for id, touched := range state.touches {
+		if touched == widget {
+			state.touches[id] = nil
+		}
+	}
+}
+
+// activeCanvases returns the canvases of enabled GameObjects in the loaded
+// scenes (or in a given one), back to front.
+func activeCanvases(scene *Scene) []*Canvas {
+	var canvases []*Canvas
+	for _, canvas := range uiInput.canvases {
+		gameObject := canvas.gameObject
+		if gameObject == nil || !gameObject.enabled {
+			continue
+		}
+		if scene != nil && gameObject.Scene != scene {
+			continue
+		}
+		if Engine.Window != nil && !sceneManager.IsLoaded(gameObject.Scene) {
+			continue
+		}
+		canvases = append(canvases, canvas)
+	}
+	sceneManager.sortByScene(canvases)
+	return canvases
+}
+
+// uiHit returns the front widget blocking the pointer at window coordinates.
+func uiHit(canvases []*Canvas, x, y float64) *Widget {
+	for i := len(canvases) - 1; i >= 0; i-- {
+		cx, cy := canvases[i].toCanvas(x, y)
+		widget := canvases[i].root.hit(cx, cy)
+		if widget != nil {
+			return widget
+		}
+	}
+	return nil
+}
+
+// updateUI runs before the other input updaters, removing from the frame
+// the presses and keys consumed by the widgets.
+func updateUI(frame *InputFrame) {
+	canvases := activeCanvases(nil)
+	for _, canvas := range canvases {
+		canvas.layout()
+	}
+	focused := uiInput.focused
+	if focused != nil && (focused.canvas == nil || !containsCanvas(canvases, focused.canvas) || !focused.focusable()) {
+		uiInput.focused = nil
+	}
+
+	updateUIPointer(frame, canvases)
+	updateUITouches(frame, canvases)
+	updateUIKeys(frame, canvases)
+}
+
+func containsCanvas(canvases []*Canvas, canvas *Canvas) bool {
+	for _, c := range canvases {
+		if c == canvas {
+			return true
+		}
+	}
+	return false
+}
+
+func updateUIPointer(frame *InputFrame, canvases []*Canvas) {
+	hit := uiHit(canvases, frame.MouseX, frame.MouseY)
+	target := interactiveWidget(hit)
+	if uiInput.hovered != target {
+		if uiInput.hovered != nil {
+			uiInput.hovered.hovered = false
+		}
+		if target != nil {
+			target.hovered = true
+		}
+		uiInput.hovered = target
+	}
+
+	// The frame may be recorded, so its slices are replaced and not modified.
+	var buttons []InputMouseButton
+	consumed := false
+	for _, button := range frame.MouseButtons {
+		if button.Pressed && hit != nil && !uiInput.mouseButtons[button.Button] {
+			uiInput.mouseButtons[button.Button] = true
+			consumed = true
+			if button.Button == uiPointerButton {
+				uiInput.focused = nil
+				if target != nil {
+					uiInput.pressed = target
+					target.pressed = true
+					x, y := target.canvas.toCanvas(frame.MouseX, frame.MouseY)
+					target.control.press(target, x, y)
+					uiInput.focused = target
+				}
+			}
+			continue
+		}
+		if !button.Pressed && uiInput.mouseButtons[button.Button] {
+			delete(uiInput.mouseButtons, button.Button)
+			consumed = true
+			pressed := uiInput.pressed
+			if button.Button == uiPointerButton && pressed != nil {
+				uiInput.pressed = nil
+				pressed.pressed = false
+				pressed.control.release(pressed, pressed == target)
+			}
+			continue
+		}
+		buttons = append(buttons, button)
+	}
+	if consumed {
+		frame.MouseButtons = buttons
+	}
+
+	pressed := uiInput.pressed
+	if pressed != nil && pressed.canvas != nil {
+		x, y := pressed.canvas.toCanvas(frame.MouseX, frame.MouseY)
+		pressed.control.drag(pressed, x, y)
+	}
+
+	if (frame.WheelX != 0 || frame.WheelY != 0) && hit != nil {
+		for w := hit; w != nil; w = w.parent {
+			if w.interactable && w.control.scroll(w, float32(frame.WheelY)) {
+				break
+			}
+		}
+		frame.WheelX = 0
+		frame.WheelY = 0
+	}
+}
+
+func updateUITouches(frame *InputFrame, canvases []*Canvas) {
+	var touches []InputTouch
+	consumed := false
+	for _, touch := range frame.Touches {
+		widget, captured := uiInput.touches[touch.Id]
+		if touch.Phase == TouchBegin && !captured {
+			hit := uiHit(canvases, float64(touch.X), float64(touch.Y))
+			if hit == nil {
+				touches = append(touches, touch)
+				continue
+			}
+			target := interactiveWidget(hit)
+			uiInput.touches[touch.Id] = target
+			if target != nil {
+				target.pressed = true
+				x, y := target.canvas.toCanvas(float64(touch.X), float64(touch.Y))
+				target.control.press(target, x, y)
+			}
+			consumed = true
+			continue
+		}
+		if !captured {
+			touches = append(touches, touch)
+			continue
+		}
+		consumed = true
+		if widget != nil && widget.canvas != nil {
+			x, y := widget.canvas.toCanvas(float64(touch.X), float64(touch.Y))
+			if touch.Phase == TouchMove {
+				widget.control.drag(widget, x, y)
+			}
+			if touch.Phase == TouchEnd {
+				widget.pressed = false
+				widget.control.release(widget, widget.Visible() && widget.rect.contains(x, y))
+			}
+		}
+		if touch.Phase == TouchEnd {
+			delete(uiInput.touches, touch.Id)
+		}
+	}
+	if consumed {
+		frame.Touches = touches
+	}
+}
+
+func updateUIKeys(frame *InputFrame, canvases []*Canvas) {
+	var keys []InputKey
+	consumed := false
+	for _, key := range frame.Keys {
+		if key.Key == KeyLeftShift || key.Key == KeyRightShift {
+			uiInput.shift = key.Pressed
+		}
+		if !key.Pressed {
+			if uiInput.keys[key.Key] {
+				delete(uiInput.keys, key.Key)
+				consumed = true
+				continue
+			}
+			keys = append(keys, key)
+			continue
+		}
+		if uiKey(canvases, key.Key) {
+			uiInput.keys[key.Key] = true
+			consumed = true
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if consumed {
+		frame.Keys = keys
+	}
+}
+
+var uiDirections = map[Key]mgl32.Vec2{
+	KeyLeft:  {-1, 0},
+	KeyRight: {1, 0},
+	KeyUp:    {0, -1},
+	KeyDown:  {0, 1},
+}
+
+// uiKey moves the focus with Tab (Shift-Tab backward) and the arrows, and
+// submits the focused widget with Enter or Space. Without a focus, Tab
+// focuses the first widget of the front canvas.
+func uiKey(canvases []*Canvas, key Key) bool {
+	focused := uiInput.focused
+	if focused == nil {
+		if key != KeyTab {
+			return false
+		}
+		for i := len(canvases) - 1; i >= 0; i-- {
+			widgets := canvases[i].focusables()
+			if len(widgets) > 0 {
+				uiInput.focused = widgets[0]
+				return true
+			}
+		}
+		return false
+	}
+
+	switch key {
+	case KeyTab:
+		widgets := focused.canvas.focusables()
+		for i, widget := range widgets {
+			if widget != focused {
+				continue
+			}
+			step := 1
+			if uiInput.shift {
+				step = len(widgets) - 1
+			}
+			uiInput.focused = widgets[(i+step)%len(widgets)]
+			break
+		}
+		return true
+	case KeyEnter, KeyKPEnter, KeySpace:
+		focused.control.submit(focused)
+		return true
+	case KeyEscape:
+		// Escape is left to the game, usually to close the menu.
+		uiInput.focused = nil
+		return false
+	}
+
+	direction, ok := uiDirections[key]
+	if !ok {
+		return false
+	}
+	if !focused.control.key(focused, key) {
+		next := focused.canvas.navigate(focused, direction)
+		if next != nil {
+			uiInput.focused = next
+		}
+	}
+	return true
+}
+
+// uiBatch collects colored quads, drawing them when the texture changes.
+type uiBatch struct {
+	mesh    *Mesh
+	texture *Texture
+}
+
+// quad adds a rectangle with uvs (u0, v0 at its top left corner), the
+// texture can be nil.
+func (batch *uiBatch) quad(rect uiRect, texture *Texture, uv [4]float32, color mgl32.Vec4) {
+	if rect.w <= 0 || rect.h <= 0 {
+		return
+	}
+	if texture != batch.texture {
+		batch.flush()
+		batch.texture = texture
+	}
+	mesh := batch.mesh
+	mesh.vertices, mesh.uvs = appendQuad(mesh.vertices, mesh.uvs, rect.x, rect.y+rect.h, rect.x+rect.w, rect.y, uv[0], uv[1], uv[2], uv[3])
+	for i := 0; i < 6; i++ {
+		mesh.colors = append(mesh.colors, color[0], color[1], color[2], color[3])
+	}
+}
+
+// triangles adds vertices with Y up centered on a point, like the ones of
+// slicedGeometry.
+func (batch *uiBatch) triangles(vertices, uvs []float32, texture *Texture, center mgl32.Vec2, color mgl32.Vec4) {
+	if texture != batch.texture {
+		batch.flush()
+		batch.texture = texture
+	}
+	mesh := batch.mesh
+	for i := 0; i+1 < len(vertices); i += 2 {
+		mesh.vertices = append(mesh.vertices, center[0]+vertices[i], center[1]-vertices[i+1])
+		mesh.colors = append(mesh.colors, color[0], color[1], color[2], color[3])
+	}
+	mesh.uvs = append(mesh.uvs, uvs...)
+}
+
+// outline draws the border of a rectangle, inside it.
+func (batch *uiBatch) outline(rect uiRect, width float32, color mgl32.Vec4) {
+	batch.quad(uiRect{rect.x, rect.y, rect.w, width}, nil, [4]float32{}, color)
+	batch.quad(uiRect{rect.x, rect.y + rect.h - width, rect.w, width}, nil, [4]float32{}, color)
+	batch.quad(uiRect{rect.x, rect.y + width, width, rect.h - width*2}, nil, [4]float32{}, color)
+	batch.quad(uiRect{rect.x + rect.w - width, rect.y + width, width, rect.h - width*2}, nil, [4]float32{}, color)
+}
+
+func (batch *uiBatch) flush() {
+	mesh := batch.mesh
+	if len(mesh.vertices) == 0 {
+		return
+	}
+	mesh.uploadDynamic()
+
+	var tid uint32
+	var textured float32
+	blend := BlendAlpha
+	if batch.texture != nil {
+		tid = batch.texture.tid
+		textured = 1
+		if batch.texture.Options.Premultiplied {
+			blend = BlendPremultiplied
+		}
+	}
+	setBlendMode(blend)
+	GLUniform(particleProgram.textured, textured)
+	IncPerFrameStats("GL.DrawCalls", 1)
+	GLDrawMesh(mesh, tid)
+
+	mesh.vertices = mesh.vertices[:0]
+	mesh.uvs = mesh.uvs[:0]
+	mesh.colors = mesh.colors[:0]
+}
+
+// drawCanvases draws the canvases of a scene on the window.
+func drawCanvases(scene *Scene) {
+	canvases := activeCanvases(scene)
+	if len(canvases) == 0 || !compileParticleProgram() {
+		return
+	}
+
+	width, height := bindRenderTarget(nil)
+	GLViewport(0, 0, width, height)
+	GLUseProgram(particleProgram.id)
+
+	for _, canvas := range canvases {
+		batch := &canvas.batch
+		if batch.mesh == nil {
+			batch.mesh = newDynamicMesh()
+		}
+		canvas.layout()
+		rect := canvas.root.rect
+		GLUniform(particleProgram.ortho, mgl32.Ortho2D(0, rect.w, rect.h, 0))
+
+		canvas.root.draw(batch)
+		focused := uiInput.focused
+		if focused != nil && focused.canvas == canvas && focused.Visible() {
+			border := focused.rect
+			batch.outline(uiRect{border.x - 2, border.y - 2, border.w + 4, border.h + 4}, 2, canvas.focusColor)
+		}
+		batch.flush()
+		batch.texture = nil
+	}
+}
+
+func init() {
+	RegisterComponent("Canvas", initCanvas)
+}
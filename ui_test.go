@@ -0,0 +1,253 @@
+package gozmo
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func newTestCanvas(t *testing.T, widgets string) (*GameObject, *Canvas) {
+	var parsed []interface{}
+	err := json.Unmarshal([]byte(widgets), &parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scene := NewScene("Test")
+	gameObject := scene.NewGameObject("UI")
+	canvas := NewCanvas()
+	gameObject.AddComponent("canvas", canvas)
+	err = canvas.SetAttr("widgets", parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gameObject, canvas
+}
+
+func checkRect(t *testing.T, widget *Widget, x, y, w, h float32) {
+	rx, ry, rw, rh := widget.Rect()
+	if rx != x || ry != y || rw != w || rh != h {
+		t.Error(widget.Name, "expected", x, y, w, h, "got", rx, ry, rw, rh)
+	}
+}
+
+func TestWidgetLayout(t *testing.T) {
+	gameObject, canvas := newTestCanvas(t, `[
+		{"type": "panel", "name": "bar", "anchor": "stretchTop", "size": [0, 40], "margins": [10, 0, 10, 0]},
+		{"type": "button", "name": "close", "anchor": "bottomRight", "position": [-10, -10], "size": [100, 50]},
+		{"type": "panel", "name": "window", "anchor": "stretch", "margins": [100, 50, 100, 50], "children": [
+			{"type": "label", "name": "title", "anchor": "top", "size": [200, 20], "pivot": [0.5, 0]}
+		]}
+	]`)
+	defer gameObject.Destroy()
+
+	// Without a window the canvas has the reference resolution.
+	canvas.layout()
+	checkRect(t, canvas.Widget("bar"), 10, 0, 780, 40)
+	checkRect(t, canvas.Widget("close"), 690, 540, 100, 50)
+	checkRect(t, canvas.Widget("window"), 100, 50, 600, 500)
+	checkRect(t, canvas.Widget("title"), 300, 50, 200, 20)
+
+	err := canvas.SetAttr("close.position", []interface{}{0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	canvas.layout()
+	checkRect(t, canvas.Widget("close"), 700, 550, 100, 50)
+
+	if canvas.SetAttr("missing.position", []interface{}{0, 0}) == nil {
+		t.Error("Expected an error for a missing widget")
+	}
+}
+
+func TestCanvasScale(t *testing.T) {
+	Engine.Window = &Window{width: 1600, height: 900}
+	defer func() { Engine.Window = nil }()
+
+	canvas := NewCanvas()
+	canvas.SetAttr("referenceResolution", []interface{}{800, 600})
+
+	if canvas.Scale() != 1 {
+		t.Error("Expected 1, got", canvas.Scale())
+	}
+	canvas.SetAttr("scaleMode", "fit")
+	if canvas.Scale() != 1.5 {
+		t.Error("Expected 1.5, got", canvas.Scale())
+	}
+	canvas.SetAttr("scaleMode", "width")
+	if canvas.Scale() != 2 {
+		t.Error("Expected 2, got", canvas.Scale())
+	}
+	width, height := canvas.Size()
+	if width != 800 || height != 450 {
+		t.Error("Expected 800 450, got", width, height)
+	}
+}
+
+func TestUIPointerInput(t *testing.T) {
+	gameObject, canvas := newTestCanvas(t, `[
+		{"type": "button", "name": "play", "event": "play", "size": [200, 50], "children": [
+			{"type": "label", "anchor": "stretch", "text": "Play"}
+		]}
+	]`)
+	defer gameObject.Destroy()
+
+	// A click on the label reaches the button, and is hidden from the mouse.
+	frame := InputFrame{MouseX: 420, MouseY: 300}
+	frame.MouseButtons = []InputMouseButton{{Button: 0, Pressed: true}, {Button: 0, Pressed: false}}
+	processInputFrame(&frame)
+	if len(frame.MouseButtons) != 0 {
+		t.Error("Expected 0, got", len(frame.MouseButtons))
+	}
+	if len(gameObject.events) != 1 || gameObject.events[0].Msg != "play" {
+		t.Fatal("Expected a play event, got", gameObject.events)
+	}
+	if gameObject.events[0].Data.(*UIEvent).Widget != "play" {
+		t.Error("Expected play, got", gameObject.events[0].Data.(*UIEvent).Widget)
+	}
+	gameObject.events = nil
+
+	// Releasing outside of the button does not click it.
+	frame = InputFrame{MouseX: 420, MouseY: 300, MouseButtons: []InputMouseButton{{Button: 0, Pressed: true}}}
+	processInputFrame(&frame)
+	frame = InputFrame{MouseX: 10, MouseY: 10, MouseButtons: []InputMouseButton{{Button: 0, Pressed: false}}}
+	processInputFrame(&frame)
+	if len(frame.MouseButtons) != 0 || len(gameObject.events) != 0 {
+		t.Error("Expected no buttons and no events, got", frame.MouseButtons, gameObject.events)
+	}
+
+	// Presses outside of the UI go to the game.
+	frame = InputFrame{MouseX: 10, MouseY: 10, MouseButtons: []InputMouseButton{{Button: 0, Pressed: true}}}
+	processInputFrame(&frame)
+	if len(frame.MouseButtons) != 1 {
+		t.Error("Expected 1, got", len(frame.MouseButtons))
+	}
+	frame = InputFrame{MouseX: 10, MouseY: 10, MouseButtons: []InputMouseButton{{Button: 0, Pressed: false}}}
+	processInputFrame(&frame)
+
+	canvas.SetAttr("play.visible", false)
+	frame = InputFrame{MouseX: 420, MouseY: 300, MouseButtons: []InputMouseButton{{Button: 0, Pressed: true}}}
+	processInputFrame(&frame)
+	if len(frame.MouseButtons) != 1 {
+		t.Error("Expected 1, got", len(frame.MouseButtons))
+	}
+	frame = InputFrame{MouseX: 420, MouseY: 300, MouseButtons: []InputMouseButton{{Button: 0, Pressed: false}}}
+	processInputFrame(&frame)
+}
+
+func TestUITouchInput(t *testing.T) {
+	gameObject, canvas := newTestCanvas(t, `[
+		{"type": "toggle", "name": "sound", "event": "sound", "anchor": "topLeft", "size": [100, 20]},
+		{"type": "slider", "name": "volume", "event": "volume", "anchor": "topLeft", "position": [0, 100], "size": [120, 20]}
+	]`)
+	defer gameObject.Destroy()
+
+	frame := InputFrame{Touches: []InputTouch{{Id: 1, X: 5, Y: 5, Phase: TouchBegin}, {Id: 2, X: 500, Y: 500, Phase: TouchBegin}}}
+	processInputFrame(&frame)
+	if len(frame.Touches) != 1 || frame.Touches[0].Id != 2 {
+		t.Fatal("Expected the touch 2, got", frame.Touches)
+	}
+	frame = InputFrame{Touches: []InputTouch{{Id: 1, X: 5, Y: 5, Phase: TouchEnd}, {Id: 2, X: 500, Y: 500, Phase: TouchEnd}}}
+	processInputFrame(&frame)
+	if len(frame.Touches) != 1 {
+		t.Error("Expected 1, got", len(frame.Touches))
+	}
+	checked, _ := canvas.GetAttr("sound.checked")
+	if checked != true {
+		t.Error("Expected true, got", checked)
+	}
+
+	// Dragging the slider handle, 20 units wide, to the middle of the track.
+	frame = InputFrame{Touches: []InputTouch{{Id: 3, X: 10, Y: 110, Phase: TouchBegin}}}
+	processInputFrame(&frame)
+	frame = InputFrame{Touches: []InputTouch{{Id: 3, X: 60, Y: 110, Phase: TouchMove}}}
+	processInputFrame(&frame)
+	frame = InputFrame{Touches: []InputTouch{{Id: 3, X: 60, Y: 110, Phase: TouchEnd}}}
+	processInputFrame(&frame)
+	value, _ := canvas.GetAttr("volume.value")
+	if value != float32(0.5) {
+		t.Error("Expected 0.5, got", value)
+	}
+
+	expected := []string{"sound", "volume"}
+	if len(gameObject.events) != len(expected) {
+		t.Fatal("Expected", len(expected), "got", len(gameObject.events))
+	}
+	for i, msg := range expected {
+		if gameObject.events[i].Msg != msg {
+			t.Error("Expected", msg, "got", gameObject.events[i].Msg)
+		}
+	}
+}
+
+func TestUIFocusNavigation(t *testing.T) {
+	gameObject, canvas := newTestCanvas(t, `[
+		{"type": "button", "name": "play", "event": "play", "position": [0, -50]},
+		{"type": "button", "name": "quit", "event": "quit", "position": [0, 50]},
+		{"type": "slider", "name": "volume", "event": "volume", "position": [200, 50], "step": 0.25},
+		{"type": "list", "name": "levels", "event": "level", "anchor": "left", "items": ["one", "two"]}
+	]`)
+	defer gameObject.Destroy()
+
+	press := func(keys ...Key) *InputFrame {
+		frame := InputFrame{}
+		for _, key := range keys {
+			frame.Keys = append(frame.Keys, InputKey{Key: key, Pressed: true}, InputKey{Key: key, Pressed: false})
+		}
+		processInputFrame(&frame)
+		return &frame
+	}
+
+	// Keys go to the game until a widget has the focus.
+	frame := press(KeyDown)
+	if len(frame.Keys) != 2 || FocusedWidget() != nil {
+		t.Fatal("Expected the keys to pass through, got", frame.Keys, FocusedWidget())
+	}
+
+	frame = press(KeyTab)
+	if len(frame.Keys) != 0 || FocusedWidget() != canvas.Widget("play") {
+		t.Fatal("Expected play focused, got", FocusedWidget())
+	}
+	press(KeyDown)
+	if FocusedWidget() != canvas.Widget("quit") {
+		t.Fatal("Expected quit focused, got", FocusedWidget())
+	}
+	press(KeyRight, KeyRight)
+	value, _ := canvas.GetAttr("volume.value")
+	if FocusedWidget() != canvas.Widget("volume") || value != float32(0.25) {
+		t.Error("Expected volume focused at 0.25, got", FocusedWidget(), value)
+	}
+
+	// Shift-Tab goes backward, wrapping around.
+	shiftTab := InputFrame{Keys: []InputKey{{Key: KeyLeftShift, Pressed: true}, {Key: KeyTab, Pressed: true}}}
+	processInputFrame(&shiftTab)
+	if len(shiftTab.Keys) != 1 || FocusedWidget() != canvas.Widget("quit") {
+		t.Error("Expected quit focused, got", FocusedWidget())
+	}
+	shiftTab = InputFrame{Keys: []InputKey{{Key: KeyTab, Pressed: false}, {Key: KeyLeftShift, Pressed: false}}}
+	processInputFrame(&shiftTab)
+
+	press(KeyEnter)
+
+	canvas.Focus(canvas.Widget("levels"))
+	press(KeyDown)
+	press(KeyDown)
+	selected, _ := canvas.GetAttr("levels.selected")
+	if selected != 1 {
+		t.Error("Expected 1, got", selected)
+	}
+
+	// Escape is left to the game.
+	frame = press(KeyEscape)
+	if len(frame.Keys) != 2 || FocusedWidget() != nil {
+		t.Error("Expected no focus, got", FocusedWidget())
+	}
+
+	expected := []string{"volume", "quit", "level", "level"}
+	if len(gameObject.events) != len(expected) {
+		t.Fatal("Expected", len(expected), "got", len(gameObject.events))
+	}
+	for i, msg := range expected {
+		if gameObject.events[i].Msg != msg {
+			t.Error("Expected", msg, "got", gameObject.events[i].Msg)
+		}
+	}
+}
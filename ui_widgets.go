@@ -0,0 +1,728 @@
+package gozmo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// uiFill draws the background of a widget: its color, or a texture tinted
+// by it, nine-sliced when it has a "border" (left, top, right, bottom in
+// texture pixels).
+type uiFill struct {
+	texture string
+	border  [4]float32
+}
+
+func (fill *uiFill) setAttr(attr string, value interface{}) (bool, error) {
+	switch attr {
+	case "texture":
+		name, ok := value.(string)
+		if !ok {
+			return true, fmt.Errorf("expects a string")
+		}
+		fill.texture = name
+		return true, nil
+	case "border":
+		border, err := castFloat32List(value, 4)
+		if err != nil {
+			return true, err
+		}
+		copy(fill.border[:], border)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (fill *uiFill) getAttr(attr string) (interface{}, bool) {
+	switch attr {
+	case "texture":
+		return fill.texture, true
+	case "border":
+		return fill.border, true
+	}
+	return nil, false
+}
+
+func (fill *uiFill) lookup(widget *Widget) *Texture {
+	if fill.texture == "" || widget.canvas == nil || widget.canvas.gameObject == nil {
+		return nil
+	}
+	texture, ok := widget.canvas.gameObject.Scene.textures[fill.texture]
+	if !ok {
+		return nil
+	}
+	return texture
+}
+
+func (fill *uiFill) draw(widget *Widget, batch *uiBatch, rect uiRect, color mgl32.Vec4) {
+	texture := fill.lookup(widget)
+	if texture == nil {
+		if color[3] > 0 {
+			batch.quad(rect, nil, [4]float32{}, color)
+		}
+		return
+	}
+	if fill.border == [4]float32{} {
+		batch.quad(rect, texture, [4]float32{0, 0, 1, 1}, color)
+		return
+	}
+	// slicedGeometry expects the borders as left, bottom, right, top.
+	width := float32(texture.Width)
+	height := float32(texture.Height)
+	border := [4]float32{fill.border[0], fill.border[3], fill.border[2], fill.border[1]}
+	uvBorder := [4]float32{border[0] / width, border[1] / height, border[2] / width, border[3] / height}
+	vertices, uvs := slicedGeometry(rect.w, rect.h, border, uvBorder, [4]float32{0, 0, 1, 1})
+	batch.triangles(vertices, uvs, texture, rect.center(), color)
+}
+
+// uiText draws a string in a rectangle of the widget. Without a "font" (or
+// when the scene does not have it) the built-in one is used; "fontSize" is
+// the height of a line in canvas units, 0 keeps the size of the font.
+type uiText struct {
+	text          string
+	fontName      string
+	fontSize      float32
+	color         mgl32.Vec4
+	align         TextAlign
+	verticalAlign TextAlign
+	wrap          bool
+}
+
+func newUIText(align TextAlign, verticalAlign TextAlign) uiText {
+	return uiText{color: mgl32.Vec4{1, 1, 1, 1}, align: align, verticalAlign: verticalAlign}
+}
+
+func (text *uiText) setAttr(attr string, value interface{}) (bool, error) {
+	var err error
+	switch attr {
+	case "text":
+		str, ok := value.(string)
+		if ok {
+			text.text = str
+			return true, nil
+		}
+		number, err := CastFloat32(value)
+		if err != nil {
+			return true, fmt.Errorf("expects a string or a number")
+		}
+		text.text = formatNumber("", number)
+	case "font":
+		name, ok := value.(string)
+		if !ok {
+			return true, fmt.Errorf("expects a string")
+		}
+		text.fontName = name
+	case "fontSize":
+		text.fontSize, err = CastFloat32(value)
+	case "textColor":
+		text.color, err = castColor(value)
+	case "align":
+		text.align, err = castTextAlign(value, textAlignNames)
+	case "verticalAlign":
+		text.verticalAlign, err = castTextAlign(value, textVerticalAlignNames)
+	case "wrap":
+		text.wrap, err = CastBool(value)
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+func (text *uiText) getAttr(attr string) (interface{}, bool) {
+	switch attr {
+	case "text":
+		return text.text, true
+	case "font":
+		return text.fontName, true
+	case "fontSize":
+		return text.fontSize, true
+	case "textColor":
+		return text.color, true
+	case "align":
+		return textAlignName(text.align, textAlignNames), true
+	case "verticalAlign":
+		return textAlignName(text.verticalAlign, textVerticalAlignNames), true
+	case "wrap":
+		return text.wrap, true
+	}
+	return nil, false
+}
+
+func (text *uiText) font(widget *Widget) *Font {
+	if text.fontName != "" && widget.canvas != nil && widget.canvas.gameObject != nil {
+		font := widget.canvas.gameObject.Scene.GetFont(text.fontName)
+		if font != nil {
+			return font
+		}
+	}
+	return builtinFont()
+}
+
+func (text *uiText) draw(widget *Widget, batch *uiBatch, rect uiRect) {
+	text.drawString(widget, batch, rect, text.text)
+}
+
+func (text *uiText) drawString(widget *Widget, batch *uiBatch, rect uiRect, str string) {
+	if str == "" {
+		return
+	}
+	font := text.font(widget)
+	texture := fontTexture(font)
+	if texture == nil {
+		return
+	}
+
+	scale := float32(1)
+	if text.fontSize > 0 && font.LineHeight > 0 {
+		scale = text.fontSize / font.LineHeight
+	}
+	var wrapWidth float32
+	if text.wrap {
+		wrapWidth = rect.w / scale
+	}
+	glyphs, _, height := font.layout(str, wrapWidth, text.align, 1)
+
+	// Lines are aligned around x = 0 by the layout.
+	x := rect.x
+	switch text.align {
+	case TextCenter:
+		x += rect.w / 2
+	case TextRight:
+		x += rect.w
+	}
+	y := rect.y
+	switch text.verticalAlign {
+	case TextMiddle:
+		y += (rect.h - height*scale) / 2
+	case TextBottom:
+		y += rect.h - height*scale
+	}
+
+	textureWidth := float32(texture.Width)
+	textureHeight := float32(texture.Height)
+	for _, placed := range glyphs {
+		glyph := placed.glyph
+		glyphRect := uiRect{x + placed.x*scale, y + placed.y*scale, glyph.Width * scale, glyph.Height * scale}
+		uv := [4]float32{glyph.X / textureWidth, glyph.Y / textureHeight,
+			(glyph.X + glyph.Width) / textureWidth, (glyph.Y + glyph.Height) / textureHeight}
+		batch.quad(glyphRect, texture, uv, text.color)
+	}
+}
+
+// A panel fills its rectangle, usually to group other widgets.
+type uiPanel struct {
+	uiStatic
+	fill uiFill
+}
+
+func (panel *uiPanel) setAttr(widget *Widget, attr string, value interface{}) (bool, error) {
+	return panel.fill.setAttr(attr, value)
+}
+
+func (panel *uiPanel) getAttr(widget *Widget, attr string) (interface{}, bool) {
+	return panel.fill.getAttr(attr)
+}
+
+func (panel *uiPanel) draw(widget *Widget, batch *uiBatch) {
+	panel.fill.draw(widget, batch, widget.rect, widget.color)
+}
+
+func newUIPanel(widget *Widget) uiControl {
+	widget.color = mgl32.Vec4{0, 0, 0, 0.6}
+	return &uiPanel{}
+}
+
+// An image draws a texture, keeping its aspect ratio with "preserveAspect".
+type uiImage struct {
+	uiStatic
+	fill           uiFill
+	preserveAspect bool
+}
+
+func (image *uiImage) setAttr(widget *Widget, attr string, value interface{}) (bool, error) {
+	if attr == "preserveAspect" {
+		var err error
+		image.preserveAspect, err = CastBool(value)
+		return true, err
+	}
+	return image.fill.setAttr(attr, value)
+}
+
+func (image *uiImage) getAttr(widget *Widget, attr string) (interface{}, bool) {
+	if attr == "preserveAspect" {
+		return image.preserveAspect, true
+	}
+	return image.fill.getAttr(attr)
+}
+
+func (image *uiImage) draw(widget *Widget, batch *uiBatch) {
+	rect := widget.rect
+	texture := image.fill.lookup(widget)
+	if image.preserveAspect && texture != nil && texture.Height > 0 && rect.h > 0 {
+		aspect := float32(texture.Width) / float32(texture.Height)
+		if rect.w/rect.h > aspect {
+			width := rect.h * aspect
+			rect = uiRect{rect.x + (rect.w-width)/2, rect.y, width, rect.h}
+		} else {
+			height := rect.w / aspect
+			rect = uiRect{rect.x, rect.y + (rect.h-height)/2, rect.w, height}
+		}
+	}
+	image.fill.draw(widget, batch, rect, widget.color)
+}
+
+func newUIImage(widget *Widget) uiControl {
+	return &uiImage{}
+}
+
+// Labels let the pointer through, so they can be placed over other widgets.
+type uiLabel struct {
+	uiStatic
+	text uiText
+}
+
+func (label *uiLabel) setAttr(widget *Widget, attr string, value interface{}) (bool, error) {
+	return label.text.setAttr(attr, value)
+}
+
+func (label *uiLabel) getAttr(widget *Widget, attr string) (interface{}, bool) {
+	return label.text.getAttr(attr)
+}
+
+func (label *uiLabel) draw(widget *Widget, batch *uiBatch) {
+	label.text.draw(widget, batch, widget.rect)
+}
+
+func newUILabel(widget *Widget) uiControl {
+	widget.raycast = false
+	return &uiLabel{text: newUIText(TextLeft, TextMiddle)}
+}
+
+// A button emits its event when released over it, or submitted.
+type uiButton struct {
+	uiStatic
+	fill uiFill
+	text uiText
+}
+
+func (button *uiButton) setAttr(widget *Widget, attr string, value interface{}) (bool, error) {
+	handled, err := button.fill.setAttr(attr, value)
+	if handled {
+		return true, err
+	}
+	return button.text.setAttr(attr, value)
+}
+
+func (button *uiButton) getAttr(widget *Widget, attr string) (interface{}, bool) {
+	value, handled := button.fill.getAttr(attr)
+	if handled {
+		return value, true
+	}
+	return button.text.getAttr(attr)
+}
+
+func (button *uiButton) draw(widget *Widget, batch *uiBatch) {
+	button.fill.draw(widget, batch, widget.rect, widget.stateColor(widget.color))
+	button.text.draw(widget, batch, widget.rect)
+}
+
+func (button *uiButton) interactive() bool {
+	return true
+}
+
+func (button *uiButton) release(widget *Widget, inside bool) {
+	if inside {
+		widget.emit(nil)
+	}
+}
+
+func (button *uiButton) submit(widget *Widget) {
+	widget.emit(nil)
+}
+
+func newUIButton(widget *Widget) uiControl {
+	widget.size = mgl32.Vec2{160, 40}
+	widget.color = mgl32.Vec4{0.25, 0.25, 0.3, 1}
+	return &uiButton{text: newUIText(TextCenter, TextMiddle)}
+}
+
+// A slider picks a value between "min" and "max", in "step" increments when
+// not 0. The arrow keys and the wheel move it by a step, or a tenth of the
+// range.
+type uiSlider struct {
+	uiStatic
+	min         float32
+	max         float32
+	value       float32
+	step        float32
+	fillColor   mgl32.Vec4
+	handleColor mgl32.Vec4
+}
+
+func (slider *uiSlider) clamp(value float32) float32 {
+	if slider.step > 0 {
+		value = slider.min + float32(math.Floor(float64((value-slider.min)/slider.step)+0.5))*slider.step
+	}
+	if value < slider.min {
+		value = slider.min
+	}
+	if value > slider.max {
+		value = slider.max
+	}
+	return value
+}
+
+func (slider *uiSlider) set(widget *Widget, value float32) {
+	value = slider.clamp(value)
+	if value != slider.value {
+		slider.value = value
+		widget.emit(value)
+	}
+}
+
+func (slider *uiSlider) increment() float32 {
+	if slider.step > 0 {
+		return slider.step
+	}
+	return (slider.max - slider.min) / 10
+}
+
+// The handle is a square as high as the slider.
+func (slider *uiSlider) ratio() float32 {
+	if slider.max <= slider.min {
+		return 0
+	}
+	return (slider.value - slider.min) / (slider.max - slider.min)
+}
+
+func (slider *uiSlider) setAttr(widget *Widget, attr string, value interface{}) (bool, error) {
+	var err error
+	switch attr {
+	case "min":
+		slider.min, err = CastFloat32(value)
+	case "max":
+		slider.max, err = CastFloat32(value)
+	case "value":
+		slider.value, err = CastFloat32(value)
+	case "step":
+		slider.step, err = CastFloat32(value)
+	case "fillColor":
+		slider.fillColor, err = castColor(value)
+	case "handleColor":
+		slider.handleColor, err = castColor(value)
+	default:
+		return false, nil
+	}
+	if err == nil && attr != "fillColor" && attr != "handleColor" {
+		slider.value = slider.clamp(slider.value)
+	}
+	return true, err
+}
+
+func (slider *uiSlider) getAttr(widget *Widget, attr string) (interface{}, bool) {
+	switch attr {
+	case "min":
+		return slider.min, true
+	case "max":
+		return slider.max, true
+	case "value":
+		return slider.value, true
+	case "step":
+		return slider.step, true
+	case "fillColor":
+		return slider.fillColor, true
+	case "handleColor":
+		return slider.handleColor, true
+	}
+	return nil, false
+}
+
+func (slider *uiSlider) draw(widget *Widget, batch *uiBatch) {
+	rect := widget.rect
+	handle := rect.h
+	track := uiRect{rect.x + handle/2, rect.y + handle*3/8, rect.w - handle, handle / 4}
+	position := track.w * slider.ratio()
+	batch.quad(track, nil, [4]float32{}, widget.color)
+	batch.quad(uiRect{track.x, track.y, position, track.h}, nil, [4]float32{}, slider.fillColor)
+	batch.quad(uiRect{track.x + position - handle/2, rect.y, handle, handle}, nil, [4]float32{}, widget.stateColor(slider.handleColor))
+}
+
+func (slider *uiSlider) interactive() bool {
+	return true
+}
+
+func (slider *uiSlider) press(widget *Widget, x, y float32) {
+	slider.drag(widget, x, y)
+}
+
+func (slider *uiSlider) drag(widget *Widget, x, y float32) {
+	rect := widget.rect
+	length := rect.w - rect.h
+	if length <= 0 {
+		return
+	}
+	ratio := (x - rect.x - rect.h/2) / length
+	slider.set(widget, slider.min+ratio*(slider.max-slider.min))
+}
+
+func (slider *uiSlider) key(widget *Widget, key Key) bool {
+	switch key {
+	case KeyLeft:
+		slider.set(widget, slider.value-slider.increment())
+		return true
+	case KeyRight:
+		slider.set(widget, slider.value+slider.increment())
+		return true
+	}
+	return false
+}
+
+func (slider *uiSlider) scroll(widget *Widget, amount float32) bool {
+	slider.set(widget, slider.value+amount*slider.increment())
+	return true
+}
+
+func newUISlider(widget *Widget) uiControl {
+	widget.size = mgl32.Vec2{200, 20}
+	widget.color = mgl32.Vec4{0.25, 0.25, 0.3, 1}
+	return &uiSlider{max: 1, fillColor: mgl32.Vec4{0.4, 0.6, 1, 1}, handleColor: mgl32.Vec4{1, 1, 1, 1}}
+}
+
+// A toggle is a check box followed by its text.
+type uiToggle struct {
+	uiStatic
+	checked    bool
+	checkColor mgl32.Vec4
+	text       uiText
+}
+
+func (toggle *uiToggle) setAttr(widget *Widget, attr string, value interface{}) (bool, error) {
+	var err error
+	switch attr {
+	case "checked":
+		toggle.checked, err = CastBool(value)
+	case "checkColor":
+		toggle.checkColor, err = castColor(value)
+	default:
+		return toggle.text.setAttr(attr, value)
+	}
+	return true, err
+}
+
+func (toggle *uiToggle) getAttr(widget *Widget, attr string) (interface{}, bool) {
+	switch attr {
+	case "checked":
+		return toggle.checked, true
+	case "checkColor":
+		return toggle.checkColor, true
+	}
+	return toggle.text.getAttr(attr)
+}
+
+func (toggle *uiToggle) draw(widget *Widget, batch *uiBatch) {
+	rect := widget.rect
+	box := rect.h
+	batch.quad(uiRect{rect.x, rect.y, box, box}, nil, [4]float32{}, widget.stateColor(widget.color))
+	if toggle.checked {
+		batch.quad(uiRect{rect.x + box/4, rect.y + box/4, box / 2, box / 2}, nil, [4]float32{}, toggle.checkColor)
+	}
+	toggle.text.draw(widget, batch, uiRect{rect.x + box*1.25, rect.y, rect.w - box*1.25, rect.h})
+}
+
+func (toggle *uiToggle) interactive() bool {
+	return true
+}
+
+func (toggle *uiToggle) flip(widget *Widget) {
+	toggle.checked = !toggle.checked
+	widget.emit(toggle.checked)
+}
+
+func (toggle *uiToggle) release(widget *Widget, inside bool) {
+	if inside {
+		toggle.flip(widget)
+	}
+}
+
+func (toggle *uiToggle) submit(widget *Widget) {
+	toggle.flip(widget)
+}
+
+func newUIToggle(widget *Widget) uiControl {
+	widget.size = mgl32.Vec2{200, 24}
+	widget.color = mgl32.Vec4{0.25, 0.25, 0.3, 1}
+	return &uiToggle{checkColor: mgl32.Vec4{0.4, 0.6, 1, 1}, text: newUIText(TextLeft, TextMiddle)}
+}
+
+// A list shows "items" in rows of "itemHeight", scrolled by the wheel. The
+// selected index (-1 for none) is emitted when it changes, and when the
+// list is submitted.
+type uiList struct {
+	uiStatic
+	items         []string
+	selected      int
+	itemHeight    float32
+	offset        float32
+	selectedColor mgl32.Vec4
+	text          uiText
+}
+
+func (list *uiList) maxOffset(widget *Widget) float32 {
+	return float32(math.Max(0, float64(float32(len(list.items))*list.itemHeight-widget.rect.h)))
+}
+
+func (list *uiList) scrollTo(widget *Widget, offset float32) {
+	list.offset = float32(math.Max(0, math.Min(float64(offset), float64(list.maxOffset(widget)))))
+}
+
+func (list *uiList) selectItem(widget *Widget, index int) {
+	if index < 0 || index >= len(list.items) || index == list.selected {
+		return
+	}
+	list.selected = index
+	// Scroll to reveal the selected row.
+	top := float32(index) * list.itemHeight
+	if top < list.offset {
+		list.scrollTo(widget, top)
+	} else if top+list.itemHeight > list.offset+widget.rect.h {
+		list.scrollTo(widget, top+list.itemHeight-widget.rect.h)
+	}
+	widget.emit(index)
+}
+
+func (list *uiList) setAttr(widget *Widget, attr string, value interface{}) (bool, error) {
+	var err error
+	switch attr {
+	case "items":
+		values, ok := value.([]interface{})
+		if !ok {
+			return true, fmt.Errorf("expects a list of strings")
+		}
+		var items []string
+		for _, item := range values {
+			str, ok := item.(string)
+			if !ok {
+				return true, fmt.Errorf("expects a list of strings")
+			}
+			items = append(items, str)
+		}
+		list.items = items
+		if list.selected >= len(items) {
+			list.selected = -1
+		}
+		list.offset = 0
+	case "selected":
+		var index int
+		index, err = CastInt(value)
+		if err == nil {
+			if index < -1 || index >= len(list.items) {
+				return true, fmt.Errorf("expects an index of the items or -1")
+			}
+			list.selected = index
+		}
+	case "itemHeight":
+		list.itemHeight, err = CastFloat32(value)
+	case "selectedColor":
+		list.selectedColor, err = castColor(value)
+	default:
+		return list.text.setAttr(attr, value)
+	}
+	return true, err
+}
+
+func (list *uiList) getAttr(widget *Widget, attr string) (interface{}, bool) {
+	switch attr {
+	case "items":
+		return list.items, true
+	case "selected":
+		return list.selected, true
+	case "itemHeight":
+		return list.itemHeight, true
+	case "selectedColor":
+		return list.selectedColor, true
+	}
+	return list.text.getAttr(attr)
+}
+
+// Only the rows entirely inside the list are drawn.
+func (list *uiList) draw(widget *Widget, batch *uiBatch) {
+	rect := widget.rect
+	batch.quad(rect, nil, [4]float32{}, widget.color)
+	if list.itemHeight <= 0 {
+		return
+	}
+	first := int(list.offset / list.itemHeight)
+	for i := first; i < len(list.items); i++ {
+		row := uiRect{rect.x, rect.y + float32(i)*list.itemHeight - list.offset, rect.w, list.itemHeight}
+		if row.y < rect.y-0.001 {
+			continue
+		}
+		if row.y+row.h > rect.y+rect.h+0.001 {
+			break
+		}
+		if i == list.selected {
+			batch.quad(row, nil, [4]float32{}, list.selectedColor)
+		}
+		list.text.drawString(widget, batch, uiRect{row.x + 4, row.y, row.w - 8, row.h}, list.items[i])
+	}
+}
+
+func (list *uiList) interactive() bool {
+	return true
+}
+
+func (list *uiList) press(widget *Widget, x, y float32) {
+	if list.itemHeight <= 0 {
+		return
+	}
+	index := int(math.Floor(float64((y - widget.rect.y + list.offset) / list.itemHeight)))
+	list.selectItem(widget, index)
+}
+
+func (list *uiList) submit(widget *Widget) {
+	if list.selected >= 0 {
+		widget.emit(list.selected)
+	}
+}
+
+// The focus leaves the list from its first and last items.
+func (list *uiList) key(widget *Widget, key Key) bool {
+	switch key {
+	case KeyUp:
+		if list.selected <= 0 {
+			return false
+		}
+		list.selectItem(widget, list.selected-1)
+		return true
+	case KeyDown:
+		if list.selected >= len(list.items)-1 {
+			return false
+		}
+		list.selectItem(widget, list.selected+1)
+		return true
+	}
+	return false
+}
+
+func (list *uiList) scroll(widget *Widget, amount float32) bool {
+	list.scrollTo(widget, list.offset-amount*list.itemHeight)
+	return true
+}
+
+func newUIList(widget *Widget) uiControl {
+	widget.size = mgl32.Vec2{200, 200}
+	widget.color = mgl32.Vec4{0, 0, 0, 0.6}
+	return &uiList{selected: -1, itemHeight: 24, selectedColor: mgl32.Vec4{0.4, 0.6, 1, 0.6}, text: newUIText(TextLeft, TextMiddle)}
+}
+
+func init() {
+	registerWidgetType("panel", newUIPanel)
+	registerWidgetType("image", newUIImage)
+	registerWidgetType("label", newUILabel)
+	registerWidgetType("button", newUIButton)
+	registerWidgetType("slider", newUISlider)
+	registerWidgetType("toggle", newUIToggle)
+	registerWidgetType("list", newUIList)
+}
@@ -0,0 +1,198 @@
+package gozmo
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A FileSystem is the virtual file system the loaders read the assets from
+// (textures, sounds, scenes, CSV tilemaps, fonts, shaders, Lua scripts). It
+// stacks file systems mounted at a directory: OS directories, embedded files
+// (embed.FS) and archives packed by the gozmopack command. The files of the
+// last mounted ones override the others, so overlay and mod directories can
+// replace single assets.
+//
+// Paths are relative and use slashes, like "assets/sd_idle.png". Absolute
+// paths, and relative ones leaving the root like "../shared/font.ttf", are
+// read from the OS, bypassing the mounts.
+type FileSystem struct {
+	// Loaders read files on the AssetLoader workers.
+	lock   sync.RWMutex
+	mounts []mount
+}
+
+type mount struct {
+	dir    string
+	fsys   fs.FS
+	closer io.Closer
+}
+
+// The default file system mounts the directory of the executable and, above
+// it, the working directory, so that games find their assets wherever they
+// are launched from.
+var fileSystem = newDefaultFileSystem()
+
+func newDefaultFileSystem() *FileSystem {
+	fileSystem := NewFileSystem()
+	executable, err := os.Executable()
+	if err == nil {
+		fileSystem.MountDir(filepath.Dir(executable))
+	}
+	fileSystem.MountDir(".")
+	return fileSystem
+}
+
+// DefaultFileSystem is the file system of the loaders.
+func DefaultFileSystem() *FileSystem {
+	return fileSystem
+}
+
+func NewFileSystem() *FileSystem {
+	return &FileSystem{}
+}
+
+// Mount adds a file system at a directory ("." for the root), overriding the
+// files already mounted there.
+func (fileSystem *FileSystem) Mount(dir string, fsys fs.FS) {
+	fileSystem.mount(mount{dir: cleanPath(dir), fsys: fsys})
+}
+
+func (fileSystem *FileSystem) mount(m mount) {
+	fileSystem.lock.Lock()
+	fileSystem.mounts = append(fileSystem.mounts, m)
+	fileSystem.lock.Unlock()
+}
+
+// MountDir adds an OS directory at the root, like a mod directory.
+func (fileSystem *FileSystem) MountDir(dir string) {
+	fileSystem.Mount(".", os.DirFS(dir))
+}
+
+// MountArchive adds the files of an archive made by gozmopack at the root.
+func (fileSystem *FileSystem) MountArchive(fileName string) error {
+	archive, err := zip.OpenReader(fileName)
+	if err != nil {
+		return err
+	}
+	fileSystem.mount(mount{dir: ".", fsys: archive, closer: archive})
+	return nil
+}
+
+// Close removes all of the mounts, closing the archives.
+func (fileSystem *FileSystem) Close() error {
+	fileSystem.lock.Lock()
+	mounts := fileSystem.mounts
+	fileSystem.mounts = nil
+	fileSystem.lock.Unlock()
+
+	var err error
+	for _, m := range mounts {
+		if m.closer != nil {
+			closeErr := m.closer.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}
+	}
+	return err
+}
+
+// Open opens a file from the last mount having it.
+func (fileSystem *FileSystem) Open(name string) (fs.File, error) {
+	if filepath.IsAbs(name) {
+		return os.Open(name)
+	}
+	cleaned := cleanPath(name)
+	if !fs.ValidPath(cleaned) {
+		// Outside of the mounts, relative to the working directory.
+		return os.Open(name)
+	}
+
+	fileSystem.lock.RLock()
+	defer fileSystem.lock.RUnlock()
+	for i := len(fileSystem.mounts) - 1; i >= 0; i-- {
+		m := fileSystem.mounts[i]
+		relative, ok := m.relative(cleaned)
+		if !ok {
+			continue
+		}
+		file, err := m.fsys.Open(relative)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		return file, err
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// relative returns the path of a file in the mounted file system.
+func (m mount) relative(name string) (string, bool) {
+	switch {
+	case m.dir == ".":
+		return name, true
+	case name == m.dir:
+		return ".", true
+	case strings.HasPrefix(name, m.dir+"/"):
+		return name[len(m.dir)+1:], true
+	}
+	return "", false
+}
+
+func (fileSystem *FileSystem) ReadFile(name string) ([]byte, error) {
+	file, err := fileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ioutil.ReadAll(file)
+}
+
+func (fileSystem *FileSystem) Stat(name string) (fs.FileInfo, error) {
+	file, err := fileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
+
+// openSeeker opens a file that can be read from any position, like a
+// streamed track. Files of archives are read in memory.
+func (fileSystem *FileSystem) openSeeker(name string) (io.ReadSeekCloser, error) {
+	file, err := fileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	seeker, ok := file.(io.ReadSeekCloser)
+	if ok {
+		return seeker, nil
+	}
+	defer file.Close()
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	return memoryFile{bytes.NewReader(data)}, nil
+}
+
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (file memoryFile) Close() error {
+	return nil
+}
+
+// cleanPath converts a relative OS path to a slash separated one.
+func cleanPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
@@ -0,0 +1,66 @@
+// +build android
+
+package gozmo
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"time"
+
+	"golang.org/x/mobile/asset"
+)
+
+// The assets directory is packed in the APK, where the working directory and
+// the executable one do not help.
+func init() {
+	fileSystem.Mount("assets", androidAssets{})
+}
+
+// androidAssets reads the assets of the APK.
+type androidAssets struct{}
+
+func (assets androidAssets) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	file, err := asset.Open(name)
+	if err != nil {
+		// The asset manager only reports that the asset is missing.
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return androidAsset{File: file, name: name}, nil
+}
+
+type androidAsset struct {
+	asset.File
+	name string
+}
+
+func (file androidAsset) Stat() (fs.FileInfo, error) {
+	position, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.Seek(position, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+	return androidAssetInfo{name: path.Base(file.name), size: size}, nil
+}
+
+type androidAssetInfo struct {
+	name string
+	size int64
+}
+
+func (info androidAssetInfo) Name() string       { return info.name }
+func (info androidAssetInfo) Size() int64        { return info.size }
+func (info androidAssetInfo) Mode() fs.FileMode  { return 0444 }
+func (info androidAssetInfo) ModTime() time.Time { return time.Time{} }
+func (info androidAssetInfo) IsDir() bool        { return false }
+func (info androidAssetInfo) Sys() interface{}   { return nil }
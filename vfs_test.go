@@ -0,0 +1,108 @@
+package gozmo
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileSystemOverlay(t *testing.T) {
+	fileSystem := NewFileSystem()
+	fileSystem.Mount(".", fstest.MapFS{
+		"assets/sd_idle.png": {Data: []byte("base")},
+		"assets/fall.lua":    {Data: []byte("speed = 1")},
+	})
+	fileSystem.Mount("assets", fstest.MapFS{
+		"sd_idle.png": {Data: []byte("mod")},
+	})
+
+	data, err := fileSystem.ReadFile("./assets/sd_idle.png")
+	if err != nil || string(data) != "mod" {
+		t.Error("Expected the file of the mod, got", string(data), err)
+	}
+	data, err = fileSystem.ReadFile(filepath.Join("assets", "fall.lua"))
+	if err != nil || string(data) != "speed = 1" {
+		t.Error("Expected the base file, got", string(data), err)
+	}
+	_, err = fileSystem.ReadFile("assets/missing.png")
+	if !os.IsNotExist(err) {
+		t.Error("Expected a missing file, got", err)
+	}
+	// Paths leaving the root are not looked up in the mounts.
+	_, err = fileSystem.ReadFile("../assets/sd_idle.png")
+	if !os.IsNotExist(err) {
+		t.Error("Expected a missing OS file, got", err)
+	}
+}
+
+func TestFileSystemArchive(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	archiveName := filepath.Join(dir, "assets.zip")
+	file, err := os.Create(archiveName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(file)
+	writer, _ := archive.Create("scenes/level.json")
+	writer.Write([]byte(`{"name": "Packed", "objects": [{"name": "Player"}]}`))
+	archive.Close()
+	file.Close()
+
+	// Absolute paths bypass the mounts.
+	absolute := filepath.Join(dir, "settings.json")
+	err = ioutil.WriteFile(absolute, []byte("{}"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(old *FileSystem) { fileSystem = old }(fileSystem)
+	fileSystem = NewFileSystem()
+	err = fileSystem.MountArchive(archiveName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fileSystem.Close()
+
+	scene := NewSceneFromFilename("scenes/level.json")
+	defer scene.Destroy()
+	if scene.Name != "Packed" || scene.FindGameObject("Player") == nil {
+		t.Error("Expected the packed scene")
+	}
+
+	seeker, err := fileSystem.openSeeker("scenes/level.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeker.Seek(9, io.SeekStart)
+	data, _ := ioutil.ReadAll(seeker)
+	seeker.Close()
+	if string(data) != `"Packed", "objects": [{"name": "Player"}]}` {
+		t.Error("Expected the end of the file, got", string(data))
+	}
+
+	data, err = fileSystem.ReadFile(absolute)
+	if err != nil || string(data) != "{}" {
+		t.Error("Expected the OS file, got", string(data), err)
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	relative, err := filepath.Rel(workingDir, absolute)
+	if err != nil || !strings.HasPrefix(relative, "..") {
+		t.Fatal("Expected a path leaving the working directory, got", relative, err)
+	}
+	data, err = fileSystem.ReadFile(relative)
+	if err != nil || string(data) != "{}" {
+		t.Error("Expected the OS file, got", string(data), err)
+	}
+}
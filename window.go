@@ -3,6 +3,7 @@
 package gozmo
 
 import (
+	"fmt"
 	"log"
 	"runtime"
 
@@ -13,16 +14,19 @@ import (
 // The Window type interfaces with the display hardware using OpenGL.
 // Coordinates are 0, 0 at screen center.
 type Window struct {
-	width        int32
-	height       int32
-	title        string
-	glfwWindow   *glfw.Window
-	currentScene *Scene
-	Projection   mgl32.Mat4
-	View         mgl32.Mat4
+	width      int32
+	height     int32
+	title      string
+	glfwWindow *glfw.Window
+	Projection mgl32.Mat4
+	View       mgl32.Mat4
 
 	OrthographicSize float32
 	AspectRatio      float32
+
+	// Cameras viewports are relative to the framebuffer.
+	framebufferWidth  int32
+	framebufferHeight int32
 }
 
 func OpenWindowVersion(width int32, height int32, title string, major int, minor int) *Window {
@@ -56,25 +60,79 @@ func OpenWindowVersion(width int32, height int32, title string, major int, minor
 
 	glfwin.MakeContextCurrent()
 
-	window.OrthographicSize = 10
+	window.OrthographicSize = defaultOrthographicSize
 
 	window.AspectRatio = float32(width) / float32(height)
 
-	window.Projection = mgl32.Ortho2D(-window.OrthographicSize*window.AspectRatio, window.OrthographicSize*window.AspectRatio, -window.OrthographicSize, window.OrthographicSize)
+	window.updateProjection()
 	window.View = mgl32.LookAt(0, 0, 1, 0, 0, 0, 0, 1, 0)
 	window.glfwWindow = glfwin
 
+	glfwin.SetKeyCallback(onKey)
+	glfwin.SetCharCallback(onChar)
+	glfwin.SetMouseButtonCallback(onMouseButton)
+	glfwin.SetCursorPosCallback(onCursorPos)
+	glfwin.SetScrollCallback(onScroll)
+	glfwin.SetSizeCallback(onSize)
+	glfwin.SetFramebufferSizeCallback(onFramebufferSize)
+
 	glfw.SwapInterval(1)
 	//glfw.SwapInterval(0)
 
 	fbWidth, fbHeight := glfwin.GetFramebufferSize()
 	GLInit(int32(fbWidth), int32(fbHeight))
+	window.framebufferWidth = int32(fbWidth)
+	window.framebufferHeight = int32(fbHeight)
 
 	Engine.Window = &window
 
 	return &window
 }
 
+// Repeated key presses are ignored, the char callback manages them for text.
+func onKey(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	switch action {
+	case glfw.Press:
+		pendingInput.Keys = append(pendingInput.Keys, InputKey{Key: Key(key), Pressed: true})
+	case glfw.Release:
+		pendingInput.Keys = append(pendingInput.Keys, InputKey{Key: Key(key), Pressed: false})
+	}
+}
+
+func onChar(w *glfw.Window, char rune) {
+	pendingInput.Chars = append(pendingInput.Chars, char)
+}
+
+func onMouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	pendingInput.MouseButtons = append(pendingInput.MouseButtons, InputMouseButton{Button: int(button), Pressed: action == glfw.Press})
+}
+
+func onCursorPos(w *glfw.Window, x float64, y float64) {
+	pendingInput.MouseX = x
+	pendingInput.MouseY = y
+}
+
+func onScroll(w *glfw.Window, x float64, y float64) {
+	pendingInput.WheelX += x
+	pendingInput.WheelY += y
+}
+
+// The cursor coordinates are relative to the window size, not to the
+// framebuffer one (they differ on high density displays).
+func onSize(w *glfw.Window, width int, height int) {
+	Engine.Window.width = int32(width)
+	Engine.Window.height = int32(height)
+}
+
+func (window *Window) updateProjection() {
+	window.Projection = mgl32.Ortho2D(-window.OrthographicSize*window.AspectRatio, window.OrthographicSize*window.AspectRatio, -window.OrthographicSize, window.OrthographicSize)
+}
+
+func onFramebufferSize(w *glfw.Window, width int, height int) {
+	Engine.Window.framebufferWidth = int32(width)
+	Engine.Window.framebufferHeight = int32(height)
+}
+
 func OpenWindow(width int32, height int32, title string) *Window {
 	return OpenWindowVersion(width, height, title, 3, 3)
 }
@@ -84,29 +142,42 @@ func (window *Window) Run() {
 
 	glfw.SetTime(0.0)
 
+	err := SetAudioBackend(NewOpenALBackend())
+	if err != nil {
+		fmt.Println("audio", err)
+	}
+
 	for !win.ShouldClose() {
 
 		GLClear()
 
-		scene := window.currentScene
-		if scene != nil {
-			scene.Update(glfw.GetTime())
-		}
+		pendingInput.Gamepads = pollGamepads()
+		now := updateInput(glfw.GetTime())
+
+		checkHotReload()
+		assetLoader.process()
+		sceneManager.update(now)
 
 		win.SwapBuffers()
 		glfw.PollEvents()
 	}
+	err = StopInputRecording()
+	if err != nil {
+		fmt.Println(err)
+	}
+	err = SetAudioBackend(nil)
+	if err != nil {
+		fmt.Println("audio", err)
+	}
+	saveSettings()
 	glfw.Terminate()
 }
 
-func (window *Window) getKey(kc Key) bool {
-	return window.glfwWindow.GetKey(glfw.Key(kc)) == glfw.Press
-}
-
+// SetScene shows a scene at once, see SceneManager.SetScene.
 func (window *Window) SetScene(scene *Scene) {
-	window.currentScene = scene
+	sceneManager.SetScene(scene)
 }
 
 func (window *Window) SetSceneByName(sceneName string) {
-	window.currentScene = Engine.scenes[sceneName]
+	window.SetScene(Engine.scenes[sceneName])
 }
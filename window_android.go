@@ -3,36 +3,60 @@
 package gozmo
 
 import (
+	"fmt"
+	"time"
+
+	"github.com/go-gl/mathgl/mgl32"
 	"golang.org/x/mobile/app"
+	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/paint"
+	"golang.org/x/mobile/event/size"
+	"golang.org/x/mobile/event/touch"
 	"golang.org/x/mobile/gl"
 )
 
-type Key int
-
 // The Window type interfaces with the display hardware using OpenGL.
 // Coordinates are 0, 0 at screen center.
 type Window struct {
-	width        int32
-	height       int32
-	title        string
-	scenes       []*Scene
-	currentScene *Scene
-}
+	width      int32
+	height     int32
+	title      string
+	scenes     []*Scene
+	Projection mgl32.Mat4
+	View       mgl32.Mat4
 
-func (window *Window) getKey(kc Key) bool {
-	return false
-}
+	OrthographicSize float32
+	AspectRatio      float32
+
+	framebufferWidth  int32
+	framebufferHeight int32
 
-var KeyboardAttr map[string]Key = map[string]Key{}
+	startTime time.Time
+}
 
 func OpenWindow(width int32, height int32, title string) *Window {
+	if Engine.Window != nil {
+		panic("a window is already active")
+	}
 	window := Window{width: width, height: height, title: title}
+	window.framebufferWidth = width
+	window.framebufferHeight = height
+	window.OrthographicSize = defaultOrthographicSize
+	window.updateProjection()
+	window.View = mgl32.LookAt(0, 0, 1, 0, 0, 0, 0, 1, 0)
+	Engine.Window = &window
 	return &window
 }
 
+// The real size is only known when the first size event arrives.
+func (window *Window) updateProjection() {
+	window.AspectRatio = float32(window.width) / float32(window.height)
+	window.Projection = mgl32.Ortho2D(-window.OrthographicSize*window.AspectRatio, window.OrthographicSize*window.AspectRatio, -window.OrthographicSize, window.OrthographicSize)
+}
+
 func (window *Window) Run() {
+	window.startTime = time.Now()
 	app.Main(func(a app.App) {
 		for e := range a.Events() {
 			switch e := a.Filter(e).(type) {
@@ -40,13 +64,83 @@ func (window *Window) Run() {
 				switch e.Crosses(lifecycle.StageVisible) {
 				case lifecycle.CrossOn:
 					glctx, _ = e.DrawContext.(gl.Context)
-					GLInit(40, 20)
+					GLInit(window.width, window.height)
+					err := SetAudioBackend(NewOpenALBackend())
+					if err != nil {
+						fmt.Println("audio", err)
+					}
+				case lifecycle.CrossOff:
+					// Apps in the background can be killed at any time.
+					err := StopInputRecording()
+					if err != nil {
+						fmt.Println(err)
+					}
+					// Silent in the background.
+					err = SetAudioBackend(nil)
+					if err != nil {
+						fmt.Println("audio", err)
+					}
+					saveSettings()
+				}
+			case size.Event:
+				window.width = int32(e.WidthPx)
+				window.height = int32(e.HeightPx)
+				window.framebufferWidth = window.width
+				window.framebufferHeight = window.height
+				window.updateProjection()
+				if glctx != nil {
+					GLViewport(0, 0, window.width, window.height)
 				}
+			case touch.Event:
+				pendingInput.Touches = append(pendingInput.Touches, InputTouch{Id: int(e.Sequence), X: e.X, Y: e.Y, Phase: TouchPhase(e.Type)})
+			case key.Event:
+				onKey(e)
 			case paint.Event:
-				window.redraw()
+				now := updateInput(time.Since(window.startTime).Seconds())
+				window.redraw(now)
 				a.Publish()
 				a.Send(paint.Event{})
 			}
 		}
 	})
 }
+
+// Hardware keyboards send key events, with Rune set for printable keys.
+func onKey(e key.Event) {
+	k, ok := mobileKeys[e.Code]
+	switch e.Direction {
+	case key.DirPress:
+		if ok {
+			pendingInput.Keys = append(pendingInput.Keys, InputKey{Key: k, Pressed: true})
+		}
+	case key.DirRelease:
+		if ok {
+			pendingInput.Keys = append(pendingInput.Keys, InputKey{Key: k, Pressed: false})
+		}
+	case key.DirNone:
+		if ok {
+			pendingInput.Keys = append(pendingInput.Keys, InputKey{Key: k, Pressed: true})
+			pendingInput.Keys = append(pendingInput.Keys, InputKey{Key: k, Pressed: false})
+		}
+	}
+	if e.Rune > 0 && e.Direction != key.DirRelease {
+		pendingInput.Chars = append(pendingInput.Chars, e.Rune)
+	}
+}
+
+func (window *Window) redraw(now float64) {
+	GLClear()
+
+	checkHotReload()
+	assetLoader.process()
+	sceneManager.update(now)
+}
+
+// SetScene shows a scene at once, see SceneManager.SetScene.
+func (window *Window) SetScene(scene *Scene) {
+	sceneManager.SetScene(scene)
+}
+
+func (window *Window) SetSceneByName(sceneName string) {
+	window.SetScene(Engine.scenes[sceneName])
+}